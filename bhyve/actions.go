@@ -0,0 +1,130 @@
+package bhyve
+
+import (
+	"fmt"
+
+	"tui"
+)
+
+// Actions of Bhyve VMs only
+const (
+	ACTION_HARDWARE = "hardware"
+	ACTION_VNC      = "vnc"
+	ACTION_SERIAL   = "serial"
+)
+
+var actions = tui.NewActionRegistry[*BhyveVm]()
+
+func init() {
+	actions.Register(
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_ACTIONS, Label: ACTIONS, Bottom: true,
+			Run: func(jail *BhyveVm) { actions.OpenActionDialog(jail.jtui, jail) },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_STARTSTOP, Label: STARTSTOP, Bottom: true, Menu: true,
+			StateLabel: func(jail *BhyveVm) string {
+				if jail.IsRunning() {
+					return STOP
+				}
+				return START
+			},
+			Available: func(jail *BhyveVm) bool { return jail.IsRunning() || jail.IsRunnable() },
+			CliArgs: func(jail *BhyveVm) []string {
+				if jail.IsRunning() {
+					return jail.GetCliArgs(commandJailStop, "inter=1")
+				}
+				return jail.GetCliArgs(commandJailStart, "inter=1")
+			},
+			Run: func(jail *BhyveVm) { jail.StartStop() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_SNAPSHOT, Label: CREATESNAP, Bottom: true, Menu: true,
+			CliArgs: func(jail *BhyveVm) []string {
+				return jail.GetSnapshotCliArgs("gettimeofday")
+			},
+			Run: func(jail *BhyveVm) { jail.OpenSnapshotDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_SNAPSHOTS, Label: DELSNAP, Bottom: true, Menu: true,
+			Run: func(jail *BhyveVm) { jail.OpenSnapActionsDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_VIEW, Label: VIEW, Bottom: true, Menu: true,
+			Run: func(jail *BhyveVm) { jail.View() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_EDIT, Label: EDIT, Bottom: true, Menu: true,
+			Run: func(jail *BhyveVm) { jail.OpenEditDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: ACTION_HARDWARE, Label: HARDWARE, Menu: true,
+			Description: "To edit CPUs, RAM, disks and NICs of the selected VM",
+			Run:         func(jail *BhyveVm) { jail.OpenHardwareDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: ACTION_VNC, Label: VNC, Menu: true,
+			Description: "To open the VNC console of the selected VM",
+			Run:         func(jail *BhyveVm) { jail.OpenVncDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: ACTION_SERIAL, Label: SERIAL, Menu: true,
+			Description: "To attach the serial console of the selected VM",
+			Available:   func(jail *BhyveVm) bool { return jail.IsRunning() },
+			Run:         func(jail *BhyveVm) { jail.AttachSerialConsole() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_CLONE, Label: CLONE, Bottom: true, Menu: true,
+			Run: func(jail *BhyveVm) { jail.OpenCloneDialog() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_EXPORT, Label: EXPORT, Bottom: true, Menu: true,
+			CliArgs: func(jail *BhyveVm) []string { return jail.GetCliArgs(commandJailExport) },
+			Run:     func(jail *BhyveVm) { jail.Export() },
+		},
+		&tui.Action[*BhyveVm]{
+			Id: tui.ACTION_DESTROY, Label: DESTROY, Bottom: true, Menu: true, Confirm: tui.CONFIRM_ALWAYS,
+			ConfirmText: func(jail *BhyveVm) string { return "Really destroy VM " + jail.Bname + "??" },
+			CliArgs:     func(jail *BhyveVm) []string { return jail.GetCliArgs(commandJailDestroy) },
+			Run:         func(jail *BhyveVm) { jail.Destroy() },
+		},
+	)
+}
+
+// GetCliArgs returns the cbsd arguments of the command for the VM
+func (jail *BhyveVm) GetCliArgs(command string, args ...string) []string {
+	res := append([]string{command}, args...)
+	return append(res, fmt.Sprintf("%s=%s", argJailName, jail.Bname))
+}
+
+func (jail *BhyveVm) GetBottomMenuActions() []string {
+	return actions.GetBottomMenuActions()
+}
+
+func (jail *BhyveVm) GetMenuActions() []string {
+	return actions.GetMenuActions()
+}
+
+func (jail *BhyveVm) GetActionCaption(action string) string {
+	return actions.GetCaption(action)
+}
+
+func (jail *BhyveVm) IsActionAvailable(action string) bool {
+	return actions.IsAvailable(jail, action)
+}
+
+func (jail *BhyveVm) GetCliActions() []string {
+	return actions.GetCliActions(jail)
+}
+
+func (jail *BhyveVm) GetActionCliArgs(action string) []string {
+	return actions.GetCliArgs(jail, action)
+}
+
+func (jail *BhyveVm) GetActionConfirmText(action string) string {
+	return actions.GetConfirmText(jail, action)
+}
+
+func (jail *BhyveVm) ExecuteAction(action string) bool {
+	return actions.Execute(jail.jtui, jail, action)
+}
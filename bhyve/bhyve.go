@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"strconv"
@@ -14,10 +15,10 @@ import (
 	"github.com/gcla/gowid"
 	"github.com/gcla/gowid/widgets/dialog"
 	"github.com/gcla/gowid/widgets/edit"
-	"github.com/gdamore/tcell"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/quasilyte/gsignal"
 
+	"container"
 	"host"
 	"tui"
 )
@@ -30,13 +31,13 @@ type BhyveVm struct {
 	OsType     string
 	VncConsole string
 	params     map[string]string
+	pending    bool // changed while running, see SetPendingReboot
 	jtui       *tui.Tui
 	evtUpdated gsignal.Event[string]
 	evtRefresh gsignal.Event[any]
 }
 
 const (
-	HELP       = "Help"
 	START      = "Start"
 	STOP       = "Stop"
 	STARTSTOP  = "Start/Stop"
@@ -45,27 +46,23 @@ const (
 	DELSNAP    = "Destroy Snap."
 	VIEW       = "View"
 	EDIT       = "Edit"
+	HARDWARE   = "Hardware..."
+	VNC        = "VNC..."
+	SERIAL     = "Serial console"
 	CLONE      = "Clone"
 	EXPORT     = "Export"
 	DESTROY    = "Destroy VM"
 	ACTIONS    = "Actions..."
-	EXIT       = "Exit"
 )
 
+const CONTAINER_TYPE string = "bhyvevm"
+
 var strStatus = []string{"Off", "On", "Slave", "Unknown(3)", "Unknown(4)", "Unknown(5)"}
 var strAutoStart = []string{"Off", "On"}
 var strHeaderTitles = []string{"NAME", "IP4_ADDRESS", "STATUS", "AUTOSTART", "OS_TYPE", "VNC_CONSOLE"}
-var strActionsMenuItems = []string{STARTSTOP, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-
-var strStartedActionsMenuItems = []string{STOP, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-var strStoppedActionsMenuItems = []string{START, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-var strNonRunnableActionsMenuItems = []string{"---", CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-
-var strBottomMenuText1 = []string{" 1", " 2", " 3", " 4", " 5", " 6", " 7", " 8", " 10", " 11", " 12"}
-var strBottomMenuText2 = []string{HELP, ACTIONS, VIEW, EDIT, CLONE, EXPORT, CREATESNAP, DESTROY, EXIT, DELSNAP, STARTSTOP}
-var keysBottomMenu = []tcell.Key{tcell.KeyF1, tcell.KeyF2, tcell.KeyF3, tcell.KeyF4, tcell.KeyF5, tcell.KeyF6, tcell.KeyF7, tcell.KeyF8, tcell.KeyF10, tcell.KeyF11, tcell.KeyF12}
 
 var commandJailLogin string = "blogin"
+var commandJailConsole string = "bhyve-console"
 var commandJailStart string = "bstart"
 var commandJailStop string = "bstop"
 var commandJailSnap string = "jsnapshot"
@@ -80,7 +77,7 @@ var argJailName = "jname"
 var argSnapName = "snapname"
 
 func (jail *BhyveVm) GetType() string {
-	return "bhyvevm"
+	return CONTAINER_TYPE
 }
 
 func (jail *BhyveVm) GetSignalUpdated() *gsignal.Event[string] {
@@ -95,42 +92,10 @@ func (jail *BhyveVm) SetTui(t *tui.Tui) {
 	jail.jtui = t
 }
 
-func (jail *BhyveVm) GetCommandHelp() string {
-	return HELP
-}
-
-func (jail *BhyveVm) GetCommandExit() string {
-	return EXIT
-}
-
-func (jail *BhyveVm) GetBottomMenuText1() []string {
-	return strBottomMenuText1
-}
-
-func (jail *BhyveVm) GetBottomMenuText2() []string {
-	return strBottomMenuText2
-}
-
 func (jail *BhyveVm) GetHeaderTitles() []string {
 	return strHeaderTitles
 }
 
-func (jail *BhyveVm) GetActionsMenuItems() []string {
-	return strActionsMenuItems
-}
-
-func (jail *BhyveVm) GetStartedActionsMenuItems() []string {
-	return strStartedActionsMenuItems
-}
-
-func (jail *BhyveVm) GetStoppedActionsMenuItems() []string {
-	return strStoppedActionsMenuItems
-}
-
-func (jail *BhyveVm) GetNonRunnableActionsMenuItems() []string {
-	return strNonRunnableActionsMenuItems
-}
-
 func (jail *BhyveVm) GetName() string {
 	return jail.Bname
 }
@@ -337,17 +302,41 @@ func GetBhyveVmsFromDb(dbname string) ([]*BhyveVm, error) {
 	return jails, nil
 }
 
-func (jail *BhyveVm) GetVncParams() (string, int) {
-	data := strings.Split(jail.VncConsole, ":")
-	if len(data) < 2 {
-		return "", 0
-	}
-	port, err := strconv.Atoi(data[1])
+func init() {
+	container.RegisterType(container.ContainerType{Name: CONTAINER_TYPE, Emulator: "bhyve", Action: tui.ACTION_VMS, Load: LoadContainers, Cleanup: RevertAllVnc})
+}
+
+// Capabilities of the Bhyve VMs
+var _ container.Loginable = (*BhyveVm)(nil)
+var _ container.Snapshotter = (*BhyveVm)(nil)
+var _ container.Cloner = (*BhyveVm)(nil)
+var _ container.Exporter = (*BhyveVm)(nil)
+var _ container.Editor = (*BhyveVm)(nil)
+var _ container.DiskLister = (*BhyveVm)(nil)
+var _ container.StatusChecker = (*BhyveVm)(nil)
+
+// LoadContainers is the loader of the bhyvevm container type
+func LoadContainers(dbname string) ([]container.Container, error) {
+	list, err := GetBhyveVmsFromDb(dbname)
 	if err != nil {
+		return make([]container.Container, 0), err
+	}
+	LoadPendingReboots(list)
+	cont := make([]container.Container, len(list))
+	for i := range list {
+		cont[i] = list[i]
+	}
+	return cont, nil
+}
+
+// GetVncParams returns the host and the port of the VNC console, empty host if the address is invalid
+func (jail *BhyveVm) GetVncParams() (string, int) {
+	if err := tui.ValidateHostPort(jail.VncConsole); err != nil {
 		return "", 0
 	}
-	// TODO: validate IP address and TCP port
-	return data[0], port
+	h, p, _ := net.SplitHostPort(jail.VncConsole)
+	port, _ := strconv.Atoi(p)
+	return h, port
 }
 
 func (jail *BhyveVm) PutJailToDb(dbname string) (bool, error) {
@@ -488,6 +477,7 @@ func (jail *BhyveVm) GetJailViewString() string {
 	strview += "Auto Start: " + jail.GetAutoStartString() + "\n"
 	strview += "OS Type: " + jail.OsType + "\n"
 	strview += "VNC Console: " + jail.VncConsole + "\n\n"
+	strview += jail.GetHardwareViewString() + "\n"
 	for key, value := range jail.params {
 		strview += key + ": " + value + "\n"
 	}
@@ -542,7 +532,7 @@ func (jail *BhyveVm) Export() {
 	} else {
 		command = host.CBSD_PROGRAM
 	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	jail.jtui.ExecActionCommand(jail.Bname, tui.ACTION_EXPORT, txtheader, command, args)
 }
 
 func (jail *BhyveVm) Destroy() {
@@ -560,52 +550,28 @@ func (jail *BhyveVm) Destroy() {
 	} else {
 		command = host.CBSD_PROGRAM
 	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	jail.jtui.ExecActionCommand(jail.Bname, tui.ACTION_DESTROY, txtheader, command, args)
 	jail.evtRefresh.Emit(nil)
 }
 
-func (jail *BhyveVm) OpenDestroyDialog() {
-	var cbsdDestroyJailDialog *dialog.Widget
-	cbsdDestroyJailDialog = jail.jtui.MakeDialogForJail(
-		jail.Bname,
-		"Destroy VM "+jail.Bname,
-		[]string{"Really destroy VM " + jail.Bname + "??"},
-		nil, nil, nil, nil,
-		func(jname string, boolparams []bool, strparams []string) {
-			cbsdDestroyJailDialog.Close(jail.jtui.App)
-			jail.Destroy()
-		},
-	)
-	cbsdDestroyJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+// GetSnapshotCliArgs returns the cbsd arguments creating the snapshot
+func (jail *BhyveVm) GetSnapshotCliArgs(snapname string) []string {
+	// cbsd jsnapshot mode=create snapname=gettimeofday jname=nim1
+	return jail.GetCliArgs(commandJailSnap, "mode=create", fmt.Sprintf("%s=%s", argSnapName, snapname))
 }
 
 func (jail *BhyveVm) Snapshot(snapname string) {
-	// cbsd jsnapshot mode=create snapname=gettimeofday jname=nim1
-	var command string
-	txtheader := "Creating VM snapshot...\n"
-	args := make([]string, 0)
-	if host.USE_DOAS {
-		args = append(args, host.CBSD_PROGRAM)
-	}
-	args = append(args, commandJailSnap)
-	args = append(args, "mode=create")
-	args = append(args, fmt.Sprintf("%s=%s", argSnapName, snapname))
-	args = append(args, fmt.Sprintf("%s=%s", argJailName, jail.Bname))
-	if host.USE_DOAS {
-		command = host.DOAS_PROGRAM
-	} else {
-		command = host.CBSD_PROGRAM
-	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	command, args := host.GetCbsdCommand(jail.GetSnapshotCliArgs(snapname)...)
+	jail.jtui.ExecActionCommand(jail.Bname, tui.ACTION_SNAPSHOT, "Creating VM snapshot...\n", command, args)
 }
 
 func (jail *BhyveVm) OpenSnapshotDialog() {
 	var cbsdSnapshotJailDialog *dialog.Widget
-	cbsdSnapshotJailDialog = jail.jtui.MakeDialogForJail(
+	cbsdSnapshotJailDialog = jail.jtui.MakeValidatedDialogForJail(
 		jail.Bname,
 		"Snapshot VM "+jail.Bname,
 		nil, nil, nil,
-		[]string{"Snapshot name: "}, []string{"gettimeofday"},
+		[]tui.StrField{{Caption: "Snapshot name: ", Default: "gettimeofday", Validate: host.ValidateSnapshotName}},
 		func(jname string, boolparams []bool, strparams []string) {
 			cbsdSnapshotJailDialog.Close(jail.jtui.App)
 			jail.Snapshot(strparams[0])
@@ -614,43 +580,45 @@ func (jail *BhyveVm) OpenSnapshotDialog() {
 	cbsdSnapshotJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
 }
 
-func (jail *BhyveVm) Clone(jnewjname string, jnewhname string, newip string) {
-	//log.Infof("Clone %s to %s (%s) IP %s", jname, jnewjname, jnewhname, newip)
+// GetCloneCliArgs returns the cbsd arguments cloning the VM
+func (jail *BhyveVm) GetCloneCliArgs(jnewjname string, jnewhname string, newip string) []string {
 	// cbsd jclone old=jail1 new=jail1clone host_hostname=jail1clone.domain.local ip4_addr=DHCP checkstate=0
-	var command string
-	txtheader := "Cloning VM...\n"
-
-	args := make([]string, 0)
-	if host.USE_DOAS {
-		args = append(args, host.CBSD_PROGRAM)
+	return []string{
+		commandJailClone,
+		fmt.Sprintf("old=%s", jail.Bname),
+		fmt.Sprintf("new=%s", jnewjname),
+		fmt.Sprintf("host_hostname=%s", jnewhname),
+		fmt.Sprintf("ip4_addr=%s", newip),
+		"checkstate=0",
 	}
-	args = append(args, commandJailClone)
-	args = append(args, fmt.Sprintf("old=%s", jail.Bname))
-	args = append(args, fmt.Sprintf("new=%s", jnewjname))
-	args = append(args, fmt.Sprintf("host_hostname=%s", jnewhname))
-	args = append(args, fmt.Sprintf("ip4_addr=%s", newip))
-	args = append(args, "checkstate=0")
+}
 
-	if host.USE_DOAS {
-		command = host.DOAS_PROGRAM
-	} else {
-		command = host.CBSD_PROGRAM
-	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+func (jail *BhyveVm) Clone(jnewjname string, jnewhname string, newip string) {
+	//log.Infof("Clone %s to %s (%s) IP %s", jname, jnewjname, jnewhname, newip)
+	command, args := host.GetCbsdCommand(jail.GetCloneCliArgs(jnewjname, jnewhname, newip)...)
+	jail.jtui.ExecActionCommand(jail.Bname, tui.ACTION_CLONE, "Cloning VM...\n", command, args)
 	jail.evtRefresh.Emit(nil)
 }
 
 func (jail *BhyveVm) OpenCloneDialog() {
 	var cbsdCloneJailDialog *dialog.Widget
-	cbsdCloneJailDialog = jail.jtui.MakeDialogForJail(
+	newip, hint := host.GetIpSuggestion()
+	names, err := host.GetContainerNames(host.GetCbsdDbConnString(false))
+	if err != nil {
+		host.LogError("Cannot get containers names", err)
+	}
+	cbsdCloneJailDialog = jail.jtui.MakeValidatedDialogForJail(
 		jail.Bname,
 		"Clone VM "+jail.Bname,
-		nil, nil, nil,
-		[]string{"New VM name: ", "New host name: ", "New IP address: "},
-		[]string{jail.Bname + "clone", jail.Bname, "DHCP"},
+		[]string{hint}, nil, nil,
+		[]tui.StrField{
+			{Caption: "New VM name: ", Default: jail.Bname + "clone", Validate: tui.ValidateAll(tui.ValidateName, tui.ValidateUnique(names))},
+			{Caption: "New host name: ", Default: jail.Bname, Validate: tui.ValidateHostname},
+			{Caption: "New IP address: ", Default: newip, Validate: host.ValidateIpAddrs},
+		},
 		func(jname string, boolparams []bool, strparams []string) {
 			cbsdCloneJailDialog.Close(jail.jtui.App)
-			jail.Clone(strparams[0], strparams[1], strparams[2])
+			jail.Clone(strings.TrimSpace(strparams[0]), strings.TrimSpace(strparams[1]), strings.TrimSpace(strparams[2]))
 		},
 	)
 	cbsdCloneJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
@@ -684,16 +652,19 @@ func (jail *BhyveVm) Edit(astart bool, vnc_console string, ip string) {
 func (jail *BhyveVm) OpenEditDialog() {
 	var cbsdEditJailDialog *dialog.Widget
 	if !jail.IsRunning() {
-		cbsdEditJailDialog = jail.jtui.MakeDialogForJail(
+		_, hint := host.GetIpSuggestion()
+		cbsdEditJailDialog = jail.jtui.MakeValidatedDialogForJail(
 			jail.Bname,
 			"Edit VM "+jail.Bname,
-			nil,
+			[]string{hint},
 			[]string{"Autostart "}, []bool{jail.GetAutoStartBool()},
-			[]string{"VNC Console: ", "IP address: "},
-			[]string{jail.GetVncConsoleAddress(), jail.GetAddr()},
+			[]tui.StrField{
+				{Caption: "VNC Console: ", Default: jail.GetVncConsoleAddress(), Validate: tui.ValidateOptional(tui.ValidateHostPort)},
+				{Caption: "IP address: ", Default: jail.GetAddr(), Validate: tui.ValidateOptional(host.ValidateIpAddrs)},
+			},
 			func(jname string, boolparams []bool, strparams []string) {
 				cbsdEditJailDialog.Close(jail.jtui.App)
-				jail.Edit(boolparams[0], strparams[0], strparams[1])
+				jail.Edit(boolparams[0], strings.TrimSpace(strparams[0]), strings.TrimSpace(strparams[1]))
 			},
 		)
 	} else {
@@ -706,7 +677,7 @@ func (jail *BhyveVm) OpenEditDialog() {
 			nil,
 			func(jname string, boolparams []bool, strparams []string) {
 				cbsdEditJailDialog.Close(jail.jtui.App)
-				jail.Edit(boolparams[0], strparams[0], "")
+				jail.Edit(boolparams[0], "", "")
 			},
 		)
 	}
@@ -717,7 +688,7 @@ func (jail *BhyveVm) View() {
 	viewspace := edit.New(edit.Options{ReadOnly: true})
 	outdlg := jail.jtui.CreateActionsLogDialog(viewspace, jail.jtui.Console.Height())
 	outdlg.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.7}, jail.jtui.App)
-	viewspace.SetText(jail.GetJailViewString(), jail.jtui.App)
+	viewspace.SetText(jail.GetJailViewString()+jail.jtui.GetViewExtra(jail.Bname), jail.jtui.App)
 	jail.jtui.App.RedrawTerminal()
 }
 
@@ -727,11 +698,9 @@ func (jail *BhyveVm) StartStop() {
 	var command string
 
 	if jail.IsRunning() {
-		if tui.CbsdJailConsoleActive == jail.Bname {
-			jail.jtui.SendTerminalCommand("exit")
-			tui.CbsdJailConsoleActive = ""
-		}
+		jail.jtui.DetachConsole(jail.Bname)
 		txtheader = "Stopping VM...\n"
+		jail.SetPendingReboot(false)
 		if host.USE_DOAS {
 			args = append(args, host.CBSD_PROGRAM)
 		}
@@ -743,7 +712,7 @@ func (jail *BhyveVm) StartStop() {
 		} else {
 			command = host.CBSD_PROGRAM
 		}
-		jail.jtui.ExecCommand(txtheader, command, args)
+		jail.jtui.ExecActionCommand(jail.Bname, tui.RUN_STOP, txtheader, command, args)
 	} else if jail.IsRunnable() {
 		txtheader = "Starting VM...\n"
 		command = host.SHELL_PROGRAM
@@ -757,7 +726,7 @@ func (jail *BhyveVm) StartStop() {
 		}
 		defer os.Remove(script)
 		args = append(args, script)
-		jail.jtui.ExecShellCommand(txtheader, command, args, host.LOGFILE_JSTART)
+		jail.jtui.ExecActionShellCommand(jail.Bname, tui.RUN_START, txtheader, command, args, host.LOGFILE_JSTART)
 	}
 	_, _ = jail.UpdateJailFromDb(host.GetCbsdDbConnString(false))
 	jail.evtUpdated.Emit(jail.Bname)
@@ -768,9 +737,21 @@ func (jail *BhyveVm) GetStartCommand() string {
 	return cmd
 }
 
-func (jail *BhyveVm) GetLoginCommand() string {
-	cmd := fmt.Sprintf("%s %s=%s", commandJailLogin, argJailName, jail.Bname)
-	return cmd
+func (jail *BhyveVm) GetLoginArgs() []string {
+	return []string{commandJailLogin, argJailName + "=" + jail.Bname}
+}
+
+func (jail *BhyveVm) GetSerialConsoleArgs() []string {
+	return []string{commandJailConsole, argJailName + "=" + jail.Bname}
+}
+
+func (jail *BhyveVm) AttachSerialConsole() {
+	if !jail.IsRunning() {
+		return
+	}
+	program, args := host.GetCbsdCommand(jail.GetSerialConsoleArgs()...)
+	status := "Serial console of " + jail.Bname + ": type " + jail.jtui.GetSerialDetach().Keys + " or 'Ctrl-Z'+'" + jail.jtui.KeyMap.GetKeyName(tui.ACTION_CLOSE_TAB) + "' to detach"
+	jail.jtui.AttachConsole(jail.Bname, append([]string{program}, args...), tui.CONSOLE_SERIAL, status)
 }
 
 func (jail *BhyveVm) CreateScriptStartJail() (string, error) {
@@ -802,87 +783,6 @@ func (jail *BhyveVm) CreateScriptStartJail() (string, error) {
 	return file.Name(), nil
 }
 
-func (jail *BhyveVm) OpenActionDialog() {
-	var cbsdActionsDialog *dialog.Widget
-	var MenuLines []string
-	if jail.IsRunning() {
-		MenuLines = jail.GetStartedActionsMenuItems()
-	} else if jail.IsRunnable() {
-		MenuLines = jail.GetStoppedActionsMenuItems()
-	} else {
-		MenuLines = jail.GetNonRunnableActionsMenuItems()
-	}
-	cbsdActionsDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, "Actions for "+jail.Bname, MenuLines,
-		[]func(jname string){
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.StartStop()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenSnapshotDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenSnapActionsDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.View()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenEditDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenCloneDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.Export()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenDestroyDialog()
-			},
-			func(jname string) {},
-		},
-	)
-	cbsdActionsDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
-}
-
-func (jail *BhyveVm) ExecuteActionOnCommand(command string) {
-	switch command {
-	case ACTIONS: // Actions Menu
-		jail.OpenActionDialog()
-	case VIEW: // View
-		jail.View()
-	case EDIT: // Edit
-		jail.OpenEditDialog()
-	case CLONE: // Clone
-		jail.OpenCloneDialog()
-	case EXPORT: // Export
-		jail.Export()
-	case CREATESNAP: // Create Snapshot
-		jail.OpenSnapshotDialog()
-	case DESTROY: // Destroy
-		jail.OpenDestroyDialog()
-	case DELSNAP: // Destroy Snapshots
-		jail.OpenSnapActionsDialog()
-	case STARTSTOP: // Start/Stop
-		jail.StartStop()
-	}
-}
-
-func (jail *BhyveVm) ExecuteActionOnKey(tkey int16) {
-	for i, k := range keysBottomMenu {
-		if int16(k) == tkey {
-			jail.ExecuteActionOnCommand(strBottomMenuText2[i])
-		}
-	}
-}
-
 func (jail *BhyveVm) GetSnapshots() [][2]string {
 	var snap = [2]string{"", ""}
 	retsnap := make([][2]string, 0)
@@ -964,7 +864,7 @@ func (jail *BhyveVm) DestroySnapshot(snapname string) {
 		command = host.CBSD_PROGRAM
 	}
 	if jail.jtui != nil {
-		jail.jtui.ExecCommand(txtheader, command, args)
+		jail.jtui.ExecActionCommand(jail.Bname, tui.RUN_DESTROY_SNAPSHOT, txtheader, command, args)
 	}
 }
 
@@ -987,6 +887,9 @@ func (jail *BhyveVm) GetAllParams() []string {
 	params := make([]string, 5)
 	params[0] = jail.GetAddr()
 	params[1] = jail.GetStatusString()
+	if jail.IsPendingReboot() {
+		params[1] += " (reboot)"
+	}
 	params[2] = jail.GetAutoStartString()
 	params[3] = jail.OsType
 	params[4] = jail.VncConsole
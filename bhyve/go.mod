@@ -4,16 +4,17 @@ go 1.19
 
 replace github.com/gcla/gowid v1.4.1-0.20221101015339-ce29e21d2804 => github.com/Peter2121/gowid v1.4.1-0.20240308210714-04c038c2ecd2
 replace tui => ../tui
+replace container => ../container
 replace host => ../host
 
 require (
 	github.com/gcla/gowid v1.4.1-0.20221101015339-ce29e21d2804
-	github.com/gdamore/tcell v1.3.1-0.20200115030318-bff4943f9a29
 	github.com/gdamore/tcell/v2 v2.5.0
 	github.com/mattn/go-sqlite3 v1.14.12
 	github.com/sirupsen/logrus v1.4.2
 	github.com/quasilyte/gsignal v0.0.0-20231010082051-3c00e9ebb4e5
 	tui v0.0.1
+	container v0.0.1
 	host v0.0.1
 )
 
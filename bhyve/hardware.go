@@ -0,0 +1,567 @@
+package bhyve
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+
+	"host"
+	"tui"
+)
+
+type BhyveDisk struct {
+	Controller string
+	Path       string
+	Size       string
+	Slot       string
+	Bootable   string
+}
+
+type BhyveNic struct {
+	Id      int
+	Driver  string
+	Parent  string
+	Hwaddr  string
+	Address string
+}
+
+type BhyveHardware struct {
+	Cpus  string
+	Ram   string
+	Boot  string
+	Disks []BhyveDisk
+	Nics  []BhyveNic
+}
+
+var commandVmDisk string = "bhyve-dsk"
+var commandVmNic string = "bhyve-nic"
+
+var strBootDevices = []string{"hdd", "cd", "net"}
+var strDiskControllers = []string{"virtio-blk", "ahci-hd", "nvme"}
+var strNicDrivers = []string{"virtio-net", "e1000"}
+
+const BHYVE_MAX_CPUS int = 256
+
+var regexpSize = regexp.MustCompile(`^[0-9]+[kKmMgGtT]?$`)
+
+func (jail *BhyveVm) IsPendingReboot() bool {
+	return jail.pending
+}
+
+// SetPendingReboot marks the VM changed while running in the state database,
+// the changes are applied after the next restart
+func (jail *BhyveVm) SetPendingReboot(pending bool) {
+	if pending == jail.pending {
+		return
+	}
+	jail.pending = pending
+	if err := host.SetPendingReboot(jail.Bname, pending); err != nil {
+		host.LogError("Cannot save pending reboot of "+jail.Bname+" in "+host.STATE_DB_NAME, err)
+	}
+}
+
+// LoadPendingReboots reads the VMs pending reboot from the state database,
+// the ones stopped since they were changed are not pending anymore
+func LoadPendingReboots(vms []*BhyveVm) {
+	pending, err := host.GetPendingReboots()
+	if err != nil {
+		host.LogError("Cannot read pending reboots from "+host.STATE_DB_NAME, err)
+		return
+	}
+	for _, vm := range vms {
+		if !pending[vm.Bname] {
+			continue
+		}
+		vm.pending = true
+		if !vm.IsRunning() {
+			vm.SetPendingReboot(false)
+		}
+	}
+}
+
+func BytesToSize(str string) string {
+	b, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || b <= 0 {
+		return str
+	}
+	units := []string{"", "k", "m", "g", "t"}
+	i := 0
+	for b%1024 == 0 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%d%s", b, units[i])
+}
+
+func (jail *BhyveVm) GetHardwareFromDb(dbname string) (BhyveHardware, error) {
+	var hw BhyveHardware
+	var ram sql.NullString
+	var boot sql.NullString
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return hw, err
+	}
+	defer db.Close()
+
+	row := db.QueryRow("SELECT vm_cpus,vm_ram,vm_boot FROM bhyve WHERE jname = ?", jail.Bname)
+	if err := row.Scan(&hw.Cpus, &ram, &boot); err != nil {
+		return hw, err
+	}
+	hw.Ram = BytesToSize(ram.String)
+	hw.Boot = boot.String
+
+	hw.Disks, err = jail.GetDisksFromDb(host.GetCbsdVmDbConnString(jail.Bname, false))
+	if err != nil {
+		return hw, err
+	}
+	hw.Nics, err = jail.GetNicsFromDb(host.GetCbsdVmDbConnString(jail.Bname, false))
+	if err != nil {
+		return hw, err
+	}
+	return hw, nil
+}
+
+func (jail *BhyveVm) GetDisksFromDb(dbname string) ([]BhyveDisk, error) {
+	disks := make([]BhyveDisk, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return disks, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT dsk_controller,dsk_path,dsk_size,dsk_slot,bootable FROM bhyvedsk WHERE jname = ?", jail.Bname)
+	if err != nil {
+		return disks, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var controller, path, size, slot, bootable sql.NullString
+		err = rows.Scan(&controller, &path, &size, &slot, &bootable)
+		if err != nil {
+			return disks, err
+		}
+		disks = append(disks, BhyveDisk{
+			Controller: controller.String,
+			Path:       path.String,
+			Size:       BytesToSize(size.String),
+			Slot:       slot.String,
+			Bootable:   bootable.String,
+		})
+	}
+	// the disk booted with the hdd boot device first
+	sort.SliceStable(disks, func(i, j int) bool { return disks[i].IsBootable() && !disks[j].IsBootable() })
+	return disks, nil
+}
+
+func (jail *BhyveVm) GetNicsFromDb(dbname string) ([]BhyveNic, error) {
+	nics := make([]BhyveNic, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return nics, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id,nic_driver,nic_parent,nic_hwaddr,nic_address FROM bhyvenic WHERE jname = ?", jail.Bname)
+	if err != nil {
+		return nics, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var nic BhyveNic
+		var driver, parent, hwaddr, address sql.NullString
+		err = rows.Scan(&nic.Id, &driver, &parent, &hwaddr, &address)
+		if err != nil {
+			return nics, err
+		}
+		nic.Driver = driver.String
+		nic.Parent = parent.String
+		nic.Hwaddr = hwaddr.String
+		nic.Address = address.String
+		nics = append(nics, nic)
+	}
+	return nics, nil
+}
+
+func (jail *BhyveVm) GetHardwareViewString() string {
+	var strview string
+	hw, err := jail.GetHardwareFromDb(host.GetCbsdDbConnString(false))
+	if err != nil {
+		return "Cannot read VM hardware: " + err.Error() + "\n"
+	}
+	strview += "CPUs: " + hw.Cpus + "\n"
+	strview += "RAM: " + hw.Ram + "\n"
+	strview += "Boot device: " + hw.Boot + "\n"
+	for _, d := range hw.Disks {
+		strview += "Disk: " + d.GetDescription() + "\n"
+	}
+	for _, n := range hw.Nics {
+		strview += "NIC: " + n.GetDescription() + "\n"
+	}
+	if jail.IsPendingReboot() {
+		strview += "Pending reboot: changes will be applied after VM restart\n"
+	}
+	return strview
+}
+
+// GetDiskImages returns the descriptions of the disks of the VM
+func (jail *BhyveVm) GetDiskImages() []string {
+	images := make([]string, 0)
+	disks, err := jail.GetDisksFromDb(host.GetCbsdVmDbConnString(jail.Bname, false))
+	if err != nil {
+		host.LogError("Cannot get disks of "+jail.Bname, err)
+		return images
+	}
+	for _, d := range disks {
+		images = append(images, d.GetDescription())
+	}
+	return images
+}
+
+func (d BhyveDisk) IsBootable() bool {
+	return d.Bootable == "true" || d.Bootable == "1"
+}
+
+func (d BhyveDisk) GetDescription() string {
+	desc := d.Path + " " + d.Controller + " " + d.Size
+	if d.IsBootable() {
+		desc += " (bootable)"
+	}
+	return desc
+}
+
+func (n BhyveNic) GetDescription() string {
+	desc := fmt.Sprintf("#%d %s", n.Id, n.Driver)
+	if n.Parent != "" {
+		desc += " on " + n.Parent
+	}
+	if n.Hwaddr != "" && n.Hwaddr != "0" {
+		desc += " " + n.Hwaddr
+	}
+	return desc
+}
+
+func ValidateSize(size string) error {
+	if !regexpSize.MatchString(size) {
+		return fmt.Errorf("Invalid size '%s', use a number with optional k/m/g/t suffix", size)
+	}
+	return nil
+}
+
+// ExecHardwareCommand runs cbsd subcommand with arguments and marks the VM
+// as pending reboot if it is running
+func (jail *BhyveVm) ExecHardwareCommand(txtheader string, subcommand string, params []string) {
+	var command string
+	args := make([]string, 0)
+	if host.USE_DOAS {
+		args = append(args, host.CBSD_PROGRAM)
+	}
+	args = append(args, subcommand)
+	args = append(args, params...)
+	args = append(args, fmt.Sprintf("%s=%s", argJailName, jail.Bname))
+	if host.USE_DOAS {
+		command = host.DOAS_PROGRAM
+	} else {
+		command = host.CBSD_PROGRAM
+	}
+	jail.jtui.ExecCommand(txtheader, command, args)
+	if jail.IsRunning() {
+		jail.SetPendingReboot(true)
+	}
+	jail.evtUpdated.Emit(jail.Bname)
+}
+
+func (jail *BhyveVm) SetCpuRamBoot(cpus string, ram string, boot string) {
+	// cbsd bset jname=vm1 vm_cpus=2 vm_ram=4g vm_boot=hdd
+	params := []string{
+		fmt.Sprintf("vm_cpus=%s", cpus),
+		fmt.Sprintf("vm_ram=%s", ram),
+		fmt.Sprintf("vm_boot=%s", boot),
+	}
+	jail.ExecHardwareCommand("Changing VM hardware...\n", commandJailSetParam, params)
+}
+
+func (jail *BhyveVm) AddDisk(controller string, size string) {
+	// cbsd bhyve-dsk mode=attach jname=vm1 dsk_controller=virtio-blk dsk_size=10g
+	params := []string{
+		"mode=attach",
+		fmt.Sprintf("dsk_controller=%s", controller),
+		fmt.Sprintf("dsk_size=%s", size),
+	}
+	jail.ExecHardwareCommand("Adding VM disk...\n", commandVmDisk, params)
+}
+
+func (jail *BhyveVm) RemoveDisk(disk BhyveDisk) {
+	// cbsd bhyve-dsk mode=detach jname=vm1 dsk_controller=virtio-blk dsk_path=dsk2.vhd
+	params := []string{
+		"mode=detach",
+		fmt.Sprintf("dsk_controller=%s", disk.Controller),
+		fmt.Sprintf("dsk_path=%s", disk.Path),
+	}
+	jail.ExecHardwareCommand("Removing VM disk...\n", commandVmDisk, params)
+}
+
+func (jail *BhyveVm) ResizeDisk(disk BhyveDisk, size string) {
+	// cbsd bhyve-dsk mode=modify jname=vm1 dsk_path=dsk1.vhd dsk_size=20g
+	params := []string{
+		"mode=modify",
+		fmt.Sprintf("dsk_path=%s", disk.Path),
+		fmt.Sprintf("dsk_size=%s", size),
+	}
+	jail.ExecHardwareCommand("Resizing VM disk...\n", commandVmDisk, params)
+}
+
+func (jail *BhyveVm) SetDiskBootable(disk BhyveDisk, bootable bool) {
+	// cbsd bhyve-dsk mode=modify jname=vm1 dsk_path=dsk1.vhd bootable=true
+	params := []string{
+		"mode=modify",
+		fmt.Sprintf("dsk_path=%s", disk.Path),
+		fmt.Sprintf("bootable=%t", bootable),
+	}
+	jail.ExecHardwareCommand("Changing VM disk boot flag...\n", commandVmDisk, params)
+}
+
+func (jail *BhyveVm) AddNic(driver string, parent string) {
+	// cbsd bhyve-nic mode=attach jname=vm1 nic_driver=virtio-net nic_parent=bridge0
+	params := []string{
+		"mode=attach",
+		fmt.Sprintf("nic_driver=%s", driver),
+		fmt.Sprintf("nic_parent=%s", parent),
+	}
+	jail.ExecHardwareCommand("Adding VM NIC...\n", commandVmNic, params)
+}
+
+func (jail *BhyveVm) RemoveNic(nic BhyveNic) {
+	// cbsd bhyve-nic mode=detach jname=vm1 nic_id=2
+	params := []string{
+		"mode=detach",
+		fmt.Sprintf("nic_id=%d", nic.Id),
+	}
+	jail.ExecHardwareCommand("Removing VM NIC...\n", commandVmNic, params)
+}
+
+func (jail *BhyveVm) OpenHardwareErrorDialog(txt []string) {
+	var cbsdErrorDialog *dialog.Widget
+	cbsdErrorDialog = jail.jtui.MakeDialogForJail(
+		jail.Bname,
+		"Hardware of VM "+jail.Bname,
+		txt,
+		nil, nil, nil, nil,
+		nil,
+	)
+	cbsdErrorDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.5}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenHardwareDialog() {
+	var cbsdHardwareDialog *dialog.Widget
+	hw, err := jail.GetHardwareFromDb(host.GetCbsdDbConnString(false))
+	if err != nil {
+		jail.OpenHardwareErrorDialog([]string{"Cannot read VM hardware: " + err.Error()})
+		return
+	}
+	title := "Hardware of " + jail.Bname
+	if jail.IsPendingReboot() {
+		title += " (pending reboot)"
+	}
+	menulines := []string{
+		fmt.Sprintf("CPUs: %s, RAM: %s, Boot: %s", hw.Cpus, hw.Ram, hw.Boot),
+		fmt.Sprintf("Disks (%d)...", len(hw.Disks)),
+		fmt.Sprintf("NICs (%d)...", len(hw.Nics)),
+	}
+	cbsdHardwareDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, title, menulines,
+		[]func(jname string){
+			func(jname string) {
+				cbsdHardwareDialog.Close(jail.jtui.App)
+				jail.OpenCpuRamDialog(hw)
+			},
+			func(jname string) {
+				cbsdHardwareDialog.Close(jail.jtui.App)
+				jail.OpenDisksDialog(hw.Disks)
+			},
+			func(jname string) {
+				cbsdHardwareDialog.Close(jail.jtui.App)
+				jail.OpenNicsDialog(hw.Nics)
+			},
+		},
+	)
+	cbsdHardwareDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenCpuRamDialog(hw BhyveHardware) {
+	var cbsdCpuRamDialog *dialog.Widget
+	cbsdCpuRamDialog = jail.jtui.MakeFormDialog(tui.Form{
+		Title: "CPU/RAM of VM " + jail.Bname,
+		Fields: []tui.FormField{
+			{Name: "cpus", Caption: "CPUs: ", Type: tui.FORM_NUMBER, Default: hw.Cpus, Min: 1, Max: BHYVE_MAX_CPUS},
+			{Name: "ram", Caption: "RAM: ", Default: hw.Ram, Validate: ValidateSize},
+			{Name: "boot", Caption: "Boot device: ", Type: tui.FORM_SELECT, Default: hw.Boot, Options: strBootDevices},
+		},
+		Submit: func(values tui.FormValues) {
+			cbsdCpuRamDialog.Close(jail.jtui.App)
+			cpus, ram, boot := values.String("cpus"), values.String("ram"), values.String("boot")
+			if cpus == hw.Cpus && ram == hw.Ram && boot == hw.Boot {
+				return
+			}
+			jail.SetCpuRamBoot(cpus, ram, boot)
+		},
+	})
+	cbsdCpuRamDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenDisksDialog(disks []BhyveDisk) {
+	var cbsdDisksDialog *dialog.Widget
+	MakeDiskFunction := func(disk BhyveDisk) func(jname string) {
+		return func(jname string) {
+			cbsdDisksDialog.Close(jail.jtui.App)
+			jail.OpenDiskActionsDialog(disk)
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	for _, d := range disks {
+		menulines = append(menulines, d.GetDescription())
+		cbfunc = append(cbfunc, MakeDiskFunction(d))
+	}
+	menulines = append(menulines, "Add disk...")
+	cbfunc = append(cbfunc, func(jname string) {
+		cbsdDisksDialog.Close(jail.jtui.App)
+		jail.OpenAddDiskDialog()
+	})
+	cbsdDisksDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, "Disks of "+jail.Bname, menulines, cbfunc)
+	cbsdDisksDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenDiskActionsDialog(disk BhyveDisk) {
+	var cbsdDiskDialog *dialog.Widget
+	bootcaption := "Set bootable"
+	if disk.IsBootable() {
+		bootcaption = "Unset bootable"
+	}
+	cbsdDiskDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, "Disk "+disk.Path+" of "+jail.Bname,
+		[]string{"Resize", bootcaption, "Remove"},
+		[]func(jname string){
+			func(jname string) {
+				cbsdDiskDialog.Close(jail.jtui.App)
+				jail.OpenResizeDiskDialog(disk)
+			},
+			func(jname string) {
+				cbsdDiskDialog.Close(jail.jtui.App)
+				jail.SetDiskBootable(disk, !disk.IsBootable())
+			},
+			func(jname string) {
+				cbsdDiskDialog.Close(jail.jtui.App)
+				jail.OpenRemoveDiskDialog(disk)
+			},
+		},
+	)
+	cbsdDiskDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenAddDiskDialog() {
+	var cbsdAddDiskDialog *dialog.Widget
+	cbsdAddDiskDialog = jail.jtui.MakeFormDialog(tui.Form{
+		Title: "Add disk to VM " + jail.Bname,
+		Fields: []tui.FormField{
+			{Name: "controller", Caption: "Controller: ", Type: tui.FORM_SELECT, Options: strDiskControllers},
+			{Name: "size", Caption: "Size: ", Default: "10g", Validate: ValidateSize},
+		},
+		Submit: func(values tui.FormValues) {
+			cbsdAddDiskDialog.Close(jail.jtui.App)
+			jail.AddDisk(values.String("controller"), values.String("size"))
+		},
+	})
+	cbsdAddDiskDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenResizeDiskDialog(disk BhyveDisk) {
+	var cbsdResizeDiskDialog *dialog.Widget
+	cbsdResizeDiskDialog = jail.jtui.MakeValidatedDialogForJail(
+		jail.Bname,
+		"Resize disk "+disk.Path+" of VM "+jail.Bname,
+		nil, nil, nil,
+		[]tui.StrField{{Caption: "New size: ", Default: disk.Size, Validate: ValidateSize}},
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdResizeDiskDialog.Close(jail.jtui.App)
+			size := strings.TrimSpace(strparams[0])
+			if size == disk.Size {
+				return
+			}
+			jail.ResizeDisk(disk, size)
+		},
+	)
+	cbsdResizeDiskDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenRemoveDiskDialog(disk BhyveDisk) {
+	var cbsdRemoveDiskDialog *dialog.Widget
+	cbsdRemoveDiskDialog = jail.jtui.MakeDialogForJail(
+		jail.Bname,
+		"Remove disk "+disk.Path+" of VM "+jail.Bname,
+		[]string{"Really remove disk " + disk.Path + "\nof VM " + jail.Bname + "??"},
+		nil, nil, nil, nil,
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdRemoveDiskDialog.Close(jail.jtui.App)
+			jail.RemoveDisk(disk)
+		},
+	)
+	cbsdRemoveDiskDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenNicsDialog(nics []BhyveNic) {
+	var cbsdNicsDialog *dialog.Widget
+	MakeNicFunction := func(nic BhyveNic) func(jname string) {
+		return func(jname string) {
+			cbsdNicsDialog.Close(jail.jtui.App)
+			jail.OpenRemoveNicDialog(nic)
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	for _, n := range nics {
+		menulines = append(menulines, n.GetDescription())
+		cbfunc = append(cbfunc, MakeNicFunction(n))
+	}
+	menulines = append(menulines, "Add NIC...")
+	cbfunc = append(cbfunc, func(jname string) {
+		cbsdNicsDialog.Close(jail.jtui.App)
+		jail.OpenAddNicDialog()
+	})
+	cbsdNicsDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, "NICs of "+jail.Bname, menulines, cbfunc)
+	cbsdNicsDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenAddNicDialog() {
+	var cbsdAddNicDialog *dialog.Widget
+	cbsdAddNicDialog = jail.jtui.MakeFormDialog(tui.Form{
+		Title: "Add NIC to VM " + jail.Bname,
+		Fields: []tui.FormField{
+			{Name: "driver", Caption: "Driver: ", Type: tui.FORM_RADIO, Default: strNicDrivers[0], Options: strNicDrivers},
+			{Name: "parent", Caption: "Parent interface: ", Default: "auto", Validate: tui.ValidateRegexp(`^\S+$`, "must be an interface name or auto")},
+		},
+		Submit: func(values tui.FormValues) {
+			cbsdAddNicDialog.Close(jail.jtui.App)
+			jail.AddNic(values.String("driver"), values.String("parent"))
+		},
+	})
+	cbsdAddNicDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenRemoveNicDialog(nic BhyveNic) {
+	var cbsdRemoveNicDialog *dialog.Widget
+	cbsdRemoveNicDialog = jail.jtui.MakeDialogForJail(
+		jail.Bname,
+		"Remove NIC of VM "+jail.Bname,
+		[]string{"Really remove NIC " + nic.GetDescription() + "\nof VM " + jail.Bname + "??"},
+		nil, nil, nil, nil,
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdRemoveNicDialog.Close(jail.jtui.App)
+			jail.RemoveNic(nic)
+		},
+	)
+	cbsdRemoveNicDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
@@ -0,0 +1,292 @@
+package bhyve
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+	log "github.com/sirupsen/logrus"
+
+	"host"
+)
+
+type VncSettings struct {
+	Bind     string
+	Password string
+}
+
+const VNC_LOCAL_BIND string = "127.0.0.1"
+const VNC_PASSWORD_LENGTH int = 8
+const vncPasswordChars string = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func GenerateVncPassword() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(vncPasswordChars)))
+	for i := 0; i < VNC_PASSWORD_LENGTH; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(vncPasswordChars[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// IsVncSecured tells if the original VNC settings of the VM are saved to be reverted
+func (jail *BhyveVm) IsVncSecured() bool {
+	saved, err := host.GetSavedVnc()
+	if err != nil {
+		host.LogError("Cannot read saved VNC settings from "+host.STATE_DB_NAME, err)
+	}
+	_, found := saved[jail.Bname]
+	return found
+}
+
+func (jail *BhyveVm) GetVncSettingsFromDb(dbname string) (VncSettings, error) {
+	var vs VncSettings
+	var bind, password sql.NullString
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return vs, err
+	}
+	defer db.Close()
+
+	row := db.QueryRow("SELECT bhyve_vnc_tcp_bind,vnc_password FROM bhyve WHERE jname = ?", jail.Bname)
+	if err := row.Scan(&bind, &password); err != nil {
+		return vs, err
+	}
+	vs.Bind = bind.String
+	vs.Password = password.String
+	return vs, nil
+}
+
+func (jail *BhyveVm) SetVncSettings(vs VncSettings) error {
+	// cbsd bset jname=vm1 bhyve_vnc_tcp_bind=127.0.0.1 vnc_password=secret
+	var command string
+	args := make([]string, 0)
+	if host.USE_DOAS {
+		args = append(args, host.CBSD_PROGRAM)
+	}
+	args = append(args, commandJailSetParam)
+	args = append(args, fmt.Sprintf("bhyve_vnc_tcp_bind=%s", vs.Bind))
+	args = append(args, fmt.Sprintf("vnc_password=%s", vs.Password))
+	args = append(args, fmt.Sprintf("%s=%s", argJailName, jail.Bname))
+	if host.USE_DOAS {
+		command = host.DOAS_PROGRAM
+	} else {
+		command = host.CBSD_PROGRAM
+	}
+	cmd := exec.Command(command, args...)
+	err := cmd.Run()
+	if err != nil {
+		return err
+	}
+	if jail.IsRunning() {
+		jail.SetPendingReboot(true)
+	}
+	_, _ = jail.UpdateJailFromDb(host.GetCbsdDbConnString(false))
+	return nil
+}
+
+// SecureVnc rebinds VNC console to VNC_LOCAL_BIND with a generated password,
+// the original settings are saved in the state database before to be restored by RevertVnc
+func (jail *BhyveVm) SecureVnc() (string, error) {
+	vs, err := jail.GetVncSettingsFromDb(host.GetCbsdDbConnString(false))
+	if err != nil {
+		return "", err
+	}
+	// the original settings are kept if the VM is already secured
+	if err = host.SaveVnc(jail.Bname, vs.Bind, vs.Password); err != nil {
+		return "", err
+	}
+	password, err := GenerateVncPassword()
+	if err != nil {
+		return "", err
+	}
+	err = jail.SetVncSettings(VncSettings{Bind: VNC_LOCAL_BIND, Password: password})
+	if err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func (jail *BhyveVm) RevertVnc() error {
+	saved, err := host.GetSavedVnc()
+	if err != nil {
+		return err
+	}
+	sv, found := saved[jail.Bname]
+	if !found {
+		return nil
+	}
+	return jail.revertVnc(sv)
+}
+
+func (jail *BhyveVm) revertVnc(sv host.SavedVnc) error {
+	password, err := host.GetSavedVncPassword(jail.Bname)
+	if err != nil {
+		return err
+	}
+	if err = jail.SetVncSettings(VncSettings{Bind: sv.Bind, Password: password}); err != nil {
+		return err
+	}
+	return host.ForgetVnc(jail.Bname)
+}
+
+// RevertAllVnc restores the original VNC settings of all the VMs secured during
+// the session or by a session which was killed, it is called on program exit
+func RevertAllVnc() {
+	saved, err := host.GetSavedVnc()
+	if err != nil {
+		host.LogError("Cannot read saved VNC settings from "+host.STATE_DB_NAME, err)
+		return
+	}
+	for bname, sv := range saved {
+		if !sv.IsOwned() {
+			continue
+		}
+		jail := New()
+		jail.Bname = bname
+		if err = jail.revertVnc(sv); err != nil {
+			host.LogError("Cannot revert VNC settings of "+bname, err)
+		}
+	}
+}
+
+func GetSshDestination() string {
+	sshhost := host.Cfg.SshHost
+	if sshhost == "" {
+		sshhost, _ = os.Hostname()
+	}
+	sshuser := host.Cfg.SshUser
+	if sshuser == "" {
+		if curuser, err := user.Current(); err == nil {
+			sshuser = curuser.Username
+		}
+	}
+	if sshuser == "" {
+		return sshhost
+	}
+	return sshuser + "@" + sshhost
+}
+
+func (jail *BhyveVm) GetVncTunnelCommand() string {
+	addr, port := jail.GetVncParams()
+	if addr == "" || addr == "0.0.0.0" {
+		addr = VNC_LOCAL_BIND
+	}
+	return fmt.Sprintf("ssh -N -L %d:%s:%d %s", port, addr, port, GetSshDestination())
+}
+
+func (jail *BhyveVm) LaunchVncViewer() error {
+	addr, port := jail.GetVncParams()
+	if port == 0 {
+		return fmt.Errorf("Cannot get valid VNC params from %s", jail.VncConsole)
+	}
+	if addr == "" || addr == "0.0.0.0" {
+		addr = VNC_LOCAL_BIND
+	}
+	viewer := strings.Fields(host.Cfg.VncViewer)
+	if len(viewer) < 1 {
+		return fmt.Errorf("VNC viewer is not configured in %s", host.CONFIG_FILE_NAME)
+	}
+	vncaddr := fmt.Sprintf("%s:%d", addr, port)
+	if strings.Contains(host.Cfg.VncViewer, "%s") {
+		for i := range viewer {
+			viewer[i] = strings.ReplaceAll(viewer[i], "%s", vncaddr)
+		}
+	} else {
+		viewer = append(viewer, vncaddr)
+	}
+	cmd := exec.Command(viewer[0], viewer[1:]...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	log.Infof("Started VNC viewer %v for %s", viewer, jail.Bname)
+	go cmd.Wait()
+	return nil
+}
+
+func (jail *BhyveVm) OpenVncInfoDialog(title string, txt []string) {
+	var cbsdVncInfoDialog *dialog.Widget
+	cbsdVncInfoDialog = jail.jtui.MakeDialogForJail(
+		jail.Bname,
+		title,
+		txt,
+		nil, nil, nil, nil,
+		nil,
+	)
+	cbsdVncInfoDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.6}, jail.jtui.App)
+}
+
+func (jail *BhyveVm) OpenVncDialog() {
+	var cbsdVncDialog *dialog.Widget
+	var menulines []string
+	var cbfunc []func(jname string)
+	if host.Cfg.VncViewer != "" {
+		menulines = append(menulines, "Launch VNC viewer")
+		cbfunc = append(cbfunc, func(jname string) {
+			cbsdVncDialog.Close(jail.jtui.App)
+			err := jail.LaunchVncViewer()
+			if err != nil {
+				jail.OpenVncInfoDialog("VNC console of "+jail.Bname, []string{"Cannot start VNC viewer: " + err.Error()})
+			}
+		})
+	}
+	menulines = append(menulines, "Show SSH tunnel command")
+	cbfunc = append(cbfunc, func(jname string) {
+		cbsdVncDialog.Close(jail.jtui.App)
+		_, port := jail.GetVncParams()
+		jail.OpenVncInfoDialog("VNC console of "+jail.Bname, []string{
+			"Run on your workstation:",
+			jail.GetVncTunnelCommand(),
+			"then connect VNC viewer to " + fmt.Sprintf("%s:%d", VNC_LOCAL_BIND, port),
+		})
+	})
+	if !jail.IsVncSecured() {
+		menulines = append(menulines, "Bind VNC to "+VNC_LOCAL_BIND+" with password")
+	} else {
+		menulines = append(menulines, "Regenerate VNC password")
+	}
+	cbfunc = append(cbfunc, func(jname string) {
+		cbsdVncDialog.Close(jail.jtui.App)
+		password, err := jail.SecureVnc()
+		if err != nil {
+			jail.OpenVncInfoDialog("VNC console of "+jail.Bname, []string{"Cannot change VNC settings: " + err.Error()})
+			return
+		}
+		jail.evtUpdated.Emit(jail.Bname)
+		txt := []string{
+			"VNC is bound to " + VNC_LOCAL_BIND + ", password: " + password,
+			"Run on your workstation:",
+			jail.GetVncTunnelCommand(),
+			"The original settings will be restored on exit",
+		}
+		if jail.IsRunning() {
+			txt = append(txt, "VM must be restarted to apply the new settings")
+		}
+		jail.OpenVncInfoDialog("VNC console of "+jail.Bname, txt)
+	})
+	if jail.IsVncSecured() {
+		menulines = append(menulines, "Revert VNC settings")
+		cbfunc = append(cbfunc, func(jname string) {
+			cbsdVncDialog.Close(jail.jtui.App)
+			err := jail.RevertVnc()
+			if err != nil {
+				jail.OpenVncInfoDialog("VNC console of "+jail.Bname, []string{"Cannot revert VNC settings: " + err.Error()})
+				return
+			}
+			jail.evtUpdated.Emit(jail.Bname)
+		})
+	}
+	cbsdVncDialog = jail.jtui.MakeActionDialogForJail(jail.Bname, "VNC console "+jail.VncConsole, menulines, cbfunc)
+	cbsdVncDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
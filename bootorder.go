@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+
+	"container"
+	"host"
+	"tui"
+)
+
+// Time to wait for a container of a group to reach the running or stopped status
+const GROUP_STATUS_TIMEOUT time.Duration = 120 * time.Second
+const GROUP_STATUS_POLL time.Duration = 2 * time.Second
+
+const BOOTORDER_MAX int = 99999
+
+// cbsd commands changing the bootorder of the containers by emulator
+var bootOrderCommands = map[string]string{
+	"jail":  "jset",
+	"bhyve": "bset",
+}
+
+// GetAllContainers reads the containers of all the types by name, the errors of the
+// types which failed to load are returned too since their containers are missing
+func GetAllContainers(dbname string) (map[string]Container, []error) {
+	all := make(map[string]Container)
+	errs := make([]error, 0)
+	for _, ct := range container.GetTypes() {
+		containers, err := ct.Load(dbname)
+		if err != nil {
+			host.LogError("Cannot load containers of type "+ct.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", ct.Name, err))
+			continue
+		}
+		for _, c := range containers {
+			all[c.GetName()] = c
+		}
+	}
+	return all, errs
+}
+
+func GetContainerEmulator(c Container) string {
+	ct, _ := container.GetType(c.GetType())
+	return ct.Emulator
+}
+
+func GetNames(all map[string]Container) []string {
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ValidateDepends checks the dependencies of jname exist and make no cycle
+func ValidateDepends(jname string, all map[string]Container, bootorders map[string]int, depends host.Depends) tui.Validator {
+	return func(value string) error {
+		deps := host.ParseDepends(value)
+		for _, d := range deps {
+			if d == jname {
+				return fmt.Errorf("'%s' cannot depend on itself", d)
+			}
+			if _, found := all[d]; !found {
+				return fmt.Errorf("no jail or VM '%s'", d)
+			}
+		}
+		changed := make(host.Depends)
+		for name, ds := range depends {
+			changed[name] = ds
+		}
+		changed[jname] = deps
+		_, err := host.OrderByDepends(GetNames(all), bootorders, changed)
+		return err
+	}
+}
+
+// WaitForStatus polls the status of the container until it is running (status 1)
+// or stopped (status 0), the containers without status check are not waited for
+func WaitForStatus(c Container, status int, timeout time.Duration) bool {
+	sc, ok := c.(container.StatusChecker)
+	if !ok {
+		return true
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if sc.GetCurrentStatus() == status {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(GROUP_STATUS_POLL)
+	}
+}
+
+// GetGroupOrder returns the containers to start with their dependencies in the start order
+// or the containers to stop with their dependents in the stop order
+func GetGroupOrder(names []string, start bool) ([]string, error) {
+	depends, err := host.LoadDepends()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load dependencies from %s: %w", host.STATE_DB_NAME, err)
+	}
+	bootorders, err := host.GetBootOrders(host.GetCbsdDbConnString(false))
+	if err != nil {
+		host.LogError("Cannot get boot order", err)
+	}
+	if start {
+		names = host.AddDependencies(names, depends)
+	} else {
+		names = host.AddDependents(names, depends)
+	}
+	order, err := host.OrderByDepends(names, bootorders, depends)
+	if err != nil {
+		return nil, err
+	}
+	if !start {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	return order, nil
+}
+
+// RunGroup starts or stops the containers one by one in the dependency order,
+// waiting for each one to reach its status before the next one
+func RunGroup(names []string, start bool) {
+	verb, done, status := "Stopping", "stopped", 0
+	if start {
+		verb, done, status = "Starting", "started", 1
+	}
+	order, err := GetGroupOrder(names, start)
+	if err != nil {
+		mainTui.OpenInfoDialog(verb+" group", []string{err.Error()})
+		return
+	}
+	all, _ := GetAllContainers(host.GetCbsdDbConnString(false))
+	logLine := mainTui.OpenLogDialog(verb + " group: " + strings.Join(order, ", ") + "\n")
+	go func() {
+		defer app.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) { RefreshJailList() }))
+		for _, name := range order {
+			c, found := all[name]
+			if !found {
+				logLine("No jail or VM " + name + ", stopped")
+				return
+			}
+			if c.IsRunning() == start {
+				logLine(name + " is already " + done)
+				continue
+			}
+			args := c.GetActionCliArgs(tui.ACTION_STARTSTOP)
+			if args == nil {
+				logLine(name + " cannot be " + done + ", stopped")
+				return
+			}
+			logLine(verb + " " + name + "...")
+			stateWatcher.ExpectChange(name)
+			runaction, started := GetRunAction(c, tui.ACTION_STARTSTOP), time.Now()
+			out, err := host.RunCbsd(args...)
+			tui.NotifyExecObservers(name, runaction, time.Since(started), err)
+			if out != "" {
+				logLine(out)
+			}
+			if err != nil {
+				host.LogError(verb+" "+name+" failed", err)
+				logLine(err.Error())
+				return
+			}
+			if !WaitForStatus(c, status, GROUP_STATUS_TIMEOUT) {
+				logLine(fmt.Sprintf("%s is not %s after %s, stopped", name, done, GROUP_STATUS_TIMEOUT))
+				return
+			}
+		}
+		logLine("Done")
+	}()
+}
+
+func GetTargetNames() []string {
+	names := make([]string, 0)
+	for _, c := range GetTargetJails() {
+		names = append(names, c.GetName())
+	}
+	return names
+}
+
+func GetBootOrderLine(c Container, bootorder int, deps []string) string {
+	line := fmt.Sprintf("%-20s %-8s bootorder %-5d", c.GetName(), c.GetType(), bootorder)
+	if len(deps) > 0 {
+		line += " after " + strings.Join(deps, ", ")
+	}
+	return line
+}
+
+// OpenBootOrderDialog lists all the jails/VMs in the start order with their bootorder
+// and dependencies, select one to edit them
+func OpenBootOrderDialog() {
+	var bootOrderDialog *dialog.Widget
+	dbname := host.GetCbsdDbConnString(false)
+	depends, err := host.LoadDepends()
+	if err != nil {
+		mainTui.OpenInfoDialog("Boot order", []string{"Cannot load dependencies from " + host.STATE_DB_NAME + ": " + err.Error()})
+		return
+	}
+	bootorders, err := host.GetBootOrders(dbname)
+	if err != nil {
+		host.LogError("Cannot get boot order", err)
+	}
+	all, _ := GetAllContainers(dbname)
+	order, err := host.OrderByDepends(GetNames(all), bootorders, depends)
+	menulines := make([]string, 0)
+	cbfunc := make([]func(jname string), 0)
+	if err != nil {
+		menulines = append(menulines, err.Error())
+		cbfunc = append(cbfunc, func(jname string) {})
+	}
+	targets := GetTargetNames()
+	if len(targets) > 0 {
+		group := strings.Join(targets, ", ")
+		menulines = append(menulines, "Start group: "+group, "Stop group: "+group)
+		cbfunc = append(cbfunc,
+			func(jname string) {
+				bootOrderDialog.Close(app)
+				RunGroup(targets, true)
+			},
+			func(jname string) {
+				bootOrderDialog.Close(app)
+				RunGroup(targets, false)
+			})
+	}
+	for _, name := range order {
+		c := all[name]
+		menulines = append(menulines, GetBootOrderLine(c, bootorders[name], depends[name]))
+		cbfunc = append(cbfunc, func(jname string) {
+			bootOrderDialog.Close(app)
+			OpenBootOrderEditDialog(c, all, bootorders, depends)
+		})
+	}
+	bootOrderDialog = mainTui.MakeActionDialogForJail("", "Boot order", menulines, cbfunc)
+	bootOrderDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.8}, app)
+}
+
+// OpenBootOrderEditDialog edits the dependencies of the container and its bootorder
+// if cbsd can change it for the emulator
+func OpenBootOrderEditDialog(c Container, all map[string]Container, bootorders map[string]int, depends host.Depends) {
+	var editDialog *dialog.Widget
+	jname := c.GetName()
+	setcmd, canset := bootOrderCommands[GetContainerEmulator(c)]
+	fields := make([]tui.FormField, 0)
+	if canset {
+		fields = append(fields, tui.FormField{Name: "bootorder", Caption: "Boot order: ", Type: tui.FORM_NUMBER,
+			Default: strconv.Itoa(bootorders[jname]), Min: 0, Max: BOOTORDER_MAX})
+	}
+	fields = append(fields, tui.FormField{Name: "depends", Caption: "Start after (names separated by commas): ",
+		Default: strings.Join(depends[jname], ","), Validate: ValidateDepends(jname, all, bootorders, depends)})
+	editDialog = mainTui.MakeFormDialog(tui.Form{
+		Title:  "Boot order of " + jname,
+		Fields: fields,
+		Submit: func(values tui.FormValues) {
+			editDialog.Close(app)
+			if err := host.SetDepends(jname, host.ParseDepends(values.String("depends"))); err != nil {
+				mainTui.OpenInfoDialog("Boot order", []string{"Cannot save dependencies to " + host.STATE_DB_NAME + ": " + err.Error()})
+				return
+			}
+			if canset && values.Int("bootorder") != bootorders[jname] {
+				command, args := host.GetCbsdCommand(setcmd, "jname="+jname, "bootorder="+values.String("bootorder"))
+				mainTui.ExecCommand("Changing boot order of "+jname+"...\n", command, args)
+				return
+			}
+			OpenBootOrderDialog()
+		},
+	})
+	editDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
+}
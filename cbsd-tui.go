@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
-	"time"
+	"syscall"
 
 	"github.com/gcla/gowid"
 	"github.com/gcla/gowid/vim"
@@ -25,10 +27,13 @@ import (
 	"github.com/gcla/gowid/widgets/text"
 	"github.com/gcla/gowid/widgets/vpadding"
 
-	"bhyve"
+	_ "bhyve"
+	"container"
 	"host"
-	"jail"
+	_ "jail"
+	_ "qemu"
 	"tui"
+	_ "xen"
 
 	tcell "github.com/gdamore/tcell/v2"
 	log "github.com/sirupsen/logrus"
@@ -49,25 +54,35 @@ var ctype string = CTYPE_JAIL
 //var ctype string = "bhyvevm"
 
 var txtProgramName = "CBSD-TUI"
-var txtHelp = `- To navigate in jails/VMs list use 'Up' and 'Down' keys or mouse
-- To open 'Actions' menu for the selected jail/VM use 'F2' key
-- To switch to jails management use 'Ctrl-J'
-- To switch to Bhyve VMs management use 'Ctrl-B'
-- To login into the selected jail/VM use 'Enter' key or mouse double-click on jail/VM name
-- To switch to terminal from jails/VMs list use 'Tab' key
-- To switch to jails/VMs list from terminal use 'Ctrl-Z'+'Tab' keys sequence
-- Use bottom menu ('Fx' keys or mouse clicks) to start actions on the selected jail/VM`
+var txtHelp = "Help"
+var txtExit = "Exit"
+var txtHelpHeader = []string{
+	"- To navigate in jails/VMs list use 'Up' and 'Down' keys or mouse",
+	"- To login into jail/VM with mouse double-click on its name, each login opens a terminal tab",
+	"- To attach the serial console of the selected VM use 'Serial console' in 'Actions' menu",
+	"- To switch to terminal tab N use 'Alt-N'",
+	"- To resize the list and the terminal with mouse drag the separator line",
+}
+var txtHelpFooter = []string{
+	"- From terminal press 'Ctrl-Z' before the keys above, e.g. 'Ctrl-Z'+'Tab' to return to the list",
+	"- Use bottom menu (keys or mouse clicks) to start actions on the selected jail/VM",
+}
 
 var logFileName = "/var/log/cbsd-tui.log"
 
 var cbsdListLines [][]gowid.IWidget
+var cbsdLineJails []int    // index in Containers of each line
+var cbsdListRows []ListRow // rows of the list after the header
 var cbsdListGrid []gowid.IWidget
 var cbsdListWalker *list.SimpleListWalker
 
 // var cbsdBottomMenu []gowid.IContainerWidget
 var cbsdJailConsole *terminal.Widget
-var cbsdWidgets *ResizeablePileWidget
-var cbsdJailConsoleActive string
+var cbsdWidgets *LayoutWidget
+var dashboard *DashboardWidget
+var containerPoller = NewContainerPoller()
+var healthChecker = NewHealthChecker()
+var stateWatcher = NewStateWatcher()
 var WIDTH = 18
 var HPAD = 2
 var VPAD = 1
@@ -76,7 +91,7 @@ var Containers []Container
 var mainTui *tui.Tui
 var gHeader *grid.Widget
 var gBmenu *columns.Widget
-var menuPanel *ResizeablePileWidget
+var menuPanel *pile.Widget
 
 var lastFocusPosition int
 
@@ -101,25 +116,91 @@ func OpenHelpDialog() {
 	HelpDialog = mainTui.MakeDialogForJail(
 		"",
 		txtProgramName,
-		[]string{txtHelp},
+		[]string{GetHelpText()},
 		nil, nil, nil, nil,
 		nil,
 	)
 	HelpDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
 }
 
-func RunMenuAction(action string) {
-	log.Infof("Menu Action: " + action)
-
-	if len(Containers) > 0 {
-		switch action {
-		// "[F1]Help ",      "[F2]Actions Menu ",    "[F3]View ",     "[F4]Edit ",     "[F5]Clone ",
-		// "[F6]Export ",    "[F7]Create Snapshot ", "[F8]Destroy ",  "[F10]Exit ",    "[F11]List Snapshots ", "[F12]Start/Stop"
-		case Containers[0].GetCommandHelp(): // Help
-			OpenHelpDialog()
+// RunAction runs the key map action, the actions not handled here
+// are executed on the selected jail/VM
+func RunAction(action string) {
+	log.Infof("Action: " + action)
+	switch action {
+	case tui.ACTION_HELP:
+		OpenHelpDialog()
+		return
+	case tui.ACTION_EXIT:
+		app.Quit()
+		return
+	case tui.ACTION_FOCUS:
+		// Ctrl-Z + focus key from terminal
+		cbsdWidgets.SetFocus(app, tui.FOCUS_ON_LIST)
+		RestoreFocus()
+		return
+	case tui.ACTION_REFRESH:
+		RefreshJailList()
+		return
+	case tui.ACTION_RECORDINGS:
+		OpenRecordingsDialog()
+		return
+	case tui.ACTION_NEXT_TAB:
+		mainTui.NextTab()
+		return
+	case tui.ACTION_CLOSE_TAB:
+		mainTui.CloseActiveTab()
+		return
+	case tui.ACTION_THEMES:
+		OpenThemesDialog()
+		return
+	case tui.ACTION_LAYOUT:
+		cbsdWidgets.ToggleLayout(app)
+		return
+	case tui.ACTION_MAXIMIZE:
+		cbsdWidgets.ToggleMaximize(app)
+		return
+	case tui.ACTION_GROW:
+		cbsdWidgets.Resize(app, LAYOUT_RESIZE_STEP)
+		return
+	case tui.ACTION_SHRINK:
+		cbsdWidgets.Resize(app, -LAYOUT_RESIZE_STEP)
+		return
+	case tui.ACTION_PALETTE:
+		OpenCommandPalette()
+		return
+	case tui.ACTION_UNMARK:
+		ClearMarks()
+		return
+	case tui.ACTION_DASHBOARD:
+		dashboard.Toggle(app)
+		return
+	case tui.ACTION_NETWORK:
+		OpenNetworkDialog()
+		return
+	case tui.ACTION_BOOTORDER:
+		OpenBootOrderDialog()
+		return
+	case tui.ACTION_GROUPSTART:
+		RunGroup(GetTargetNames(), true)
+		return
+	case tui.ACTION_GROUPSTOP:
+		RunGroup(GetTargetNames(), false)
+		return
+	case tui.ACTION_GROUPBYTAG:
+		ToggleGroupByTag()
+		return
+	case tui.ACTION_MARKTAG:
+		OpenMarkTagDialog()
+		return
+	case tui.ACTION_NOTICES:
+		OpenNoticesDialog()
+		return
+	}
+	for _, ct := range container.GetTypes() {
+		if ct.Action == action {
+			SwitchContainerType(ct.Name)
 			return
-		case Containers[0].GetCommandExit(): // Exit
-			app.Quit()
 		}
 	}
 
@@ -128,44 +209,111 @@ func RunMenuAction(action string) {
 		return
 	}
 	log.Infof("JailName: " + curjail.GetName())
-	curjail.ExecuteActionOnCommand(action)
+	switch action {
+	case tui.ACTION_LOGIN:
+		LoginToJail(curjail.GetName(), mainTui)
+		return
+	case tui.ACTION_MARK:
+		ToggleMark(curjail.GetName())
+		return
+	case tui.ACTION_STORAGE:
+		OpenStorageDialog(curjail)
+		return
+	case tui.ACTION_TAGS:
+		OpenTagsDialog(curjail.GetName())
+		return
+	}
+	curjail.ExecuteAction(action)
+}
+
+func SwitchContainerType(newtype string) {
+	if ctype != newtype {
+		oldtype := ctype
+		ctype = newtype
+		if !RefreshJailList() {
+			ctype = oldtype
+		}
+	}
 }
 
 func GetSelectedJail() Container {
 	curpos := GetSelectedPosition()
-	if curpos < 0 {
-		return nil
-	}
-	if len(Containers) < curpos {
+	if curpos < 0 || curpos >= len(cbsdLineJails) {
 		return nil
 	}
-	return Containers[curpos]
+	return Containers[cbsdLineJails[curpos]]
 }
 
+// GetSelectedPosition returns the index of the selected line in cbsdListLines,
+// -1 if the header or a tag section is selected
 func GetSelectedPosition() int {
 	ifocus := cbsdListJails.Walker().Focus()
-	return int(ifocus.(list.ListPos)) - 1
+	row := int(ifocus.(list.ListPos)) - 1
+	if row < 0 || row >= len(cbsdListRows) {
+		return -1
+	}
+	return cbsdListRows[row].Line
 }
 
-func RefreshJailList() {
-	var err error
-	Containers, err = GetContainersFromDb(ctype, host.GetCbsdDbConnString(false))
-	if err != nil {
-		panic(err)
+// GetLineListPos returns the list position of the line of cbsdListLines
+func GetLineListPos(line int) (list.ListPos, bool) {
+	for i, row := range cbsdListRows {
+		if row.Line == line {
+			return list.ListPos(i + 1), true
+		}
 	}
-	cbsdListLines = MakeJailsLines()
+	return list.ListPos(0), false
+}
+
+// MakeJailsListGrid makes the lines of the containers and the rows of the list
+func MakeJailsListGrid() []gowid.IWidget {
+	cbsdListLines, cbsdLineJails, cbsdListRows = MakeJailsLines()
 	cbsdListGrid = make([]gowid.IWidget, 0)
 	gHeader = grid.New(GetJailsListHeader(), WIDTH, HPAD, VPAD, gowid.HAlignMiddle{})
 	cbsdListGrid = append(cbsdListGrid, gHeader)
-	for _, line := range cbsdListLines {
-		gline := grid.New(line, WIDTH, HPAD, VPAD, gowid.HAlignMiddle{},
+	for _, row := range cbsdListRows {
+		if row.Line < 0 {
+			cbsdListGrid = append(cbsdListGrid, MakeTagSection(row.Tag, row.Count))
+			continue
+		}
+		gline := grid.New(cbsdListLines[row.Line], WIDTH, HPAD, VPAD, gowid.HAlignMiddle{},
 			grid.Options{
 				DownKeys: []vim.KeyPress{},
 				UpKeys:   []vim.KeyPress{},
 			})
 		cbsdListGrid = append(cbsdListGrid, gline)
 	}
-	cbsdListWalker = list.NewSimpleListWalker(cbsdListGrid)
+	return cbsdListGrid
+}
+
+// RebuildJailList makes the list again without reloading the containers,
+// the selected container stays selected
+func RebuildJailList() {
+	var jname string
+	if curjail := GetSelectedJail(); curjail != nil {
+		jname = curjail.GetName()
+	}
+	cbsdListWalker = list.NewSimpleListWalker(MakeJailsListGrid())
+	cbsdListJails.SetWalker(cbsdListWalker, app)
+	SetJailListFocus()
+	if jname != "" {
+		SelectJail(jname)
+	}
+}
+
+// RefreshJailList reloads the list from the cbsd database, the current list is kept
+// and false is returned if it cannot be read
+func RefreshJailList() bool {
+	containers, err := GetContainersFromDb(ctype, host.GetCbsdDbConnString(false))
+	if err != nil {
+		host.LogError("Cannot load the containers from the cbsd database", err)
+		mainTui.OpenInfoDialog("Refresh", []string{"Cannot load the containers from the cbsd database: " + err.Error()})
+		return false
+	}
+	Containers = containers
+	PruneMarks()
+	LoadContainerTags()
+	cbsdListWalker = list.NewSimpleListWalker(MakeJailsListGrid())
 	cbsdListJails.SetWalker(cbsdListWalker, app)
 	for i := range Containers {
 		Containers[i].SetTui(mainTui)
@@ -175,8 +323,9 @@ func RefreshJailList() {
 		Containers[i].GetSignalUpdated().Connect(nil, func(jname string) { UpdateJailLine(GetJailByName(jname)) })
 	}
 	gBmenu = columns.New(MakeBottomMenu(), columns.Options{DoNotSetSelected: true, LeftKeys: make([]vim.KeyPress, 0), RightKeys: make([]vim.KeyPress, 0)})
-	menuPanel.Widget.SetSubWidgets([]gowid.IWidget{gBmenu}, app)
+	menuPanel.SetSubWidgets([]gowid.IWidget{gBmenu}, app)
 	SetJailListFocus()
+	return true
 }
 
 func UpdateJailLine(jail Container) {
@@ -191,21 +340,26 @@ func UpdateJailLine(jail Container) {
 		//	var cbsdJlsHeader = []string{"NAME", "IP4_ADDRESS", "STATUS", "AUTOSTART", "VERSION"}
 
 		line[0] = GetMenuButton(jail, "")
-		jail_params := jail.GetAllParams()
-		for i, param := range jail_params {
-			line[i+1] = GetStyledWidget(text.New(param, HALIGN_MIDDLE), style)
+		for i, cell := range MakeParamCells(jail, style) {
+			line[i+1] = cell
 		}
 	}
 }
 
 func ChangeJailBtnColor(color string, position int) {
+	if position < 0 || position >= len(cbsdListLines) {
+		return
+	}
 	line := cbsdListLines[position]
-	jail := Containers[position]
+	jail := Containers[cbsdLineJails[position]]
 	line[0] = GetMenuButton(jail, color)
 }
 
 func GetMenuButton(jail Container, style string) *keypress.Widget {
 	btxt := text.New(jail.GetName(), HALIGN_MIDDLE)
+	if len(style) == 0 && markedJails[jail.GetName()] {
+		style = "marked"
+	}
 	if len(style) == 0 {
 		style = GetJailStyle(jail.GetStatus(), jail.GetAstart())
 	}
@@ -221,14 +375,8 @@ func GetMenuButton(jail Container, style string) *keypress.Widget {
 	kpbtn := keypress.New(
 		cellmod.Opaque(btnnew),
 		keypress.Options{
-			Keys: []gowid.IKey{
-				gowid.MakeKeyExt(tcell.KeyEnter),
-				gowid.MakeKeyExt(tcell.KeyF2),
-				gowid.MakeKeyExt(tcell.KeyTab),
-				gowid.MakeKeyExt(tcell.KeyCtrlR),
-				gowid.MakeKeyExt(tcell.KeyCtrlJ),
-				gowid.MakeKeyExt(tcell.KeyCtrlB),
-			},
+			Keys: keyMap.GetGowidKeys(append(container.GetTypeActions(), tui.ACTION_LOGIN, tui.ACTION_ACTIONS, tui.ACTION_FOCUS,
+				tui.ACTION_REFRESH, tui.ACTION_MARK)...),
 		},
 	)
 	kpbtn.OnKeyPress(keypress.MakeCallback("kpbtn_"+btxt.Content().String(), func(app gowid.IApp, w gowid.IWidget, k gowid.IKey) {
@@ -249,32 +397,26 @@ func GetJailByName(jname string) Container {
 }
 
 func LoginToJail(jname string, t *tui.Tui) {
-	if jname == cbsdJailConsoleActive {
-		t.SendTerminalCommand("\x03")
-		t.SendTerminalCommand("exit")
-		cbsdJailConsoleActive = ""
-		t.ResetTerminal()
-		RestoreFocus()
+	jail := GetJailByName(jname)
+	if jail == nil || !jail.IsRunning() {
 		return
 	}
-	jail := GetJailByName(jname)
-	if jail != nil && jail.IsRunning() {
-		if cbsdJailConsoleActive != "" {
-			t.SendTerminalCommand("\x03")
-			t.SendTerminalCommand("exit")
-			t.ResetTerminal()
-			RestoreFocus()
-		}
-		if host.USE_DOAS {
-			t.SendTerminalCommand(host.DOAS_PROGRAM + " " + host.CBSD_PROGRAM + " " + jail.GetLoginCommand())
-		} else {
-			t.SendTerminalCommand(host.CBSD_PROGRAM + " " + jail.GetLoginCommand())
+	if loginable, ok := jail.(container.Loginable); ok {
+		program, args := host.GetCbsdCommand(loginable.GetLoginArgs()...)
+		status := "'Ctrl-Z'+'" + keyMap.GetKeyName(tui.ACTION_FOCUS) + "' to return to the list, 'Ctrl-Z'+'" + keyMap.GetKeyName(tui.ACTION_CLOSE_TAB) + "' to log out"
+		command, err := GetRecordedCommand(jname, append([]string{program}, args...))
+		if err != nil {
+			status = "Not recorded: " + err.Error() + "; " + status
 		}
-		cbsdJailConsoleActive = jname
+		t.AttachConsole(jname, command, tui.CONSOLE_LOGIN, status)
+	}
+}
+
+func OnConsoleChanged(jname string) {
+	if jname != "" {
 		ReleaseFocus()
-		if cbsdWidgets.Focus() == 0 {
-			cbsdWidgets.SetFocus(app, tui.FOCUS_ON_TERMINAL)
-		}
+	} else {
+		RestoreFocus()
 	}
 }
 
@@ -282,7 +424,7 @@ func GetJailsListHeader() []gowid.IWidget {
 	header := make([]gowid.IWidget, 0)
 	titles := make([]string, 0)
 	if len(Containers) > 0 {
-		titles = Containers[0].GetHeaderTitles()
+		titles = append(append(titles, Containers[0].GetHeaderTitles()...), HEALTH_TITLE, TAGS_TITLE)
 	}
 	for _, h := range titles {
 		htext := text.New(h, HALIGN_MIDDLE)
@@ -308,43 +450,52 @@ func GetJailStyle(jailstatus int, jailastart int) string {
 
 func SetJailListFocus() {
 	var newpos list.ListPos
-	if len(Containers) > 0 {
+	if len(cbsdListRows) > 0 {
 		newpos = list.ListPos(1)
 	} else {
 		newpos = list.ListPos(0)
 	}
-	for i, jail := range Containers {
-		if jail.IsRunning() {
-			newpos = list.ListPos(i + 1)
-			break
+	for i, c := range cbsdLineJails {
+		if Containers[c].IsRunning() {
+			if pos, found := GetLineListPos(i); found {
+				newpos = pos
+				break
+			}
 		}
 	}
 	cbsdListJails.Walker().SetFocus(newpos, app)
 }
 
+// SelectJail moves the list focus to the container
+func SelectJail(jname string) {
+	for i, c := range cbsdLineJails {
+		if Containers[c].GetName() == jname {
+			if pos, found := GetLineListPos(i); found {
+				cbsdListJails.Walker().SetFocus(pos, app)
+				return
+			}
+		}
+	}
+}
+
 func JailListButtonCallBack(jname string, key gowid.IKey) {
-	switch key.Key() {
-	case tcell.KeyEnter:
+	action, found := keyMap.GetAction(key)
+	if !found {
+		return
+	}
+	switch action {
+	case tui.ACTION_LOGIN:
 		LoginToJail(jname, mainTui)
-	case tcell.KeyF2:
-		curjail := GetJailByName(jname)
-		curjail.OpenActionDialog()
-	case tcell.KeyCtrlR:
-		RefreshJailList()
-	case tcell.KeyCtrlJ:
-		if ctype != CTYPE_JAIL {
-			ctype = CTYPE_JAIL
-			RefreshJailList()
-		}
-	case tcell.KeyCtrlB:
-		if ctype != CTYPE_BHYVEVM {
-			ctype = CTYPE_BHYVEVM
-			RefreshJailList()
-		}
-	case tcell.KeyTab:
-		// Tab from jails list
+	case tui.ACTION_ACTIONS:
+		GetJailByName(jname).ExecuteAction(action)
+	case tui.ACTION_FOCUS:
+		// focus key from jails list
 		cbsdWidgets.SetFocus(app, tui.FOCUS_ON_TERMINAL)
 		ReleaseFocus()
+	case tui.ACTION_MARK:
+		ToggleMark(jname)
+	default:
+		RunAction(action)
 	}
 }
 
@@ -353,20 +504,49 @@ func MakeGridLine(jail Container) []gowid.IWidget {
 	line := make([]gowid.IWidget, 0)
 	style = GetJailStyle(jail.GetStatus(), jail.GetAstart())
 	line = append(line, GetMenuButton(jail, ""))
-	jail_params := jail.GetAllParams()
-	for _, param := range jail_params {
-		line = append(line, GetStyledWidget(text.New(param, HALIGN_MIDDLE), style))
-	}
+	line = append(line, MakeParamCells(jail, style)...)
 	return line
 }
 
-func MakeJailsLines() [][]gowid.IWidget {
+// MakeParamCells makes the columns of the container line after the name
+func MakeParamCells(jail Container, style string) []gowid.IWidget {
+	cells := make([]gowid.IWidget, 0)
+	for _, param := range jail.GetAllParams() {
+		cells = append(cells, GetStyledWidget(text.New(param, HALIGN_MIDDLE), style))
+	}
+	health, healthStyle := healthChecker.GetCell(jail, style)
+	cells = append(cells, GetStyledWidget(text.New(health, HALIGN_MIDDLE), healthStyle))
+	cells = append(cells, GetStyledWidget(text.New(strings.Join(containerTags[jail.GetName()], ","), HALIGN_MIDDLE), style))
+	return cells
+}
+
+// MakeJailsLines returns the lines of the containers with the indexes of their containers
+// and the rows of the list, grouped by tag if enabled
+func MakeJailsLines() ([][]gowid.IWidget, []int, []ListRow) {
 	lines := make([][]gowid.IWidget, 0)
-	for i := range Containers {
-		line := MakeGridLine(Containers[i])
-		lines = append(lines, line)
+	jails := make([]int, 0)
+	rows := make([]ListRow, 0)
+	AddLine := func(i int, tag string) {
+		rows = append(rows, ListRow{Line: len(lines), Tag: tag})
+		lines = append(lines, MakeGridLine(Containers[i]))
+		jails = append(jails, i)
+	}
+	if !uiState.GroupByTag {
+		for i := range Containers {
+			AddLine(i, "")
+		}
+		return lines, jails, rows
 	}
-	return lines
+	for _, section := range GetTagSections() {
+		rows = append(rows, ListRow{Line: -1, Tag: section.Tag, Count: len(section.Jails)})
+		if IsTagCollapsed(section.Tag) {
+			continue
+		}
+		for _, i := range section.Jails {
+			AddLine(i, section.Tag)
+		}
+	}
+	return lines, jails, rows
 }
 
 func RedirectLogger(path string) *os.File {
@@ -392,43 +572,20 @@ func (h handler) UnhandledInput(app gowid.IApp, ev interface{}) bool {
 	if ok {
 		handled = true
 		//log.Infof(string(evk.Key()))
-		// "[F1]Help ",            "[F2]Actions Menu ", "[F4]Edit ",   "[F5]Clone ",           "[F6]Export ",
-		// "[F7]Create Snapshot ", "[F8]Destroy ",      "[F10]Exit ",  "[F11]List Snapshots ", "[F12]Start/Stop"
-		ekey := evk.Key()
-		switch ekey {
-		case tcell.KeyCtrlC, tcell.KeyEsc, tcell.KeyF10:
-			app.Quit()
-		case tcell.KeyTab:
-			// CtrlZ-Tab from terminal
-			if next, ok := cbsdWidgets.FindNextSelectable(gowid.Forwards, true); ok {
-				cbsdWidgets.SetFocus(app, next)
-			}
-			RestoreFocus()
-			return handled
-		case tcell.KeyF1:
-			OpenHelpDialog()
+		if evk.Key() == tcell.KeyRune && evk.Modifiers()&tcell.ModAlt != 0 && evk.Rune() >= '1' && evk.Rune() <= '9' {
+			mainTui.SwitchTab(int(evk.Rune() - '1'))
 			return handled
 		}
-		curjail := GetSelectedJail()
-		if curjail == nil {
-			return handled
+		if action, found := keyMap.GetAction(evk); found {
+			RunAction(action)
 		}
-		curjail.ExecuteActionOnKey(int16(ekey))
+	}
+	if evm, ok := ev.(*tcell.EventMouse); ok {
+		handled = cbsdWidgets.Drag(app, evm)
 	}
 	return handled
 }
 
-type ResizeablePileWidget struct {
-	*pile.Widget
-	offset int
-}
-
-func NewResizeablePile(widgets []gowid.IContainerWidget) *ResizeablePileWidget {
-	res := &ResizeablePileWidget{}
-	res.Widget = pile.New(widgets)
-	return res
-}
-
 func GetStyledWidget(w gowid.IWidget, color string) *styled.Widget {
 	cfocus := color + "-focus"
 	cnofocus := color + "-nofocus"
@@ -438,16 +595,42 @@ func GetStyledWidget(w gowid.IWidget, color string) *styled.Widget {
 	)
 }
 
-func MakeBottomMenu() []gowid.IContainerWidget {
-	cbsdBottomMenu := make([]gowid.IContainerWidget, 0)
-	menu_text := make([]string, 0)
-	menu_text2 := make([]string, 0)
+// GetBottomMenuActions returns the global and the container type actions
+// ordered by their F-keys, the actions without F-keys are shown last
+func GetBottomMenuActions() []string {
+	actions := []string{tui.ACTION_HELP}
 	if len(Containers) > 0 {
-		menu_text = Containers[0].GetBottomMenuText1()
-		menu_text2 = Containers[0].GetBottomMenuText2()
+		actions = append(actions, Containers[0].GetBottomMenuActions()...)
 	}
-	for i, m := range menu_text2 {
-		mtext1 := text.New(menu_text[i], HALIGN_LEFT)
+	actions = append(actions, tui.ACTION_EXIT)
+	FKeyOrder := func(action string) int {
+		for _, k := range keyMap.GetKeys(action) {
+			if k.Key >= tcell.KeyF1 && k.Key <= tcell.KeyF64 {
+				return int(k.Key - tcell.KeyF1)
+			}
+		}
+		return int(tcell.KeyF64 - tcell.KeyF1 + 1)
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return FKeyOrder(actions[i]) < FKeyOrder(actions[j]) })
+	return actions
+}
+
+func GetBottomMenuCaption(action string) string {
+	switch action {
+	case tui.ACTION_HELP:
+		return txtHelp
+	case tui.ACTION_EXIT:
+		return txtExit
+	}
+	return Containers[0].GetActionCaption(action)
+}
+
+func MakeBottomMenu() []gowid.IContainerWidget {
+	cbsdBottomMenu := make([]gowid.IContainerWidget, 0)
+	for _, action := range GetBottomMenuActions() {
+		action := action
+		m := GetBottomMenuCaption(action)
+		mtext1 := text.New(keyMap.GetKeyLabel(action), HALIGN_LEFT)
 		mtext1st := styled.New(mtext1, gowid.MakePaletteRef("blackgreen"))
 		mtext2 := text.New(m+" ", HALIGN_LEFT)
 		mtext2st := styled.New(mtext2, gowid.MakePaletteRef("graydgreen"))
@@ -459,7 +642,7 @@ func MakeBottomMenu() []gowid.IContainerWidget {
 		mbtn := button.New(mtextgrp, button.Options{Decoration: button.BareDecoration})
 		mbtn.OnClick(gowid.WidgetCallback{Name: "cbb_" + mtext2.Content().String(), WidgetChangedFunction: func(app gowid.IApp, w gowid.IWidget) {
 			app.Run(gowid.RunFunction(func(app gowid.IApp) {
-				RunMenuAction(strings.TrimSpace(mtext2.Content().String()))
+				RunAction(action)
 			}))
 		}})
 		cbsdBottomMenu = append(cbsdBottomMenu, &gowid.ContainerWidget{IWidget: mbtn, D: gowid.RenderFixed{}})
@@ -468,31 +651,7 @@ func MakeBottomMenu() []gowid.IContainerWidget {
 }
 
 func GetContainersFromDb(c_type string, db string) ([]Container, error) {
-	switch c_type {
-	case "jail":
-		jails, err := jail.GetJailsFromDb(db)
-		if err != nil {
-			return make([]Container, 0), err
-		}
-		jlen := len(jails)
-		cont := make([]Container, jlen)
-		for i := range jails {
-			cont[i] = jails[i]
-		}
-		return cont, nil
-	case "bhyvevm":
-		vms, err := bhyve.GetBhyveVmsFromDb(db)
-		if err != nil {
-			return make([]Container, 0), err
-		}
-		jlen := len(vms)
-		cont := make([]Container, jlen)
-		for i := range vms {
-			cont[i] = vms[i]
-		}
-		return cont, nil
-	}
-	return make([]Container, 0), nil
+	return container.Load(c_type, db)
 }
 
 func ReleaseFocus() {
@@ -509,35 +668,8 @@ func RestoreFocus() {
 
 func main() {
 	var err error
-	g23color, _ := gowid.MakeColorSafe("g23")
-	//g7color, _ := gowid.MakeColorSafe("g7")
-	palette := gowid.Palette{
-		"red-nofocus":      gowid.MakePaletteEntry(gowid.ColorPurple, gowid.ColorNone),
-		"red-focus":        gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorPurple),
-		"green-nofocus":    gowid.MakePaletteEntry(gowid.ColorGreen, gowid.ColorNone),
-		"green-focus":      gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorGreen),
-		"white-nofocus":    gowid.MakePaletteEntry(gowid.ColorWhite, gowid.ColorNone),
-		"white-focus":      gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorWhite),
-		"gray-nofocus":     gowid.MakePaletteEntry(gowid.ColorLightGray, gowid.ColorNone),
-		"gray-focus":       gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorLightGray),
-		"cyan-nofocus":     gowid.MakePaletteEntry(gowid.ColorCyan, gowid.ColorNone),
-		"cyan-focus":       gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorCyan),
-		"red":              gowid.MakePaletteEntry(gowid.ColorRed, gowid.ColorNone),
-		"redgray":          gowid.MakePaletteEntry(gowid.ColorRed, gowid.ColorLightGray),
-		"blackgreen":       gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorGreen),
-		"graydgreen":       gowid.MakePaletteEntry(gowid.ColorLightGray, gowid.ColorDarkGreen),
-		"bluebg":           gowid.MakePaletteEntry(gowid.ColorWhite, gowid.ColorCyan),
-		"invred":           gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorRed),
-		"streak":           gowid.MakePaletteEntry(gowid.ColorBlack, gowid.ColorRed),
-		"darkblue-focus":   gowid.MakePaletteEntry(gowid.ColorWhite, gowid.ColorDarkBlue),
-		"darkblue-nofocus": gowid.MakePaletteEntry(gowid.ColorWhite, gowid.ColorDarkBlue),
-		"inactive-focus":   gowid.MakePaletteEntry(gowid.ColorWhite, g23color),
-		"inactive-nofocus": gowid.MakePaletteEntry(gowid.ColorWhite, g23color),
-		"test2focus":       gowid.MakePaletteEntry(gowid.ColorMagenta, gowid.ColorBlack),
-		"test2notfocus":    gowid.MakePaletteEntry(gowid.ColorCyan, gowid.ColorBlack),
-		"yellow-focus":     gowid.MakePaletteEntry(gowid.ColorYellow, gowid.ColorYellow),
-		"yellow-nofocus":   gowid.MakePaletteEntry(gowid.ColorYellow, gowid.ColorYellow),
-		"magenta":          gowid.MakePaletteEntry(gowid.ColorMagenta, gowid.ColorNone),
+	if RunSubcommand(os.Args) {
+		return
 	}
 
 	f := RedirectLogger(logFileName)
@@ -548,6 +680,14 @@ func main() {
 		log.Errorf("Error from host.NeedDoAs(): %v", err)
 	}
 
+	err = host.LoadConfig(host.CONFIG_FILE_NAME)
+	if err != nil {
+		log.Errorf("Error from host.LoadConfig(): %v", err)
+	}
+	LoadUiState()
+	LoadKeyMap()
+	palette := GetPalette()
+
 	Containers, err = GetContainersFromDb(ctype, host.GetCbsdDbConnString(false))
 	if err != nil {
 		panic(err)
@@ -558,27 +698,11 @@ func main() {
 		return
 	}
 
-	cbsdListLines = MakeJailsLines()
-
-	cbsdListGrid = make([]gowid.IWidget, 0)
-	gHeader = grid.New(GetJailsListHeader(), WIDTH, HPAD, VPAD, gowid.HAlignMiddle{})
-	cbsdListGrid = append(cbsdListGrid, gHeader)
-	for _, line := range cbsdListLines {
-		gline := grid.New(line, WIDTH, HPAD, VPAD, gowid.HAlignMiddle{},
-			grid.Options{
-				DownKeys: []vim.KeyPress{},
-				UpKeys:   []vim.KeyPress{},
-			})
-		cbsdListGrid = append(cbsdListGrid, gline)
-	}
+	PruneContainerState()
+	LoadContainerTags()
+	MakeJailsListGrid()
 
-	cbsdJailConsole, err = terminal.NewExt(terminal.Options{
-		Command:           strings.Split(os.Getenv("SHELL"), " "),
-		HotKey:            terminal.HotKey{K: tcell.KeyCtrlZ},
-		HotKeyPersistence: &terminal.HotKeyDuration{D: time.Second * 2},
-		Scrollbar:         true,
-		Scrollback:        1000,
-	})
+	cbsdJailConsole, err = tui.NewTerminal(tui.GetShellCommand())
 	if err != nil {
 		panic(err)
 	}
@@ -589,7 +713,7 @@ func main() {
 
 	gBmenu = columns.New(MakeBottomMenu(), columns.Options{DoNotSetSelected: true, LeftKeys: make([]vim.KeyPress, 0), RightKeys: make([]vim.KeyPress, 0)})
 
-	top_panel := NewResizeablePile([]gowid.IContainerWidget{
+	top_panel := pile.New([]gowid.IContainerWidget{
 		&gowid.ContainerWidget{IWidget: listjails, D: gowid.RenderWithWeight{W: 1}},
 	})
 	top_panel.OnFocusChanged(
@@ -607,19 +731,22 @@ func main() {
 		},
 	)
 
-	menuPanel = NewResizeablePile([]gowid.IContainerWidget{
+	menuPanel = pile.New([]gowid.IContainerWidget{
 		&gowid.ContainerWidget{IWidget: gBmenu, D: gowid.RenderWithUnits{U: 1}},
 	})
 
 	hline := styled.New(fill.New('⎯'), gowid.MakePaletteRef("line"))
+	statusHolder := holder.New(hline)
+	terminalHolder := holder.New(cbsdJailConsole)
 
-	cbsdWidgets = NewResizeablePile([]gowid.IContainerWidget{
-		&gowid.ContainerWidget{IWidget: top_panel, D: gowid.RenderWithWeight{W: 1}},
-		&gowid.ContainerWidget{IWidget: menuPanel, D: gowid.RenderWithUnits{U: 1}},
-		&gowid.ContainerWidget{IWidget: hline, D: gowid.RenderWithUnits{U: 1}},
-		&gowid.ContainerWidget{IWidget: cbsdJailConsole, D: gowid.RenderWithWeight{W: 1}},
-	})
-	viewHolder = holder.New(cbsdWidgets)
+	cbsdWidgets = NewLayout(top_panel, menuPanel, statusHolder, terminalHolder, &uiState.Layout)
+	dashboard = NewDashboard()
+	mainWidgets := []gowid.IContainerWidget{
+		&gowid.ContainerWidget{IWidget: dashboard, D: gowid.RenderFlow{}},
+		&gowid.ContainerWidget{IWidget: cbsdWidgets, D: gowid.RenderWithWeight{W: 1}},
+	}
+	mainPile := pile.New(mainWidgets)
+	viewHolder = holder.New(mainPile)
 
 	app, err = gowid.NewApp(gowid.AppArgs{
 		View:    viewHolder,
@@ -627,7 +754,13 @@ func main() {
 		Log:     log.StandardLogger(),
 	})
 
-	mainTui = tui.NewTui(app, viewHolder, cbsdJailConsole, cbsdWidgets.Widget)
+	mainTui = tui.NewTui(app, viewHolder, cbsdJailConsole, cbsdWidgets)
+	mainTui.KeyMap = keyMap
+	mainTui.SerialConsole = host.Cfg.SerialConsole
+	mainTui.SetStatusHolder(statusHolder)
+	mainTui.SetTerminalHolder(terminalHolder)
+	mainTui.EvtConsoleChanged.Connect(nil, OnConsoleChanged)
+	mainTui.ViewExtra = healthChecker.GetView
 	for i := range Containers {
 		Containers[i].SetTui(mainTui)
 	}
@@ -638,5 +771,23 @@ func main() {
 
 	ExitOnErr(err)
 	SetJailListFocus()
+	dashboard.Start(app)
+	healthChecker.Start(app, containerPoller)
+	stateWatcher.Start(app, containerPoller, mainPile, mainWidgets)
+	containerPoller.Start()
+	defer container.Cleanup()
+	QuitOnSignals()
 	app.MainLoop(handler{})
 }
+
+// QuitOnSignals leaves the main loop on SIGTERM and SIGHUP (the terminal is closed)
+// so that the cleanup of the container types deferred in main runs
+func QuitOnSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-signals
+		log.Infof("Quitting on signal %v", sig)
+		app.Run(gowid.RunFunction(func(app gowid.IApp) { app.Quit() }))
+	}()
+}
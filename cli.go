@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"container"
+	"host"
+	"recorder"
+)
+
+const CMD_ACTION string = "action"
+
+// RunSubcommand handles the subcommands, the helper modes started by the TUI in its
+// terminals and through doas, the command line actions and the services:
+//
+//	cbsd-tui record <file> <title> <command> [args...]
+//	cbsd-tui play <file> <speed>
+//	cbsd-tui check-theme <name|file>
+//	cbsd-tui action <jail|bhyvevm> <name> [action] [-y]
+//	cbsd-tui serve [--listen address]
+//	cbsd-tui exporter [--listen address]
+//	cbsd-tui store <operation> [args...]
+//	cbsd-tui store secret-set|secret-get|secret-forget <kind> <name>
+//
+// it returns false if the arguments do not contain a subcommand
+func RunSubcommand(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+	var err error
+	switch args[1] {
+	case CMD_RECORD:
+		if len(args) < 5 {
+			ExitOnErr(fmt.Errorf("Usage: %s %s <file> <title> <command> [args...]", args[0], CMD_RECORD))
+		}
+		err = RunRecorded(args[2], args[3], args[4:])
+	case CMD_PLAY:
+		speed := 1.0
+		if len(args) < 3 {
+			ExitOnErr(fmt.Errorf("Usage: %s %s <file> [speed]", args[0], CMD_PLAY))
+		}
+		if len(args) > 3 {
+			speed, err = strconv.ParseFloat(args[3], 64)
+			ExitOnErr(err)
+		}
+		err = recorder.Play(args[2], speed)
+	case CMD_CHECK_THEME:
+		if len(args) < 3 {
+			ExitOnErr(fmt.Errorf("Usage: %s %s <name|file>", args[0], CMD_CHECK_THEME))
+		}
+		_ = host.LoadConfig(host.CONFIG_FILE_NAME)
+		if err = CheckTheme(args[2]); errors.Is(err, ErrThemeProblems) {
+			os.Exit(2)
+		}
+	case CMD_ACTION:
+		_ = host.LoadConfig(host.CONFIG_FILE_NAME)
+		err = RunCliAction(args)
+	case CMD_SERVE:
+		_ = host.LoadConfig(host.CONFIG_FILE_NAME)
+		err = RunServer(args)
+	case CMD_EXPORTER:
+		_ = host.LoadConfig(host.CONFIG_FILE_NAME)
+		err = RunExporter(args)
+	case host.STORE_SUBCOMMAND:
+		if len(args) < 3 {
+			ExitOnErr(fmt.Errorf("Usage: %s %s <operation> [args...]", args[0], host.STORE_SUBCOMMAND))
+		}
+		err = host.RunStoreCommand(args[2:], os.Stdin, os.Stdout)
+	default:
+		return false
+	}
+	ExitOnErr(err)
+	return true
+}
+
+// RunCliAction runs the action of the registry of the container type on the named
+// container with cbsd, without the action the available actions are listed:
+//
+//	cbsd-tui action <jail|bhyvevm> <name> [action] [-y]
+func RunCliAction(args []string) error {
+	usage := fmt.Errorf("Usage: %s %s <%s> <name> [action] [-y]", args[0], CMD_ACTION, strings.Join(container.GetTypeNames(), "|"))
+	if len(args) < 4 {
+		return usage
+	}
+	if _, found := container.GetType(args[2]); !found {
+		return usage
+	}
+	if _, err := host.NeedDoAs(); err != nil {
+		return err
+	}
+	containers, err := GetContainersFromDb(args[2], host.GetCbsdDbConnString(false))
+	if err != nil {
+		return err
+	}
+	var container Container = nil
+	for _, c := range containers {
+		if c.GetName() == args[3] {
+			container = c
+			break
+		}
+	}
+	if container == nil {
+		return fmt.Errorf("%s '%s' not found", args[2], args[3])
+	}
+	if len(args) < 5 {
+		for _, action := range container.GetCliActions() {
+			fmt.Printf("%-12s %s\n", action, container.GetActionCaption(action))
+		}
+		return nil
+	}
+	action := args[4]
+	cbsdargs := container.GetActionCliArgs(action)
+	if cbsdargs == nil {
+		return fmt.Errorf("action '%s' is not available for %s from the command line", action, container.GetName())
+	}
+	if question := container.GetActionConfirmText(action); question != "" && !(len(args) > 5 && args[5] == "-y") {
+		fmt.Printf("%s [y/N] ", question)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return nil
+		}
+	}
+	command, cmdargs := host.GetCbsdCommand(cbsdargs...)
+	cmd := exec.Command(command, cmdargs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
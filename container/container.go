@@ -0,0 +1,153 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/quasilyte/gsignal"
+
+	"tui"
+)
+
+// Container is the core interface implemented by all container types,
+// the actions are run through the action registry of the type
+type Container interface {
+	GetType() string
+	GetSignalUpdated() *gsignal.Event[string]
+	GetSignalRefresh() *gsignal.Event[any]
+	SetTui(t *tui.Tui)
+	GetBottomMenuActions() []string
+	GetMenuActions() []string
+	GetActionCaption(action string) string
+	IsActionAvailable(action string) bool
+	GetCliActions() []string
+	GetActionCliArgs(action string) []string
+	GetActionConfirmText(action string) string
+	ExecuteAction(action string) bool
+	GetHeaderTitles() []string
+	GetName() string
+	GetStatus() int
+	GetAstart() int
+	IsRunning() bool
+	GetAllParams() []string
+}
+
+// Optional capabilities of the containers
+
+type Loginable interface {
+	GetLoginArgs() []string // cbsd arguments of the login
+}
+
+type Snapshotter interface {
+	Snapshot(snapname string)
+	GetSnapshotCliArgs(snapname string) []string
+	GetSnapshots() [][2]string
+	DestroySnapshot(snapname string)
+}
+
+type Cloner interface {
+	Clone(newname string, newhname string, newip string)
+	GetCloneCliArgs(newname string, newhname string, newip string) []string
+}
+
+type Exporter interface {
+	Export()
+}
+
+type Editor interface {
+	OpenEditDialog()
+}
+
+// StatusChecker asks cbsd for the current status of the container, 1 if running
+type StatusChecker interface {
+	GetCurrentStatus() int
+}
+
+// DiskLister lists the disk images of a VM for the storage panel
+type DiskLister interface {
+	GetDiskImages() []string
+}
+
+// Loader reads the containers of a type from the cbsd database
+type Loader func(dbname string) ([]Container, error)
+
+// ContainerType is registered by the package implementing the type
+type ContainerType struct {
+	Name     string // returned by GetType(), like "jail" or "bhyvevm"
+	Emulator string // value of the emulator column in the cbsd jails table, like "jail" or "bhyve"
+	Action   string // key map action switching the list to the type
+	Load     Loader
+	Cleanup  func() // optional, called on exit
+}
+
+var types = make([]ContainerType, 0)
+
+// RegisterType adds the container type, the types are listed in the order of registration
+func RegisterType(ct ContainerType) {
+	if ct.Name == "" || ct.Load == nil {
+		panic("container type without name or loader")
+	}
+	if _, found := GetType(ct.Name); found {
+		panic(fmt.Sprintf("container type '%s' is already registered", ct.Name))
+	}
+	types = append(types, ct)
+}
+
+func GetType(name string) (ContainerType, bool) {
+	for _, ct := range types {
+		if ct.Name == name {
+			return ct, true
+		}
+	}
+	return ContainerType{}, false
+}
+
+// GetTypeByEmulator returns the type of the containers with the emulator in the cbsd database
+func GetTypeByEmulator(emulator string) (ContainerType, bool) {
+	for _, ct := range types {
+		if ct.Emulator == emulator {
+			return ct, true
+		}
+	}
+	return ContainerType{}, false
+}
+
+func GetTypes() []ContainerType {
+	return types
+}
+
+func GetTypeNames() []string {
+	names := make([]string, len(types))
+	for i, ct := range types {
+		names[i] = ct.Name
+	}
+	return names
+}
+
+// GetTypeActions returns the key map actions switching the list to the types
+func GetTypeActions() []string {
+	actions := make([]string, 0, len(types))
+	for _, ct := range types {
+		if ct.Action != "" {
+			actions = append(actions, ct.Action)
+		}
+	}
+	return actions
+}
+
+// Load reads the containers of the registered type
+func Load(name string, dbname string) ([]Container, error) {
+	ct, found := GetType(name)
+	if !found {
+		return make([]Container, 0), fmt.Errorf("unknown container type '%s'", name)
+	}
+	return ct.Load(dbname)
+}
+
+// Cleanup runs the cleanup functions of all the types
+func Cleanup() {
+	for _, ct := range types {
+		if ct.Cleanup != nil {
+			ct.Cleanup()
+		}
+	}
+}
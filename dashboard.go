@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/holder"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gcla/gowid/widgets/text"
+	_ "github.com/mattn/go-sqlite3"
+
+	"host"
+)
+
+const DASHBOARD_REFRESH_DEFAULT int = 10 // seconds
+
+type ContainerCount struct {
+	Emulator string
+	Total    int
+	Running  int
+}
+
+// GetContainerCounts counts the containers of the cbsd database by emulator,
+// the status column is used as is to avoid running jstatus for every container
+func GetContainerCounts(dbname string) ([]ContainerCount, error) {
+	counts := make([]ContainerCount, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return counts, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT emulator, COUNT(*), SUM(CASE WHEN status=1 THEN 1 ELSE 0 END) FROM jails GROUP BY emulator ORDER BY emulator")
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ContainerCount
+		if err = rows.Scan(&c.Emulator, &c.Total, &c.Running); err != nil {
+			return counts, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, nil
+}
+
+// DashboardWidget is the host summary above the containers list,
+// collapsed to one line or expanded, refreshed periodically
+type DashboardWidget struct {
+	*holder.Widget
+	txt    *text.Widget
+	info   host.HostInfo
+	counts []ContainerCount
+	logged bool
+}
+
+func NewDashboard() *DashboardWidget {
+	txt := text.New("Loading host summary...")
+	return &DashboardWidget{
+		Widget: holder.New(styled.New(txt, gowid.MakePaletteRef("dashboard"))),
+		txt:    txt,
+	}
+}
+
+func GetDashboardRefresh() time.Duration {
+	if host.Cfg.DashboardRefresh <= 0 {
+		return time.Duration(DASHBOARD_REFRESH_DEFAULT) * time.Second
+	}
+	return time.Duration(host.Cfg.DashboardRefresh) * time.Second
+}
+
+// collect reads the summary, the errors are logged only once
+func (d *DashboardWidget) collect() (host.HostInfo, []ContainerCount) {
+	info, errs := host.GetHostInfo()
+	counts, err := GetContainerCounts(host.GetCbsdDbConnString(false))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if !d.logged {
+		for _, err := range errs {
+			host.LogError("Dashboard", err)
+		}
+		d.logged = true
+	}
+	return info, counts
+}
+
+// Start refreshes the dashboard now and then periodically in background
+func (d *DashboardWidget) Start(app *gowid.App) {
+	go func() {
+		for {
+			info, counts := d.collect()
+			app.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) {
+				d.info = info
+				d.counts = counts
+				d.Update(app)
+			}))
+			time.Sleep(GetDashboardRefresh())
+		}
+	}()
+}
+
+func (d *DashboardWidget) Update(app gowid.IApp) {
+	d.txt.SetText(d.GetText(uiState.DashboardCollapsed), app)
+}
+
+func (d *DashboardWidget) Toggle(app gowid.IApp) {
+	uiState.DashboardCollapsed = !uiState.DashboardCollapsed
+	SaveUiState()
+	d.Update(app)
+}
+
+func (d *DashboardWidget) GetText(collapsed bool) string {
+	info := d.info
+	mem := ""
+	if info.TotalMemory > 0 {
+		mem = host.FormatSize(info.FreeMemory)
+	}
+	if collapsed {
+		parts := []string{info.Hostname}
+		counts := make([]string, 0)
+		for _, c := range d.counts {
+			counts = append(counts, fmt.Sprintf("%s %d/%d", c.Emulator, c.Running, c.Total))
+		}
+		parts = append(parts, strings.Join(counts, " "))
+		parts = append(parts, "load "+info.LoadAverage)
+		if mem != "" {
+			parts = append(parts, "mem "+mem+" free")
+		}
+		for _, p := range info.Pools {
+			parts = append(parts, p.Name+" "+p.Free+" free")
+		}
+		return " " + strings.Join(parts, " | ")
+	}
+	lines := make([]string, 0)
+	lines = append(lines, fmt.Sprintf(" Host: %s   FreeBSD %s   cbsd %s", info.Hostname, info.OsVersion, info.CbsdVersion))
+	line := " Load: " + info.LoadAverage
+	if mem != "" {
+		line += "   Memory: " + mem + " free of " + host.FormatSize(info.TotalMemory)
+	}
+	lines = append(lines, line)
+	counts := make([]string, 0)
+	for _, c := range d.counts {
+		counts = append(counts, fmt.Sprintf("%s: %d (%d running, %d stopped)", c.Emulator, c.Total, c.Running, c.Total-c.Running))
+	}
+	lines = append(lines, " "+strings.Join(counts, "   "))
+	pools := make([]string, 0)
+	for _, p := range info.Pools {
+		pools = append(pools, fmt.Sprintf("%s %s free of %s (%s used)", p.Name, p.Free, p.Size, p.Capacity))
+	}
+	if len(pools) > 0 {
+		lines = append(lines, " ZFS: "+strings.Join(pools, "   "))
+	}
+	return strings.Join(lines, "\n")
+}
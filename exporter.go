@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"host"
+	"tui"
+)
+
+const CMD_EXPORTER string = "exporter"
+const EXPORTER_LISTEN_DEFAULT string = "127.0.0.1:9595"
+const METRICS_PATH string = "/metrics"
+
+func init() {
+	tui.AddExecObserver(RecordActionRun)
+}
+
+// RecordActionRun adds a run of an action from the TUI to the statistics exported
+func RecordActionRun(name string, action string, duration time.Duration, err error) {
+	if rerr := host.RecordAction(action, duration, err != nil); rerr != nil {
+		host.LogError("Cannot record action in "+host.STATE_DB_NAME, rerr)
+	}
+}
+
+func GetExporterInterval() time.Duration {
+	if host.Cfg.ExporterInterval <= 0 {
+		return time.Duration(host.EXPORTER_INTERVAL_DEFAULT) * time.Second
+	}
+	return time.Duration(host.Cfg.ExporterInterval) * time.Second
+}
+
+// Metrics builds the Prometheus text exposition format
+type Metrics struct {
+	sb strings.Builder
+}
+
+// Describe starts a metric family, its samples must follow
+func (m *Metrics) Describe(name string, kind string, help string) {
+	m.sb.WriteString("# HELP " + name + " " + help + "\n")
+	m.sb.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// Add writes a sample, labels are name and value pairs
+func (m *Metrics) Add(name string, value float64, labels ...string) {
+	m.sb.WriteString(name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, labels[i]+"=\""+EscapeLabelValue(labels[i+1])+"\"")
+		}
+		m.sb.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	m.sb.WriteString(" " + fmt.Sprint(value) + "\n")
+}
+
+func (m *Metrics) String() string {
+	return m.sb.String()
+}
+
+func EscapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func BoolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// CollectMetrics loads all the jails and VMs, their snapshots and the statistics of the actions,
+// the number of the sources which failed, each type of containers counting as one, is exported too
+func CollectMetrics() string {
+	var m Metrics
+	started := time.Now()
+	errors := 0
+	all, errs := GetAllContainers(host.GetCbsdDbConnString(false))
+	errors += len(errs)
+	names := GetNames(all)
+	sort.Strings(names)
+
+	m.Describe("cbsd_container_up", "gauge", "Whether the container is running.")
+	for _, name := range names {
+		m.Add("cbsd_container_up", BoolValue(all[name].IsRunning()), "name", name, "type", all[name].GetType())
+	}
+	m.Describe("cbsd_container_autostart", "gauge", "Whether the container is started on boot.")
+	for _, name := range names {
+		m.Add("cbsd_container_autostart", BoolValue(all[name].GetAstart() == 1), "name", name, "type", all[name].GetType())
+	}
+
+	snaps, err := host.GetSnapshotTimes()
+	if err != nil {
+		host.LogError("Cannot list ZFS snapshots", err)
+		errors++
+	}
+	m.Describe("cbsd_container_snapshots", "gauge", "Number of the ZFS snapshots of the container datasets.")
+	newest := make(map[string]time.Time)
+	oldest := make(map[string]time.Time)
+	for _, name := range names {
+		count := 0
+		for _, snap := range snaps {
+			if !host.IsContainerDataset(name, snap.Dataset) {
+				continue
+			}
+			count++
+			if snap.Created.After(newest[name]) {
+				newest[name] = snap.Created
+			}
+			if t, found := oldest[name]; !found || snap.Created.Before(t) {
+				oldest[name] = snap.Created
+			}
+		}
+		m.Add("cbsd_container_snapshots", float64(count), "name", name, "type", all[name].GetType())
+	}
+	m.Describe("cbsd_container_newest_snapshot_age_seconds", "gauge", "Age of the newest snapshot of the container, absent without snapshots.")
+	for _, name := range names {
+		if t, found := newest[name]; found {
+			m.Add("cbsd_container_newest_snapshot_age_seconds", started.Sub(t).Seconds(), "name", name, "type", all[name].GetType())
+		}
+	}
+	m.Describe("cbsd_container_oldest_snapshot_age_seconds", "gauge", "Age of the oldest snapshot of the container, absent without snapshots.")
+	for _, name := range names {
+		if t, found := oldest[name]; found {
+			m.Add("cbsd_container_oldest_snapshot_age_seconds", started.Sub(t).Seconds(), "name", name, "type", all[name].GetType())
+		}
+	}
+
+	stats, err := host.GetActionStats()
+	if err != nil {
+		host.LogError("Cannot read action statistics from "+host.STATE_DB_NAME, err)
+		errors++
+	}
+	m.Describe("cbsd_action_duration_seconds", "summary", "Duration of the actions run from cbsd-tui and its API.")
+	for _, st := range stats {
+		m.Add("cbsd_action_duration_seconds_sum", st.DurationSum, "action", st.Action)
+		m.Add("cbsd_action_duration_seconds_count", float64(st.Runs), "action", st.Action)
+	}
+	m.Describe("cbsd_action_failures_total", "counter", "Number of the failed actions run from cbsd-tui and its API.")
+	for _, st := range stats {
+		m.Add("cbsd_action_failures_total", float64(st.Failures), "action", st.Action)
+	}
+	m.Describe("cbsd_action_last_duration_seconds", "gauge", "Duration of the last run of the action.")
+	for _, st := range stats {
+		m.Add("cbsd_action_last_duration_seconds", st.LastDuration, "action", st.Action)
+	}
+	m.Describe("cbsd_action_last_failed", "gauge", "Whether the last run of the action failed.")
+	for _, st := range stats {
+		m.Add("cbsd_action_last_failed", BoolValue(st.LastFailed), "action", st.Action)
+	}
+	m.Describe("cbsd_action_last_run_timestamp_seconds", "gauge", "Time the last run of the action finished.")
+	for _, st := range stats {
+		m.Add("cbsd_action_last_run_timestamp_seconds", float64(st.LastRun.Unix()), "action", st.Action)
+	}
+
+	m.Describe("cbsd_exporter_collect_errors", "gauge", "Number of the sources which could not be read in the last collection.")
+	m.Add("cbsd_exporter_collect_errors", float64(errors))
+	m.Describe("cbsd_exporter_collect_duration_seconds", "gauge", "Duration of the last collection.")
+	m.Add("cbsd_exporter_collect_duration_seconds", time.Since(started).Seconds())
+	m.Describe("cbsd_exporter_collect_timestamp_seconds", "gauge", "Time of the last collection.")
+	m.Add("cbsd_exporter_collect_timestamp_seconds", float64(started.Unix()))
+	return m.String()
+}
+
+// RunExporter collects the metrics periodically and serves the last ones on METRICS_PATH:
+//
+//	cbsd-tui exporter [--listen address]
+func RunExporter(args []string) error {
+	flags := flag.NewFlagSet(CMD_EXPORTER, flag.ContinueOnError)
+	listen := flags.String("listen", EXPORTER_LISTEN_DEFAULT, "address to listen on")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	if _, err := host.NeedDoAs(); err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	metrics := CollectMetrics()
+	go func() {
+		for {
+			time.Sleep(GetExporterInterval())
+			collected := CollectMetrics()
+			mu.Lock()
+			metrics = collected
+			mu.Unlock()
+		}
+	}()
+	mux := http.NewServeMux()
+	mux.HandleFunc(METRICS_PATH, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		body := metrics
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := w.Write([]byte(body)); err != nil {
+			host.LogError("Cannot write metrics", err)
+		}
+	})
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the metrics on http://%s%s\n", *listen, METRICS_PATH)
+	return server.ListenAndServe()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gcla/gowid"
+
+	"host"
+)
+
+const HEALTH_TITLE string = "HEALTH"
+
+// Number of the check results kept per container for the View dialog
+const HEALTH_HISTORY_SIZE int = 20
+
+// HealthChecker runs the configured health checks of the running containers in background,
+// the results are stored in the UI goroutine only
+type HealthChecker struct {
+	last    map[string][]host.HealthResult // results of the last round, nil if the container is not running
+	history map[string][]host.HealthResult // newest first
+	running atomic.Bool                    // checks of a round are running
+}
+
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		last:    make(map[string][]host.HealthResult),
+		history: make(map[string][]host.HealthResult),
+	}
+}
+
+func GetHealthInterval() time.Duration {
+	if host.Cfg.HealthInterval <= 0 {
+		return time.Duration(host.HEALTH_INTERVAL_DEFAULT) * time.Second
+	}
+	return time.Duration(host.Cfg.HealthInterval) * time.Second
+}
+
+// Start runs the checks on the snapshots of the poller, a round is skipped while the checks
+// of the previous one still run; nothing is done without configured checks
+func (hc *HealthChecker) Start(app *gowid.App, poller *ContainerPoller) {
+	if len(host.Cfg.HealthChecks) == 0 {
+		return
+	}
+	poller.Subscribe(GetHealthInterval(), func(all map[string]Container) {
+		if !hc.running.CompareAndSwap(false, true) {
+			return
+		}
+		go func() {
+			defer hc.running.Store(false)
+			for jname, checks := range host.Cfg.HealthChecks {
+				jname := jname
+				var results []host.HealthResult
+				if c, found := all[jname]; found && c.IsRunning() {
+					results = host.RunHealthChecks(jname, GetContainerEmulator(c) == "jail", checks)
+				}
+				app.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) {
+					hc.Store(jname, results)
+				}))
+			}
+		}()
+	})
+}
+
+func (hc *HealthChecker) Store(jname string, results []host.HealthResult) {
+	hc.last[jname] = results
+	history := append(append([]host.HealthResult{}, results...), hc.history[jname]...)
+	if len(history) > HEALTH_HISTORY_SIZE {
+		history = history[:HEALTH_HISTORY_SIZE]
+	}
+	hc.history[jname] = history
+	if jail := GetJailByName(jname); jail != nil {
+		UpdateJailLine(jail)
+	}
+}
+
+// GetCell returns the HEALTH column of the container and its style,
+// the line style is used for the containers without checks, stopped or not checked yet
+func (hc *HealthChecker) GetCell(jail Container, style string) (string, string) {
+	jname := jail.GetName()
+	if _, found := host.Cfg.HealthChecks[jname]; !found || !jail.IsRunning() {
+		return "-", style
+	}
+	results := hc.last[jname]
+	if len(results) == 0 {
+		return "...", style
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.Ok {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return "OK", "green"
+	}
+	return fmt.Sprintf("FAIL %d/%d", failed, len(results)), "red"
+}
+
+// GetView returns the recent results of the checks for the View dialog
+func (hc *HealthChecker) GetView(jname string) string {
+	if _, found := host.Cfg.HealthChecks[jname]; !found {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Health checks (every " + GetHealthInterval().String() + ", running only):\n")
+	if len(hc.history[jname]) == 0 {
+		sb.WriteString("no results yet\n")
+	}
+	for _, r := range hc.history[jname] {
+		status := "OK  "
+		if !r.Ok {
+			status = "FAIL"
+		}
+		sb.WriteString(r.Time.Format("2006-01-02 15:04:05") + " " + status + " " + r.Check)
+		if r.Message != "" {
+			sb.WriteString(": " + r.Message)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
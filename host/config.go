@@ -0,0 +1,89 @@
+package host
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const CONFIG_FILE_NAME string = "/usr/local/etc/cbsd-tui.json"
+
+// Config holds the optional user settings read from CONFIG_FILE_NAME,
+// all the fields can be omitted
+type Config struct {
+	VncViewer string              `json:"vnc_viewer"` // command to start local VNC viewer, %s is replaced by host:port
+	SshHost   string              `json:"ssh_host"`   // host name used in SSH tunnel commands, hostname by default
+	SshUser   string              `json:"ssh_user"`   // user name used in SSH tunnel commands, current user by default
+	RecordDir string              `json:"record_dir"` // directory to record login sessions to, no recording if empty
+	Theme     string              `json:"theme"`      // name of the color theme, "default" by default
+	ThemeDir  string              `json:"theme_dir"`  // directory with theme files, DEFAULT_THEME_DIR by default
+	Keymap    string              `json:"keymap"`     // key map preset: default, vim or emacs
+	Keys      map[string][]string `json:"keys"`       // key names bound to actions, override the preset
+
+	SerialConsole string `json:"serial_console"` // backend of cbsd bhyve-console: cu (default) or tmux, sets the detach key
+
+	DashboardRefresh int      `json:"dashboard_refresh"` // refresh period of the host summary in seconds, 10 by default
+	IpPools          []string `json:"ip_pools"`          // subnets to suggest free addresses from, cbsd nodeippool by default
+
+	HealthChecks   map[string][]HealthCheck `json:"health_checks"`   // checks of the running containers by name
+	HealthInterval int                      `json:"health_interval"` // period of the checks in seconds, HEALTH_INTERVAL_DEFAULT by default
+
+	WatchInterval int                   `json:"watch_interval"` // period of the state watcher in seconds, WATCH_INTERVAL_DEFAULT by default
+	NotifyHooks   map[string]NotifyHook `json:"notify_hooks"`   // hooks run on unexpected stops by container name, "*" for all
+
+	ApiToken string `json:"api_token"` // bearer token of the HTTP API, CBSD_TUI_API_TOKEN environment variable overrides it
+
+	ExporterInterval int `json:"exporter_interval"` // period of the metrics exporter in seconds, EXPORTER_INTERVAL_DEFAULT by default
+}
+
+const DEFAULT_THEME_DIR string = "/usr/local/etc/cbsd-tui/themes"
+
+func GetThemeDir() string {
+	if Cfg.ThemeDir == "" {
+		return DEFAULT_THEME_DIR
+	}
+	return Cfg.ThemeDir
+}
+
+var Cfg Config
+
+func LoadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, &Cfg)
+}
+
+// UI state saved between runs in the home directory of the user
+const STATE_FILE_NAME string = ".cbsd-tui.json"
+
+func GetStateFileName() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, STATE_FILE_NAME)
+}
+
+func LoadState(state interface{}) error {
+	data, err := os.ReadFile(GetStateFileName())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, state)
+}
+
+func SaveState(state interface{}) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetStateFileName(), data, 0600)
+}
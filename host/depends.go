@@ -0,0 +1,171 @@
+package host
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Depends maps the container name to the names of the containers started before it,
+// it is kept in the depends table of the state database
+type Depends map[string][]string
+
+func LoadDepends() (Depends, error) {
+	depends := make(Depends)
+	err := QueryState("SELECT jname,depend FROM depends ORDER BY jname,depend", func(rows *sql.Rows) error {
+		var jname, depend string
+		if err := rows.Scan(&jname, &depend); err != nil {
+			return err
+		}
+		depends[jname] = append(depends[jname], depend)
+		return nil
+	})
+	return depends, err
+}
+
+// SetDepends replaces the dependencies of the container
+func SetDepends(jname string, deps []string) error {
+	return WriteState("depends", append([]string{jname}, deps...)...)
+}
+
+func setDependsTx(tx *sql.Tx, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: depends <name> [dependencies...]")
+	}
+	if _, err := tx.Exec("DELETE FROM depends WHERE jname=?", args[0]); err != nil {
+		return err
+	}
+	for _, dep := range args[1:] {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO depends (jname,depend) VALUES (?,?)", args[0], dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseDepends splits the comma or space separated list of names
+func ParseDepends(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// AddDependencies adds the dependencies of the names recursively
+func AddDependencies(names []string, depends Depends) []string {
+	return closeOver(names, func(name string) []string { return depends[name] })
+}
+
+// AddDependents adds the containers depending on the names recursively
+func AddDependents(names []string, depends Depends) []string {
+	return closeOver(names, func(name string) []string {
+		dependents := make([]string, 0)
+		for n, deps := range depends {
+			for _, d := range deps {
+				if d == name {
+					dependents = append(dependents, n)
+				}
+			}
+		}
+		return dependents
+	})
+}
+
+func closeOver(names []string, next func(name string) []string) []string {
+	res := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	queue := append([]string{}, names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+		queue = append(queue, next(name)...)
+	}
+	return res
+}
+
+// OrderByDepends sorts the names in the start order: the dependencies first,
+// then by cbsd bootorder and by name, the dependencies outside of names are ignored;
+// the containers of a dependency cycle are returned last with an error
+func OrderByDepends(names []string, bootorder map[string]int, depends Depends) ([]string, error) {
+	inset := make(map[string]bool)
+	for _, name := range names {
+		inset[name] = true
+	}
+	waiting := make(map[string]int)
+	for _, name := range names {
+		for _, d := range depends[name] {
+			if inset[d] && d != name {
+				waiting[name]++
+			}
+		}
+	}
+	less := func(a string, b string) bool {
+		if bootorder[a] != bootorder[b] {
+			return bootorder[a] < bootorder[b]
+		}
+		return a < b
+	}
+	ready := make([]string, 0)
+	for name := range inset {
+		if waiting[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	res := make([]string, 0, len(inset))
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return less(ready[i], ready[j]) })
+		name := ready[0]
+		ready = ready[1:]
+		res = append(res, name)
+		for n := range inset {
+			for _, d := range depends[n] {
+				if d == name && n != name {
+					waiting[n]--
+					if waiting[n] == 0 {
+						ready = append(ready, n)
+					}
+				}
+			}
+		}
+	}
+	if len(res) < len(inset) {
+		cycle := make([]string, 0)
+		for name := range inset {
+			if waiting[name] > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return append(res, cycle...), fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+	}
+	return res, nil
+}
+
+// GetBootOrders returns the cbsd bootorder of all the containers
+func GetBootOrders(dbname string) (map[string]int, error) {
+	orders := make(map[string]int)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return orders, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT jname,IFNULL(bootorder,0) FROM jails")
+	if err != nil {
+		return orders, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var order int
+		if err = rows.Scan(&name, &order); err != nil {
+			return orders, err
+		}
+		orders[name] = order
+	}
+	return orders, rows.Err()
+}
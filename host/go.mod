@@ -2,7 +2,10 @@ module host
 
 go 1.19
 
-require github.com/sirupsen/logrus v1.4.2
+require (
+	github.com/mattn/go-sqlite3 v1.14.12
+	github.com/sirupsen/logrus v1.4.2
+)
 
 require (
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
@@ -0,0 +1,133 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const JEXEC_PROGRAM string = "/usr/sbin/jexec"
+
+// Types of the health checks
+const (
+	HEALTH_TCP     string = "tcp"     // connect to Address
+	HEALTH_HTTP    string = "http"    // get Url, the status must be below 400
+	HEALTH_COMMAND string = "command" // run Command in the jail with jexec, the exit code must be 0
+)
+
+const HEALTH_TIMEOUT_DEFAULT int = 5   // seconds
+const HEALTH_INTERVAL_DEFAULT int = 30 // seconds
+const HEALTH_MESSAGE_MAX_LEN int = 200 // output of the failed commands is cut
+
+// HealthCheck is a check of a container configured in health_checks
+type HealthCheck struct {
+	Type    string `json:"type"`
+	Address string `json:"address"` // host:port for tcp checks
+	Url     string `json:"url"`     // URL for http checks
+	Command string `json:"command"` // shell command for command checks
+	Timeout int    `json:"timeout"` // seconds, HEALTH_TIMEOUT_DEFAULT by default
+}
+
+type HealthResult struct {
+	Time    time.Time
+	Check   string
+	Ok      bool
+	Message string
+}
+
+func (hc HealthCheck) GetTimeout() time.Duration {
+	if hc.Timeout <= 0 {
+		return time.Duration(HEALTH_TIMEOUT_DEFAULT) * time.Second
+	}
+	return time.Duration(hc.Timeout) * time.Second
+}
+
+func (hc HealthCheck) GetDescription() string {
+	switch hc.Type {
+	case HEALTH_TCP:
+		return "tcp " + hc.Address
+	case HEALTH_HTTP:
+		return "http " + hc.Url
+	case HEALTH_COMMAND:
+		return "command " + hc.Command
+	}
+	return hc.Type
+}
+
+// Run runs the check of the container, command checks can be run in jails only
+func (hc HealthCheck) Run(jname string, isjail bool) HealthResult {
+	res := HealthResult{Time: time.Now(), Check: hc.GetDescription()}
+	var err error
+	switch hc.Type {
+	case HEALTH_TCP:
+		err = checkTcp(hc.Address, hc.GetTimeout())
+	case HEALTH_HTTP:
+		err = checkHttp(hc.Url, hc.GetTimeout())
+	case HEALTH_COMMAND:
+		if !isjail {
+			err = fmt.Errorf("command checks are run in jails only")
+		} else {
+			err = checkCommand(jname, hc.Command, hc.GetTimeout())
+		}
+	default:
+		err = fmt.Errorf("unknown check type '%s', use %s, %s or %s", hc.Type, HEALTH_TCP, HEALTH_HTTP, HEALTH_COMMAND)
+	}
+	res.Ok = err == nil
+	if err != nil {
+		res.Message = err.Error()
+		if len(res.Message) > HEALTH_MESSAGE_MAX_LEN {
+			res.Message = res.Message[:HEALTH_MESSAGE_MAX_LEN] + "..."
+		}
+	}
+	return res
+}
+
+func RunHealthChecks(jname string, isjail bool, checks []HealthCheck) []HealthResult {
+	results := make([]HealthResult, len(checks))
+	for i, hc := range checks {
+		results[i] = hc.Run(jname, isjail)
+	}
+	return results
+}
+
+func checkTcp(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func checkHttp(url string, timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+func checkCommand(jname string, command string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	program, args := JEXEC_PROGRAM, []string{jname, SHELL_PROGRAM, "-c", command}
+	if USE_DOAS {
+		program, args = DOAS_PROGRAM, append([]string{JEXEC_PROGRAM}, args...)
+	}
+	out, err := exec.CommandContext(ctx, program, args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timeout after %s", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package host
 
 import (
+	"database/sql"
 	"os/user"
 
+	_ "github.com/mattn/go-sqlite3"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,6 +19,9 @@ const LOGFILE_JSTART string = "/var/log/jstart.log"
 
 const CBSD_USER_NAME string = "cbsd"
 const CBSD_DB_NAME string = "/var/db/local.sqlite"
+const CBSD_DB_NAME_VM string = "/local.sqlite"
+const CBSD_JAILS_SYSTEM_DIR string = "/jails-system"
+const CBSD_JAILS_DATA_DIR string = "/jails-data"
 
 func NeedDoAs() (bool, error) {
 	curuser, err := user.Current()
@@ -47,3 +52,41 @@ func GetCbsdDbConnString(readwrite bool) string {
 		return "file:" + cbsdUser.HomeDir + CBSD_DB_NAME + "?mode=ro"
 	}
 }
+
+// GetCbsdVmDbConnString returns the connection string of the per-VM database
+// which holds bhyvedsk and bhyvenic tables
+func GetCbsdVmDbConnString(jname string, readwrite bool) string {
+	cbsdUser, err := user.Lookup(CBSD_USER_NAME)
+	if err != nil {
+		panic(err)
+	}
+	dbpath := cbsdUser.HomeDir + CBSD_JAILS_SYSTEM_DIR + "/" + jname + CBSD_DB_NAME_VM
+	if readwrite {
+		return "file:" + dbpath + "?mode=rw"
+	} else {
+		return "file:" + dbpath + "?mode=ro"
+	}
+}
+
+// GetContainerNames returns the names of the containers of all types
+func GetContainerNames(dbname string) ([]string, error) {
+	names := make([]string, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return names, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT jname FROM jails")
+	if err != nil {
+		return names, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,178 @@
+package host
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Maximal number of addresses scanned in one pool, IPv6 pools are never scanned in full
+const IP_SCAN_LIMIT int = 65536
+
+// ValidateIpAddrs checks the ip4_addr value: a comma separated list of IPv4/IPv6
+// addresses with optional prefix length and interface ("em0#10.0.0.2/24") or a placeholder
+func ValidateIpAddrs(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("IP address cannot be empty, use DHCP or 0 for no address")
+	}
+	if strings.TrimSpace(value) == "0" {
+		return nil
+	}
+	invalid := make([]string, 0)
+	for _, addr := range ParseIpAddrs(value) {
+		if addr.Ip == nil && !addr.Placeholder {
+			invalid = append(invalid, addr.Value)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid IP address '%s', use IPv4/IPv6 addresses with optional /prefix separated by commas or DHCP", strings.Join(invalid, "', '"))
+	}
+	return nil
+}
+
+// GetNodeIpPool returns the nodeippool setting of cbsd
+func GetNodeIpPool(dbname string) (string, error) {
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var pool sql.NullString
+	if err = db.QueryRow("SELECT nodeippool FROM local").Scan(&pool); err != nil {
+		return "", err
+	}
+	return pool.String, nil
+}
+
+// GetIpPools returns the subnets to allocate addresses from,
+// ip_pools of the configuration or cbsd nodeippool
+func GetIpPools(dbname string) ([]*net.IPNet, error) {
+	if len(Cfg.IpPools) > 0 {
+		pools, invalid := ParseIpPools(strings.Join(Cfg.IpPools, " "))
+		if len(invalid) > 0 {
+			return pools, fmt.Errorf("invalid ip_pools in configuration: %s", strings.Join(invalid, " "))
+		}
+		return pools, nil
+	}
+	pool, err := GetNodeIpPool(dbname)
+	if err != nil {
+		return make([]*net.IPNet, 0), err
+	}
+	pools, invalid := ParseIpPools(pool)
+	if len(invalid) > 0 {
+		return pools, fmt.Errorf("invalid nodeippool: %s", strings.Join(invalid, " "))
+	}
+	return pools, nil
+}
+
+// GetUsedIps returns the addresses of all jails/VMs and of the node itself
+func GetUsedIps(dbname string) (map[string]bool, error) {
+	used := make(map[string]bool)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return used, err
+	}
+	defer db.Close()
+	var nodeip sql.NullString
+	if err = db.QueryRow("SELECT nodeip FROM local").Scan(&nodeip); err == nil {
+		for _, addr := range ParseIpAddrs(nodeip.String) {
+			if addr.Ip != nil {
+				used[addr.Ip.String()] = true
+			}
+		}
+	}
+	rows, err := db.Query("SELECT IFNULL(ip4_addr,'') FROM jails")
+	if err != nil {
+		return used, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return used, err
+		}
+		for _, addr := range ParseIpAddrs(value) {
+			if addr.Ip != nil {
+				used[addr.Ip.String()] = true
+			}
+		}
+	}
+	return used, nil
+}
+
+func nextIp(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func isBroadcast(ip net.IP, pool *net.IPNet) bool {
+	for i := range ip {
+		if ip[i]|pool.Mask[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// NextFreeIp returns the first address of the pool not in used like "10.0.0.5/24",
+// the network and IPv4 broadcast addresses are skipped
+func NextFreeIp(pool *net.IPNet, used map[string]bool) (string, bool) {
+	network := pool.IP.Mask(pool.Mask)
+	if ip4 := network.To4(); ip4 != nil && len(pool.Mask) == net.IPv4len {
+		network = ip4
+	}
+	ones, _ := pool.Mask.Size()
+	ip := nextIp(network)
+	for i := 0; i < IP_SCAN_LIMIT && pool.Contains(ip); i++ {
+		if len(ip) == net.IPv4len && isBroadcast(ip, pool) {
+			break
+		}
+		if !used[ip.String()] {
+			return fmt.Sprintf("%s/%d", ip, ones), true
+		}
+		ip = nextIp(ip)
+	}
+	return "", false
+}
+
+// SuggestIpAddrs returns the next free address of each pool
+func SuggestIpAddrs(dbname string) ([]string, error) {
+	suggestions := make([]string, 0)
+	pools, err := GetIpPools(dbname)
+	if err != nil {
+		return suggestions, err
+	}
+	used, err := GetUsedIps(dbname)
+	if err != nil {
+		return suggestions, err
+	}
+	for _, pool := range pools {
+		if ip, found := NextFreeIp(pool, used); found {
+			suggestions = append(suggestions, ip)
+		}
+	}
+	return suggestions, nil
+}
+
+// GetIpSuggestion returns the address proposed by default (the first free one or DHCP)
+// and the hint shown in the dialogs, the errors are logged
+func GetIpSuggestion() (string, string) {
+	suggestions, err := SuggestIpAddrs(GetCbsdDbConnString(false))
+	if err != nil {
+		LogError("Cannot suggest IP address", err)
+	}
+	if len(suggestions) == 0 {
+		return "DHCP", "No free addresses found in the configured subnets"
+	}
+	return suggestions[0], "Free addresses: " + strings.Join(suggestions, ", ")
+}
@@ -0,0 +1,110 @@
+package host
+
+import (
+	"net"
+	"testing"
+)
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, pool, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestNextFreeIp(t *testing.T) {
+	tests := []struct {
+		pool  string
+		used  []string
+		want  string
+		found bool
+	}{
+		{"10.0.0.0/24", nil, "10.0.0.1/24", true},
+		{"10.0.0.0/24", []string{"10.0.0.1", "10.0.0.2"}, "10.0.0.3/24", true},
+		{"10.0.0.17/24", nil, "10.0.0.1/24", true},
+		// the broadcast address is never suggested
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2"}, "", false},
+		{"10.0.0.0/31", nil, "", false},
+		{"10.0.0.5/32", nil, "", false},
+		{"2001:db8::/64", nil, "2001:db8::1/64", true},
+		{"2001:db8::/64", []string{"2001:db8::1"}, "2001:db8::2/64", true},
+		// IPv6 has no broadcast address
+		{"2001:db8::/127", nil, "2001:db8::1/127", true},
+		{"2001:db8::/128", nil, "", false},
+	}
+	for _, tt := range tests {
+		used := make(map[string]bool)
+		for _, ip := range tt.used {
+			used[ip] = true
+		}
+		got, found := NextFreeIp(mustParseCIDR(t, tt.pool), used)
+		if got != tt.want || found != tt.found {
+			t.Errorf("NextFreeIp(%s, %v) = %q, %v; want %q, %v", tt.pool, tt.used, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestNextFreeIpScanLimit(t *testing.T) {
+	pool := mustParseCIDR(t, "10.0.0.0/8")
+	used := make(map[string]bool)
+	ip := net.IPv4(10, 0, 0, 0).To4()
+	for i := 0; i < IP_SCAN_LIMIT; i++ {
+		ip = nextIp(ip)
+		used[ip.String()] = true
+	}
+	if got, found := NextFreeIp(pool, used); found {
+		t.Errorf("NextFreeIp after %d used addresses = %q, want none", IP_SCAN_LIMIT, got)
+	}
+	delete(used, ip.String())
+	if got, found := NextFreeIp(pool, used); !found || got != ip.String()+"/8" {
+		t.Errorf("NextFreeIp with the last scanned address free = %q, %v; want %q", got, found, ip.String()+"/8")
+	}
+}
+
+func TestNextIp(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.0.0.1", "10.0.0.2"},
+		{"10.0.0.255", "10.0.1.0"},
+		{"10.255.255.255", "11.0.0.0"},
+		{"255.255.255.255", "0.0.0.0"},
+		{"2001:db8::ffff", "2001:db8::1:0"},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		if got := nextIp(ip).String(); got != tt.want {
+			t.Errorf("nextIp(%s) = %s, want %s", tt.ip, got, tt.want)
+		}
+		if ip.String() != tt.ip {
+			t.Errorf("nextIp(%s) changed its argument to %s", tt.ip, ip)
+		}
+	}
+}
+
+func TestIsBroadcast(t *testing.T) {
+	tests := []struct {
+		ip   string
+		pool string
+		want bool
+	}{
+		{"10.0.0.255", "10.0.0.0/24", true},
+		{"10.0.0.254", "10.0.0.0/24", false},
+		{"10.0.0.3", "10.0.0.0/30", true},
+		{"10.0.0.1", "10.0.0.0/31", true},
+		{"10.0.0.5", "10.0.0.5/32", true},
+		{"10.255.255.255", "10.0.0.0/8", true},
+		{"10.0.255.255", "10.0.0.0/8", false},
+	}
+	for _, tt := range tests {
+		if got := isBroadcast(net.ParseIP(tt.ip).To4(), mustParseCIDR(t, tt.pool)); got != tt.want {
+			t.Errorf("isBroadcast(%s, %s) = %v, want %v", tt.ip, tt.pool, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package host
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Number of the finished jobs kept for the API
+const JOBS_MAX int = 100
+
+// JobInfo is the state of a job
+type JobInfo struct {
+	Id       string     `json:"id"`
+	Name     string     `json:"name"`   // container name
+	Action   string     `json:"action"` // action id
+	Args     []string   `json:"args"`   // cbsd arguments
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Done     bool       `json:"done"`
+	Error    string     `json:"error,omitempty"`
+	Output   []string   `json:"output,omitempty"`
+}
+
+// Job is a cbsd command run in background, its output can be followed while it runs
+type Job struct {
+	JobInfo
+	mu      sync.Mutex
+	updated chan struct{} // closed and replaced on each change
+}
+
+// JobObserver is told about the jobs when they finish
+type JobObserver func(info JobInfo)
+
+var jobObservers = make([]JobObserver, 0)
+
+// AddJobObserver adds the observer, it must be called before the jobs are started
+func AddJobObserver(o JobObserver) {
+	jobObservers = append(jobObservers, o)
+}
+
+var jobsMu sync.Mutex
+var jobs = make(map[string]*Job)
+var jobsSeq int
+
+// StartJob runs cbsd with the arguments in background, if a job of the container
+// is still running it is returned with false and nothing is started
+func StartJob(name string, action string, args []string) (*Job, bool) {
+	jobsMu.Lock()
+	if running := getRunningJob(name); running != nil {
+		jobsMu.Unlock()
+		return running, false
+	}
+	jobsSeq++
+	job := &Job{
+		JobInfo: JobInfo{
+			Id:      strconv.Itoa(jobsSeq),
+			Name:    name,
+			Action:  action,
+			Args:    args,
+			Started: time.Now(),
+			Output:  make([]string, 0),
+		},
+		updated: make(chan struct{}),
+	}
+	jobs[job.Id] = job
+	pruneJobs()
+	jobsMu.Unlock()
+	go job.run()
+	return job, true
+}
+
+// getRunningJob returns the job of the container not done yet or nil, jobsMu is locked
+func getRunningJob(name string) *Job {
+	for _, job := range jobs {
+		job.mu.Lock()
+		running := job.Name == name && !job.Done
+		job.mu.Unlock()
+		if running {
+			return job
+		}
+	}
+	return nil
+}
+
+func (job *Job) run() {
+	command, args := GetCbsdCommand(job.Args...)
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), "NOCOLOR=1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		job.finish(err)
+		return
+	}
+	cmd.Stderr = cmd.Stdout
+	if err = cmd.Start(); err != nil {
+		job.finish(err)
+		return
+	}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		job.addLine(scanner.Text())
+	}
+	job.finish(cmd.Wait())
+}
+
+func (job *Job) notify() {
+	close(job.updated)
+	job.updated = make(chan struct{})
+}
+
+func (job *Job) addLine(line string) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Output = append(job.Output, line)
+	job.notify()
+}
+
+func (job *Job) finish(err error) {
+	job.mu.Lock()
+	finished := time.Now()
+	job.Done = true
+	job.Finished = &finished
+	if err != nil {
+		job.Error = err.Error()
+		LogError("Job "+job.Id+" ("+job.Action+" "+job.Name+") failed", err)
+	}
+	job.notify()
+	job.mu.Unlock()
+	if rerr := RecordAction(job.Action, finished.Sub(job.Started), err != nil); rerr != nil {
+		LogError("Cannot record action in "+STATE_DB_NAME, rerr)
+	}
+	info := job.GetInfo(false)
+	for _, o := range jobObservers {
+		o(info)
+	}
+}
+
+// Follow returns the output lines from the line number from, if the job is done
+// and the channel closed on the next change
+func (job *Job) Follow(from int) ([]string, bool, <-chan struct{}) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	lines := make([]string, 0)
+	if from < len(job.Output) {
+		lines = append(lines, job.Output[from:]...)
+	}
+	return lines, job.Done, job.updated
+}
+
+// GetInfo returns the state of the job, without output if withOutput is false
+func (job *Job) GetInfo(withOutput bool) JobInfo {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	res := job.JobInfo
+	res.Output = nil
+	if withOutput {
+		res.Output = append([]string{}, job.Output...)
+	}
+	return res
+}
+
+func GetJob(id string) (*Job, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	job, found := jobs[id]
+	return job, found
+}
+
+// GetJobs returns the jobs from the newest
+func GetJobs() []*Job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	res := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		res = append(res, job)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Started.After(res[j].Started) })
+	return res
+}
+
+// pruneJobs removes the oldest finished jobs above JOBS_MAX, jobsMu is locked
+func pruneJobs() {
+	if len(jobs) <= JOBS_MAX {
+		return
+	}
+	finished := make([]*Job, 0)
+	for _, job := range jobs {
+		job.mu.Lock()
+		if job.Done {
+			finished = append(finished, job)
+		}
+		job.mu.Unlock()
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Started.Before(finished[j].Started) })
+	excess := len(jobs) - JOBS_MAX
+	for i := 0; i < excess && i < len(finished); i++ {
+		delete(jobs, finished[i].Id)
+	}
+}
@@ -0,0 +1,62 @@
+package host
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const EXPORTER_INTERVAL_DEFAULT int = 60 // seconds
+
+// ActionStats sums up the runs of an action, durations are in seconds
+type ActionStats struct {
+	Action       string
+	Runs         int64
+	Failures     int64
+	DurationSum  float64
+	LastDuration float64
+	LastRun      time.Time
+	LastFailed   bool
+}
+
+// RecordAction adds a run of the action finished now to its statistics in the actions table
+// of the state database, the exporter reads them from there
+func RecordAction(action string, duration time.Duration, failed bool) error {
+	return WriteState("action", action, strconv.FormatFloat(duration.Seconds(), 'f', -1, 64), strconv.FormatBool(failed))
+}
+
+func recordActionTx(tx *sql.Tx, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: action <action> <seconds> <failed>")
+	}
+	seconds, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return err
+	}
+	failed, err := strconv.ParseBool(args[2])
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO actions (action,runs,failures,duration_sum,last_duration,last_run,last_failed) VALUES (?,1,?,?,?,?,?) "+
+		"ON CONFLICT(action) DO UPDATE SET runs=runs+1, failures=failures+excluded.failures, duration_sum=duration_sum+excluded.duration_sum, "+
+		"last_duration=excluded.last_duration, last_run=excluded.last_run, last_failed=excluded.last_failed",
+		args[0], failed, seconds, seconds, time.Now().Unix(), failed)
+	return err
+}
+
+// GetActionStats returns the statistics of all the actions run, sorted by action
+func GetActionStats() ([]ActionStats, error) {
+	stats := make([]ActionStats, 0)
+	err := QueryState("SELECT action,runs,failures,duration_sum,last_duration,last_run,last_failed FROM actions ORDER BY action", func(rows *sql.Rows) error {
+		var st ActionStats
+		var lastrun int64
+		if err := rows.Scan(&st.Action, &st.Runs, &st.Failures, &st.DurationSum, &st.LastDuration, &lastrun, &st.LastFailed); err != nil {
+			return err
+		}
+		st.LastRun = time.Unix(lastrun, 0)
+		stats = append(stats, st)
+		return nil
+	})
+	return stats, err
+}
@@ -0,0 +1,75 @@
+package host
+
+import (
+	"net"
+	"strings"
+)
+
+// Placeholders accepted by cbsd instead of addresses in ip4_addr, "0" means no address
+var IpPlaceholders = []string{"DHCP", "REALDHCP", "DHCPv6"}
+
+// IpAddr is one entry of the ip4_addr value like "em0#10.0.0.2/24", "fd00::2/64" or "DHCP"
+type IpAddr struct {
+	Value       string // the entry as is
+	Interface   string // interface before '#', if any
+	Ip          net.IP // nil for placeholders and invalid entries
+	Net         *net.IPNet
+	Placeholder bool
+}
+
+// ParseIpAddrs splits the ip4_addr value by commas and parses the entries
+func ParseIpAddrs(value string) []IpAddr {
+	addrs := make([]IpAddr, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || entry == "0" {
+			continue
+		}
+		addr := IpAddr{Value: entry}
+		ip := entry
+		if pos := strings.Index(ip, "#"); pos >= 0 {
+			addr.Interface = ip[:pos]
+			ip = ip[pos+1:]
+		}
+		for _, p := range IpPlaceholders {
+			if strings.EqualFold(ip, p) {
+				addr.Placeholder = true
+			}
+		}
+		if !addr.Placeholder {
+			if strings.Contains(ip, "/") {
+				addr.Ip, addr.Net, _ = net.ParseCIDR(ip)
+			} else {
+				addr.Ip = net.ParseIP(ip)
+			}
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// ParseIpPools parses the networks separated by spaces or commas like "10.0.0.0/24 10.0.1.0/24",
+// the invalid ones are returned separately
+func ParseIpPools(value string) ([]*net.IPNet, []string) {
+	pools := make([]*net.IPNet, 0)
+	invalid := make([]string, 0)
+	for _, p := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+		_, pool, err := net.ParseCIDR(p)
+		if err != nil {
+			invalid = append(invalid, p)
+			continue
+		}
+		pools = append(pools, pool)
+	}
+	return pools, invalid
+}
+
+// IsInPools reports if the address belongs to one of the pools
+func IsInPools(ip net.IP, pools []*net.IPNet) bool {
+	for _, pool := range pools {
+		if pool.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package host
+
+import "testing"
+
+func TestParseIpAddrs(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []IpAddr
+	}{
+		{"", []IpAddr{}},
+		{"0", []IpAddr{}},
+		{"10.0.0.2", []IpAddr{{Value: "10.0.0.2"}}},
+		{"10.0.0.2/24", []IpAddr{{Value: "10.0.0.2/24"}}},
+		{"em0#10.0.0.2/24", []IpAddr{{Value: "em0#10.0.0.2/24", Interface: "em0"}}},
+		{"em0#fd00::2/64, DHCP", []IpAddr{
+			{Value: "em0#fd00::2/64", Interface: "em0"},
+			{Value: "DHCP", Placeholder: true},
+		}},
+		{"dhcpv6,REALDHCP", []IpAddr{
+			{Value: "dhcpv6", Placeholder: true},
+			{Value: "REALDHCP", Placeholder: true},
+		}},
+		{"vtnet0#DHCP", []IpAddr{{Value: "vtnet0#DHCP", Interface: "vtnet0", Placeholder: true}}},
+		{"10.0.0.300, ,em0#", []IpAddr{{Value: "10.0.0.300"}, {Value: "em0#", Interface: "em0"}}},
+	}
+	wantIps := map[string]string{
+		"10.0.0.2":        "10.0.0.2",
+		"10.0.0.2/24":     "10.0.0.2",
+		"em0#10.0.0.2/24": "10.0.0.2",
+		"em0#fd00::2/64":  "fd00::2",
+	}
+	wantNets := map[string]string{
+		"10.0.0.2/24":     "10.0.0.0/24",
+		"em0#10.0.0.2/24": "10.0.0.0/24",
+		"em0#fd00::2/64":  "fd00::/64",
+	}
+	for _, tt := range tests {
+		got := ParseIpAddrs(tt.value)
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseIpAddrs(%q) returned %d entries, want %d", tt.value, len(got), len(tt.want))
+			continue
+		}
+		for i, addr := range got {
+			want := tt.want[i]
+			if addr.Value != want.Value || addr.Interface != want.Interface || addr.Placeholder != want.Placeholder {
+				t.Errorf("ParseIpAddrs(%q)[%d] = %+v, want %+v", tt.value, i, addr, want)
+			}
+			ip := ""
+			if addr.Ip != nil {
+				ip = addr.Ip.String()
+			}
+			if ip != wantIps[want.Value] {
+				t.Errorf("ParseIpAddrs(%q)[%d].Ip = %q, want %q", tt.value, i, ip, wantIps[want.Value])
+			}
+			ipnet := ""
+			if addr.Net != nil {
+				ipnet = addr.Net.String()
+			}
+			if ipnet != wantNets[want.Value] {
+				t.Errorf("ParseIpAddrs(%q)[%d].Net = %q, want %q", tt.value, i, ipnet, wantNets[want.Value])
+			}
+		}
+	}
+}
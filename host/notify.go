@@ -0,0 +1,108 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const WATCH_INTERVAL_DEFAULT int = 15 // seconds
+const NOTIFY_SCRIPT_TIMEOUT time.Duration = 30 * time.Second
+const NOTIFY_SYSLOG_TAG string = "cbsd-tui"
+
+// Hooks of the containers without their own hooks
+const NOTIFY_HOOKS_DEFAULT string = "*"
+
+// NotifyHook is run when a container stops unexpectedly, all the fields are optional
+type NotifyHook struct {
+	Script      string `json:"script"`       // run with the event as arguments and in CBSD_* environment variables
+	Syslog      bool   `json:"syslog"`       // log the event to syslog with LOG_WARNING priority
+	WebhookFile string `json:"webhook_file"` // append the JSON payload a webhook would get to the file
+}
+
+// StateEvent is a change of the container state not initiated from the TUI
+type StateEvent struct {
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	Name string    `json:"name"`
+	Type string    `json:"type"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+func (ev StateEvent) String() string {
+	return fmt.Sprintf("%s %s %s is %s (was %s)", ev.Time.Format("15:04:05"), ev.Type, ev.Name, ev.To, ev.From)
+}
+
+// GetNotifyHook returns the hooks of the container or the default ones
+func GetNotifyHook(name string) (NotifyHook, bool) {
+	if hook, found := Cfg.NotifyHooks[name]; found {
+		return hook, true
+	}
+	hook, found := Cfg.NotifyHooks[NOTIFY_HOOKS_DEFAULT]
+	return hook, found
+}
+
+// Run runs all the hooks configured, the errors of the hooks are returned
+func (hook NotifyHook) Run(ev StateEvent) []error {
+	errs := make([]error, 0)
+	if hook.Syslog {
+		if err := sendSyslog(ev); err != nil {
+			errs = append(errs, fmt.Errorf("syslog: %w", err))
+		}
+	}
+	if hook.WebhookFile != "" {
+		if err := writeWebhookFile(hook.WebhookFile, ev); err != nil {
+			errs = append(errs, fmt.Errorf("webhook file %s: %w", hook.WebhookFile, err))
+		}
+	}
+	if hook.Script != "" {
+		if err := runNotifyScript(hook.Script, ev); err != nil {
+			errs = append(errs, fmt.Errorf("script %s: %w", hook.Script, err))
+		}
+	}
+	return errs
+}
+
+func sendSyslog(ev StateEvent) error {
+	w, err := syslog.New(syslog.LOG_WARNING|syslog.LOG_DAEMON, NOTIFY_SYSLOG_TAG)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return w.Warning(fmt.Sprintf("%s %s is %s (was %s)", ev.Type, ev.Name, ev.To, ev.From))
+}
+
+func writeWebhookFile(path string, ev StateEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runNotifyScript(script string, ev StateEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), NOTIFY_SCRIPT_TIMEOUT)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, script, ev.Name, ev.Type, ev.From, ev.To)
+	cmd.Env = append(os.Environ(),
+		"CBSD_NAME="+ev.Name, "CBSD_TYPE="+ev.Type, "CBSD_FROM="+ev.From, "CBSD_TO="+ev.To,
+		"CBSD_TIME="+ev.Time.Format(time.RFC3339), "CBSD_HOST="+ev.Host)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, string(out))
+	}
+	return nil
+}
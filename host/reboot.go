@@ -0,0 +1,43 @@
+package host
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// SetPendingReboot marks the VM changed while running, the changes are applied after
+// the next restart; the VM is kept in the state database until it is stopped
+func SetPendingReboot(jname string, pending bool) error {
+	return WriteState("pending-reboot", jname, strconv.FormatBool(pending))
+}
+
+func setPendingRebootTx(tx *sql.Tx, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: pending-reboot <jname> <true|false>")
+	}
+	pending, err := strconv.ParseBool(args[1])
+	if err != nil {
+		return err
+	}
+	if pending {
+		_, err = tx.Exec("INSERT OR IGNORE INTO pending_reboot (jname) VALUES (?)", args[0])
+	} else {
+		_, err = tx.Exec("DELETE FROM pending_reboot WHERE jname=?", args[0])
+	}
+	return err
+}
+
+// GetPendingReboots returns the names of the VMs pending reboot
+func GetPendingReboots() (map[string]bool, error) {
+	pending := make(map[string]bool)
+	err := QueryState("SELECT jname FROM pending_reboot", func(rows *sql.Rows) error {
+		var jname string
+		if err := rows.Scan(&jname); err != nil {
+			return err
+		}
+		pending[jname] = true
+		return nil
+	})
+	return pending, err
+}
@@ -0,0 +1,341 @@
+package host
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// State of the containers not known to cbsd is kept in our own database readable by all
+// the operators; root writes it directly and the other users through doas like cbsd
+const STATE_DB_NAME string = "/var/db/cbsd-tui/state.sqlite"
+
+// Secrets kept to restore the state, like the original VNC passwords, are kept apart
+// in a database readable by root only
+const STATE_PRIVATE_DB_NAME string = "/var/db/cbsd-tui/private/secrets.sqlite"
+
+// Subcommand of cbsd-tui applying a write operation to the state database or handling
+// a secret, the secret is read from the standard input and written to the standard output:
+//
+//	cbsd-tui store <operation> [args...]
+//	cbsd-tui store secret-set|secret-get|secret-forget <kind> <name>
+const STORE_SUBCOMMAND string = "store"
+
+// Root-owned wrapper running only the store subcommand, when installed the other users
+// run it through doas instead of cbsd-tui itself so that doas can be restricted to it
+const STORE_WRAPPER string = "/usr/local/libexec/cbsd-tui-store"
+
+var stateSchema = []string{
+	"CREATE TABLE IF NOT EXISTS depends (jname TEXT NOT NULL, depend TEXT NOT NULL, PRIMARY KEY (jname, depend))",
+	"CREATE TABLE IF NOT EXISTS tags (jname TEXT NOT NULL, tag TEXT NOT NULL, PRIMARY KEY (jname, tag))",
+	"CREATE TABLE IF NOT EXISTS actions (action TEXT PRIMARY KEY, runs INTEGER NOT NULL, failures INTEGER NOT NULL, " +
+		"duration_sum REAL NOT NULL, last_duration REAL NOT NULL, last_run INTEGER NOT NULL, last_failed INTEGER NOT NULL)",
+	// the passwords were kept here in plain text before the private database
+	"DROP TABLE IF EXISTS vnc",
+	"CREATE TABLE IF NOT EXISTS vnc_saved (jname TEXT PRIMARY KEY, bind TEXT NOT NULL, pid INTEGER NOT NULL)",
+	"CREATE TABLE IF NOT EXISTS pending_reboot (jname TEXT PRIMARY KEY)",
+}
+
+// Names of the containers the state is kept for and the statements removing the state
+// of a container, ?1 is its name
+const stateNamesQuery string = "SELECT jname FROM depends UNION SELECT depend FROM depends UNION SELECT jname FROM tags UNION SELECT jname FROM vnc_saved UNION SELECT jname FROM pending_reboot"
+
+var stateForget = []string{
+	"DELETE FROM depends WHERE jname=?1 OR depend=?1",
+	"DELETE FROM tags WHERE jname=?1",
+	"DELETE FROM vnc_saved WHERE jname=?1",
+	"DELETE FROM pending_reboot WHERE jname=?1",
+}
+
+// StateOp is a write operation of the state database, args come from the command line
+type StateOp func(tx *sql.Tx, args []string) error
+
+var stateOps = map[string]StateOp{
+	"depends":        setDependsTx,
+	"tags":           setTagsTx,
+	"action":         recordActionTx,
+	"vnc-save":       saveVncTx,
+	"vnc-forget":     forgetVncTx,
+	"pending-reboot": setPendingRebootTx,
+	"forget":         forgetTx,
+}
+
+func forgetTx(tx *sql.Tx, args []string) error {
+	for _, name := range args {
+		for _, stmt := range stateForget {
+			if _, err := tx.Exec(stmt, name); err != nil {
+				return err
+			}
+		}
+		if err := forgetSecrets(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneState removes the state of the containers not in names, the destroyed ones,
+// so that a new container with the same name does not get it
+func PruneState(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	existing := make(map[string]bool)
+	for _, name := range names {
+		existing[name] = true
+	}
+	orphans := make([]string, 0)
+	err := QueryState(stateNamesQuery, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if !existing[name] {
+			orphans = append(orphans, name)
+		}
+		return nil
+	})
+	if err != nil || len(orphans) == 0 {
+		return err
+	}
+	return WriteState("forget", orphans...)
+}
+
+// openStateDb opens the state database, it is created with its tables for writing
+func openStateDb(write bool) (*sql.DB, error) {
+	if !write {
+		return sql.Open("sqlite3", "file:"+STATE_DB_NAME+"?mode=ro")
+	}
+	if err := os.MkdirAll(filepath.Dir(STATE_DB_NAME), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+STATE_DB_NAME+"?mode=rwc")
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range stateSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// QueryState runs the query on the state database and calls scan for each row,
+// nothing is done if the database was never written
+func QueryState(query string, scan func(rows *sql.Rows) error, args ...any) error {
+	if _, err := os.Stat(STATE_DB_NAME); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := openStateDb(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// WriteState applies the operation to the state database, through doas if needed
+func WriteState(op string, args ...string) error {
+	if USE_DOAS {
+		_, err := runStore("", append([]string{op}, args...)...)
+		return err
+	}
+	return ApplyStateOp(op, args)
+}
+
+// runStore runs the store subcommand through doas with the input on its standard input,
+// the secrets are not visible in the arguments
+func runStore(input string, args ...string) (string, error) {
+	command := []string{STORE_WRAPPER}
+	if _, err := os.Stat(STORE_WRAPPER); err != nil {
+		exe, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+		command = []string{exe, STORE_SUBCOMMAND}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(DOAS_PROGRAM, append(command, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", DOAS_PROGRAM, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// RunStoreCommand runs the store subcommand, it is run by root
+func RunStoreCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no state operation")
+	}
+	op, args := args[0], args[1:]
+	switch op {
+	case "secret-set", "secret-get", "secret-forget":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s <kind> <name>", op)
+		}
+	}
+	switch op {
+	case "secret-set":
+		secret, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		return setSecret(args[0], args[1], string(secret))
+	case "secret-get":
+		secret, err := getSecret(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, secret)
+		return err
+	case "secret-forget":
+		return forgetSecret(args[0], args[1])
+	}
+	return ApplyStateOp(op, args)
+}
+
+// SetSecret keeps the secret of the name, the first one is kept until it is forgotten
+func SetSecret(kind string, name string, secret string) error {
+	if USE_DOAS {
+		_, err := runStore(secret, "secret-set", kind, name)
+		return err
+	}
+	return setSecret(kind, name, secret)
+}
+
+// GetSecret returns the secret of the name, empty if there is none
+func GetSecret(kind string, name string) (string, error) {
+	if USE_DOAS {
+		return runStore("", "secret-get", kind, name)
+	}
+	return getSecret(kind, name)
+}
+
+func ForgetSecret(kind string, name string) error {
+	if USE_DOAS {
+		_, err := runStore("", "secret-forget", kind, name)
+		return err
+	}
+	return forgetSecret(kind, name)
+}
+
+// openPrivateDb opens the private database, its directory and file are root only
+func openPrivateDb() (*sql.DB, error) {
+	dir := filepath.Dir(STATE_PRIVATE_DB_NAME)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(STATE_PRIVATE_DB_NAME, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if err = os.Chmod(STATE_PRIVATE_DB_NAME, 0600); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+STATE_PRIVATE_DB_NAME+"?mode=rw")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS secrets (kind TEXT NOT NULL, name TEXT NOT NULL, secret TEXT NOT NULL, PRIMARY KEY (kind, name))")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func setSecret(kind string, name string, secret string) error {
+	db, err := openPrivateDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("INSERT OR IGNORE INTO secrets (kind,name,secret) VALUES (?,?,?)", kind, name, secret)
+	return err
+}
+
+func getSecret(kind string, name string) (string, error) {
+	db, err := openPrivateDb()
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var secret string
+	err = db.QueryRow("SELECT secret FROM secrets WHERE kind=? AND name=?", kind, name).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return secret, err
+}
+
+func forgetSecret(kind string, name string) error {
+	db, err := openPrivateDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("DELETE FROM secrets WHERE kind=? AND name=?", kind, name)
+	return err
+}
+
+// forgetSecrets removes the secrets of all the kinds of the name, it is run by root
+func forgetSecrets(name string) error {
+	if _, err := os.Stat(STATE_PRIVATE_DB_NAME); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := openPrivateDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("DELETE FROM secrets WHERE name=?", name)
+	return err
+}
+
+// ApplyStateOp runs the operation in a transaction, it is run by root
+func ApplyStateOp(op string, args []string) error {
+	fn, found := stateOps[op]
+	if !found {
+		return fmt.Errorf("unknown state operation '%s'", op)
+	}
+	db, err := openStateDb(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx, args); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,155 @@
+package host
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const SYSCTL_PROGRAM string = "/sbin/sysctl"
+const ZPOOL_PROGRAM string = "/sbin/zpool"
+const FREEBSD_VERSION_PROGRAM string = "/bin/freebsd-version"
+
+type PoolInfo struct {
+	Name     string
+	Size     string
+	Free     string
+	Capacity string
+}
+
+// HostInfo is the summary of the host shown by the dashboard
+type HostInfo struct {
+	Hostname    string
+	OsVersion   string
+	CbsdVersion string
+	LoadAverage string
+	FreeMemory  uint64
+	TotalMemory uint64
+	Pools       []PoolInfo
+}
+
+// RunProgram returns the trimmed standard output of the program
+func RunProgram(program string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(program, args...)
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, "NOCOLOR=1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", program, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GetCbsdCommand returns the program and the arguments running cbsd with args (through doas if needed)
+func GetCbsdCommand(args ...string) (string, []string) {
+	if USE_DOAS {
+		return DOAS_PROGRAM, append([]string{CBSD_PROGRAM}, args...)
+	}
+	return CBSD_PROGRAM, args
+}
+
+// RunCbsd runs cbsd with the arguments and returns its output
+func RunCbsd(args ...string) (string, error) {
+	command, cmdargs := GetCbsdCommand(args...)
+	return RunProgram(command, cmdargs...)
+}
+
+func GetSysctlUint(name string) (uint64, error) {
+	out, err := RunProgram(SYSCTL_PROGRAM, "-n", name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(out, 10, 64)
+}
+
+// GetLoadAverage returns the load averages like "0.15 0.20 0.18"
+func GetLoadAverage() (string, error) {
+	out, err := RunProgram(SYSCTL_PROGRAM, "-n", "vm.loadavg")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(strings.Trim(out, "{} ")), " "), nil
+}
+
+// GetMemory returns free (free and inactive pages) and total memory in bytes
+func GetMemory() (uint64, uint64, error) {
+	total, err := GetSysctlUint("hw.physmem")
+	if err != nil {
+		return 0, 0, err
+	}
+	pagesize, err := GetSysctlUint("hw.pagesize")
+	if err != nil {
+		return 0, total, err
+	}
+	free, err := GetSysctlUint("vm.stats.vm.v_free_count")
+	if err != nil {
+		return 0, total, err
+	}
+	inactive, err := GetSysctlUint("vm.stats.vm.v_inactive_count")
+	if err != nil {
+		return free * pagesize, total, nil
+	}
+	return (free + inactive) * pagesize, total, nil
+}
+
+func GetPools() ([]PoolInfo, error) {
+	pools := make([]PoolInfo, 0)
+	out, err := RunProgram(ZPOOL_PROGRAM, "list", "-H", "-o", "name,size,free,capacity")
+	if err != nil {
+		return pools, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		pools = append(pools, PoolInfo{Name: fields[0], Size: fields[1], Free: fields[2], Capacity: fields[3]})
+	}
+	return pools, nil
+}
+
+// GetHostInfo collects the host summary, the values which cannot be read are left empty
+func GetHostInfo() (HostInfo, []error) {
+	var info HostInfo
+	var err error
+	errs := make([]error, 0)
+	if info.Hostname, err = os.Hostname(); err != nil {
+		errs = append(errs, err)
+	}
+	if info.OsVersion, err = RunProgram(FREEBSD_VERSION_PROGRAM, "-u"); err != nil {
+		errs = append(errs, err)
+	}
+	if info.CbsdVersion, err = RunCbsd("version"); err != nil {
+		errs = append(errs, err)
+	}
+	if info.LoadAverage, err = GetLoadAverage(); err != nil {
+		errs = append(errs, err)
+	}
+	if info.FreeMemory, info.TotalMemory, err = GetMemory(); err != nil {
+		errs = append(errs, err)
+	}
+	if info.Pools, err = GetPools(); err != nil {
+		errs = append(errs, err)
+	}
+	return info, errs
+}
+
+// FormatSize shows the size in bytes like "1.5G"
+func FormatSize(b uint64) string {
+	units := []string{"B", "K", "M", "G", "T", "P"}
+	size := float64(b)
+	i := 0
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+	if i == 0 || size >= 10 {
+		return fmt.Sprintf("%.0f%s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", size, units[i])
+}
@@ -0,0 +1,97 @@
+package host
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var reTag = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func ValidateTag(tag string) error {
+	if !reTag.MatchString(tag) {
+		return fmt.Errorf("invalid tag '%s', use letters, digits, '_', '.' and '-'", tag)
+	}
+	return nil
+}
+
+// ParseTags splits the comma or space separated list of tags, the duplicates are removed
+func ParseTags(value string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ValidateTags checks all the tags of the list
+func ValidateTags(value string) error {
+	for _, tag := range ParseTags(value) {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTags returns the sorted tags of all the containers by name from the tags table of the state database
+func GetTags() (map[string][]string, error) {
+	tags := make(map[string][]string)
+	err := QueryState("SELECT jname,tag FROM tags ORDER BY jname,tag", func(rows *sql.Rows) error {
+		var jname, tag string
+		if err := rows.Scan(&jname, &tag); err != nil {
+			return err
+		}
+		tags[jname] = append(tags[jname], tag)
+		return nil
+	})
+	return tags, err
+}
+
+// SetTags replaces the tags of the container
+func SetTags(jname string, tags []string) error {
+	return WriteState("tags", append([]string{jname}, tags...)...)
+}
+
+func setTagsTx(tx *sql.Tx, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: tags <name> [tags...]")
+	}
+	for _, tag := range args[1:] {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE jname=?", args[0]); err != nil {
+		return err
+	}
+	for _, tag := range args[1:] {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (jname,tag) VALUES (?,?)", args[0], tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllTags returns the sorted list of the tags used by the containers
+func GetAllTags(tags map[string][]string) []string {
+	seen := make(map[string]bool)
+	all := make([]string, 0)
+	for _, ts := range tags {
+		for _, tag := range ts {
+			if !seen[tag] {
+				seen[tag] = true
+				all = append(all, tag)
+			}
+		}
+	}
+	sort.Strings(all)
+	return all
+}
@@ -0,0 +1,90 @@
+package host
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// SavedVnc is the original VNC console setting of a bhyve VM rebound by cbsd-tui,
+// it is kept in the state database until reverted so that the settings changed
+// by a killed session are reverted by the next one; the original password is kept
+// apart as a secret readable by root only
+type SavedVnc struct {
+	Bind string
+	Pid  int // process of the session which changed the settings
+}
+
+// Kind of the secrets keeping the original VNC passwords
+const VNC_PASSWORD_SECRET string = "vnc-password"
+
+// IsOwned tells if the settings were changed by this process or by a process which
+// does not run anymore
+func (sv SavedVnc) IsOwned() bool {
+	if sv.Pid == os.Getpid() {
+		return true
+	}
+	err := syscall.Kill(sv.Pid, 0)
+	return err != nil && !errors.Is(err, syscall.EPERM)
+}
+
+// SaveVnc keeps the original settings of jname, the first saved ones are kept
+// if the settings are changed again
+func SaveVnc(jname string, bind string, password string) error {
+	if err := SetSecret(VNC_PASSWORD_SECRET, jname, password); err != nil {
+		return err
+	}
+	return WriteState("vnc-save", jname, bind, strconv.Itoa(os.Getpid()))
+}
+
+func saveVncTx(tx *sql.Tx, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: vnc-save <jname> <bind> <pid>")
+	}
+	pid, err := strconv.Atoi(args[2])
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO vnc_saved (jname,bind,pid) VALUES (?,?,?) ON CONFLICT(jname) DO UPDATE SET pid=excluded.pid",
+		args[0], args[1], pid)
+	return err
+}
+
+// GetSavedVncPassword returns the original password of jname
+func GetSavedVncPassword(jname string) (string, error) {
+	return GetSecret(VNC_PASSWORD_SECRET, jname)
+}
+
+// ForgetVnc removes the saved settings of jname once they are reverted
+func ForgetVnc(jname string) error {
+	if err := WriteState("vnc-forget", jname); err != nil {
+		return err
+	}
+	return ForgetSecret(VNC_PASSWORD_SECRET, jname)
+}
+
+func forgetVncTx(tx *sql.Tx, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: vnc-forget <jname>")
+	}
+	_, err := tx.Exec("DELETE FROM vnc_saved WHERE jname=?", args[0])
+	return err
+}
+
+// GetSavedVnc returns the saved settings by VM name
+func GetSavedVnc() (map[string]SavedVnc, error) {
+	saved := make(map[string]SavedVnc)
+	err := QueryState("SELECT jname,bind,pid FROM vnc_saved", func(rows *sql.Rows) error {
+		var jname string
+		var sv SavedVnc
+		if err := rows.Scan(&jname, &sv.Bind, &sv.Pid); err != nil {
+			return err
+		}
+		saved[jname] = sv
+		return nil
+	})
+	return saved, err
+}
@@ -0,0 +1,182 @@
+package host
+
+import (
+	"fmt"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ZFS_PROGRAM string = "/sbin/zfs"
+
+// Dataset is a ZFS filesystem, volume or snapshot, sizes are in bytes
+type Dataset struct {
+	Name          string
+	Type          string
+	Used          uint64
+	Referenced    uint64
+	Quota         uint64
+	Reservation   uint64
+	CompressRatio string
+	Compression   string
+	Origin        string
+	Clones        []string
+}
+
+var datasetProperties = "name,type,used,referenced,quota,reservation,compressratio,compression,origin,clones"
+
+var reZfsSize = regexp.MustCompile(`^(none|[0-9]+(\.[0-9]+)?[KMGTPkmgtp]?)$`)
+
+// ValidateZfsSize checks the value of quota/reservation properties like "10G" or "none"
+func ValidateZfsSize(size string) error {
+	if !reZfsSize.MatchString(size) {
+		return fmt.Errorf("incorrect size '%s', use 'none' or a number with K, M, G, T or P suffix", size)
+	}
+	return nil
+}
+
+var reSnapshotName = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// ValidateSnapshotName checks the name of a ZFS snapshot, "gettimeofday" is replaced by cbsd
+func ValidateSnapshotName(name string) error {
+	if !reSnapshotName.MatchString(name) {
+		return fmt.Errorf("incorrect snapshot name '%s', use letters, digits and '_', '.', ':', '-'", name)
+	}
+	return nil
+}
+
+// GetZfsCommand returns the program and the arguments running zfs (through doas if needed)
+func GetZfsCommand(args ...string) (string, []string) {
+	if USE_DOAS {
+		return DOAS_PROGRAM, append([]string{ZFS_PROGRAM}, args...)
+	}
+	return ZFS_PROGRAM, args
+}
+
+func parseDatasets(out string) []Dataset {
+	datasets := make([]Dataset, 0)
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) < 10 {
+			continue
+		}
+		ds := Dataset{Name: f[0], Type: f[1], CompressRatio: f[6], Compression: f[7]}
+		ds.Used, _ = strconv.ParseUint(f[2], 10, 64)
+		ds.Referenced, _ = strconv.ParseUint(f[3], 10, 64)
+		ds.Quota, _ = strconv.ParseUint(f[4], 10, 64)
+		ds.Reservation, _ = strconv.ParseUint(f[5], 10, 64)
+		if f[8] != "-" {
+			ds.Origin = f[8]
+		}
+		if f[9] != "-" && f[9] != "" {
+			ds.Clones = strings.Split(f[9], ",")
+		}
+		datasets = append(datasets, ds)
+	}
+	return datasets
+}
+
+// GetDatasets lists all ZFS filesystems and volumes
+func GetDatasets() ([]Dataset, error) {
+	out, err := RunProgram(ZFS_PROGRAM, "list", "-H", "-p", "-t", "filesystem,volume", "-o", datasetProperties)
+	if err != nil {
+		return make([]Dataset, 0), err
+	}
+	return parseDatasets(out), nil
+}
+
+// GetSnapshots lists the snapshots of the dataset (not recursive)
+func GetSnapshots(dataset string) ([]Dataset, error) {
+	out, err := RunProgram(ZFS_PROGRAM, "list", "-H", "-p", "-t", "snapshot", "-d", "1", "-o", datasetProperties, dataset)
+	if err != nil {
+		return make([]Dataset, 0), err
+	}
+	return parseDatasets(out), nil
+}
+
+// SnapshotTime is a ZFS snapshot of Dataset with its creation time
+type SnapshotTime struct {
+	Dataset string
+	Name    string
+	Created time.Time
+}
+
+// GetSnapshotTimes lists all the ZFS snapshots with their creation time
+func GetSnapshotTimes() ([]SnapshotTime, error) {
+	snaps := make([]SnapshotTime, 0)
+	out, err := RunProgram(ZFS_PROGRAM, "list", "-H", "-p", "-t", "snapshot", "-o", "name,creation")
+	if err != nil {
+		return snaps, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) < 2 {
+			continue
+		}
+		dataset, name, found := strings.Cut(f[0], "@")
+		created, err := strconv.ParseInt(f[1], 10, 64)
+		if !found || err != nil {
+			continue
+		}
+		snaps = append(snaps, SnapshotTime{Dataset: dataset, Name: name, Created: time.Unix(created, 0)})
+	}
+	return snaps, nil
+}
+
+// GetContainerDatasets returns the datasets of the container, cbsd keeps
+// the data of jname in <pool>/.../<jname>-data and its children
+func GetContainerDatasets(jname string, datasets []Dataset) []Dataset {
+	res := make([]Dataset, 0)
+	for _, ds := range datasets {
+		if IsContainerDataset(jname, ds.Name) {
+			res = append(res, ds)
+		}
+	}
+	return res
+}
+
+// GetJailsDataDataset returns the dataset of the cbsd jails-data directory,
+// the datasets of the containers and their clones are under it
+func GetJailsDataDataset() (string, error) {
+	cbsdUser, err := user.Lookup(CBSD_USER_NAME)
+	if err != nil {
+		return "", err
+	}
+	return RunProgram(ZFS_PROGRAM, "list", "-H", "-o", "name", cbsdUser.HomeDir+CBSD_JAILS_DATA_DIR)
+}
+
+// GetDestroyedDatasets returns the datasets and snapshots "zfs destroy -r" would remove
+// with the dataset, from a dry run
+func GetDestroyedDatasets(dataset string) ([]string, error) {
+	destroyed := make([]string, 0)
+	command, args := GetZfsCommand("destroy", "-r", "-n", "-v", "-p", dataset)
+	out, err := RunProgram(command, args...)
+	if err != nil {
+		return destroyed, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if action, name, found := strings.Cut(line, "\t"); found && action == "destroy" {
+			destroyed = append(destroyed, name)
+		}
+	}
+	return destroyed, nil
+}
+
+func IsContainerDataset(jname string, dataset string) bool {
+	for _, part := range strings.Split(dataset, "/")[1:] {
+		if part == jname+"-data" {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatZfsSize shows the size of the property, "none" for zero quota and reservation
+func FormatZfsSize(b uint64, zero string) string {
+	if b == 0 {
+		return zero
+	}
+	return FormatSize(b)
+}
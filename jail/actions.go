@@ -0,0 +1,107 @@
+package jail
+
+import (
+	"fmt"
+
+	"tui"
+)
+
+var actions = tui.NewActionRegistry[*Jail]()
+
+func init() {
+	actions.Register(
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_ACTIONS, Label: ACTIONS, Bottom: true,
+			Run: func(jail *Jail) { actions.OpenActionDialog(jail.jtui, jail) },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_STARTSTOP, Label: STARTSTOP, Bottom: true, Menu: true,
+			StateLabel: func(jail *Jail) string {
+				if jail.IsRunning() {
+					return STOP
+				}
+				return START
+			},
+			Available: func(jail *Jail) bool { return jail.IsRunning() || jail.IsRunnable() },
+			CliArgs: func(jail *Jail) []string {
+				if jail.IsRunning() {
+					return jail.GetCliArgs(commandJailStop, "inter=1")
+				}
+				return jail.GetCliArgs(commandJailStart, "inter=1")
+			},
+			Run: func(jail *Jail) { jail.StartStop() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_SNAPSHOT, Label: CREATESNAP, Bottom: true, Menu: true,
+			CliArgs: func(jail *Jail) []string {
+				return jail.GetSnapshotCliArgs("gettimeofday")
+			},
+			Run: func(jail *Jail) { jail.OpenSnapshotDialog() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_SNAPSHOTS, Label: DELSNAP, Bottom: true, Menu: true,
+			Run: func(jail *Jail) { jail.OpenSnapActionsDialog() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_VIEW, Label: VIEW, Bottom: true, Menu: true,
+			Run: func(jail *Jail) { jail.View() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_EDIT, Label: EDIT, Bottom: true, Menu: true,
+			Run: func(jail *Jail) { jail.OpenEditDialog() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_CLONE, Label: CLONE, Bottom: true, Menu: true,
+			Run: func(jail *Jail) { jail.OpenCloneDialog() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_EXPORT, Label: EXPORT, Bottom: true, Menu: true,
+			CliArgs: func(jail *Jail) []string { return jail.GetCliArgs(commandJailExport) },
+			Run:     func(jail *Jail) { jail.Export() },
+		},
+		&tui.Action[*Jail]{
+			Id: tui.ACTION_DESTROY, Label: DESTROY, Bottom: true, Menu: true, Confirm: tui.CONFIRM_ALWAYS,
+			ConfirmText: func(jail *Jail) string { return "Really destroy jail " + jail.Jname + "??" },
+			CliArgs:     func(jail *Jail) []string { return jail.GetCliArgs(commandJailDestroy) },
+			Run:         func(jail *Jail) { jail.Destroy() },
+		},
+	)
+}
+
+// GetCliArgs returns the cbsd arguments of the command for the jail
+func (jail *Jail) GetCliArgs(command string, args ...string) []string {
+	res := append([]string{command}, args...)
+	return append(res, fmt.Sprintf("%s=%s", argJailName, jail.Jname))
+}
+
+func (jail *Jail) GetBottomMenuActions() []string {
+	return actions.GetBottomMenuActions()
+}
+
+func (jail *Jail) GetMenuActions() []string {
+	return actions.GetMenuActions()
+}
+
+func (jail *Jail) GetActionCaption(action string) string {
+	return actions.GetCaption(action)
+}
+
+func (jail *Jail) IsActionAvailable(action string) bool {
+	return actions.IsAvailable(jail, action)
+}
+
+func (jail *Jail) GetCliActions() []string {
+	return actions.GetCliActions(jail)
+}
+
+func (jail *Jail) GetActionCliArgs(action string) []string {
+	return actions.GetCliArgs(jail, action)
+}
+
+func (jail *Jail) GetActionConfirmText(action string) string {
+	return actions.GetConfirmText(jail, action)
+}
+
+func (jail *Jail) ExecuteAction(action string) bool {
+	return actions.Execute(jail.jtui, jail, action)
+}
@@ -13,12 +13,12 @@ import (
 	"github.com/gcla/gowid"
 	"github.com/gcla/gowid/widgets/dialog"
 	"github.com/gcla/gowid/widgets/edit"
-	"github.com/gdamore/tcell"
 	_ "github.com/mattn/go-sqlite3"
 
 	//"github.com/prometheus/common/log"
 	"github.com/quasilyte/gsignal"
 
+	"container"
 	"host"
 	"tui"
 )
@@ -36,7 +36,6 @@ type Jail struct {
 }
 
 const (
-	HELP       = "Help"
 	START      = "Start"
 	STOP       = "Stop"
 	STARTSTOP  = "Start/Stop"
@@ -49,21 +48,13 @@ const (
 	EXPORT     = "Export"
 	DESTROY    = "Destroy Jail"
 	ACTIONS    = "Actions..."
-	EXIT       = "Exit"
 )
 
+const CONTAINER_TYPE string = "jail"
+
 var strStatus = []string{"Off", "On", "Slave", "Unknown(3)", "Unknown(4)", "Unknown(5)"}
 var strAutoStart = []string{"Off", "On"}
 var strHeaderTitles = []string{"NAME", "IP4_ADDRESS", "STATUS", "AUTOSTART", "VERSION"}
-var strActionsMenuItems = []string{STARTSTOP, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-
-var strStartedActionsMenuItems = []string{STOP, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-var strStoppedActionsMenuItems = []string{START, CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-var strNonRunnableActionsMenuItems = []string{"---", CREATESNAP, DELSNAP, VIEW, EDIT, CLONE, EXPORT, DESTROY}
-
-var strBottomMenuText1 = []string{" 1", " 2", " 3", " 4", " 5", " 6", " 7", " 8", " 10", " 11", " 12"}
-var strBottomMenuText2 = []string{HELP, ACTIONS, VIEW, EDIT, CLONE, EXPORT, CREATESNAP, DESTROY, EXIT, DELSNAP, STARTSTOP}
-var keysBottomMenu = []tcell.Key{tcell.KeyF1, tcell.KeyF2, tcell.KeyF3, tcell.KeyF4, tcell.KeyF5, tcell.KeyF6, tcell.KeyF7, tcell.KeyF8, tcell.KeyF10, tcell.KeyF11, tcell.KeyF12}
 
 var commandJailLogin string = "jlogin"
 var commandJailStart string = "jstart"
@@ -77,7 +68,7 @@ var argJailName = "jname"
 var argSnapName = "snapname"
 
 func (jail *Jail) GetType() string {
-	return "jail"
+	return CONTAINER_TYPE
 }
 
 func (jail *Jail) GetSignalUpdated() *gsignal.Event[string] {
@@ -92,42 +83,10 @@ func (jail *Jail) SetTui(t *tui.Tui) {
 	jail.jtui = t
 }
 
-func (jail *Jail) GetCommandHelp() string {
-	return HELP
-}
-
-func (jail *Jail) GetCommandExit() string {
-	return EXIT
-}
-
-func (jail *Jail) GetBottomMenuText1() []string {
-	return strBottomMenuText1
-}
-
-func (jail *Jail) GetBottomMenuText2() []string {
-	return strBottomMenuText2
-}
-
 func (jail *Jail) GetHeaderTitles() []string {
 	return strHeaderTitles
 }
 
-func (jail *Jail) GetActionsMenuItems() []string {
-	return strActionsMenuItems
-}
-
-func (jail *Jail) GetStartedActionsMenuItems() []string {
-	return strStartedActionsMenuItems
-}
-
-func (jail *Jail) GetStoppedActionsMenuItems() []string {
-	return strStoppedActionsMenuItems
-}
-
-func (jail *Jail) GetNonRunnableActionsMenuItems() []string {
-	return strNonRunnableActionsMenuItems
-}
-
 func (jail *Jail) GetName() string {
 	return jail.Jname
 }
@@ -325,6 +284,31 @@ func GetJailsFromDb(dbname string) ([]*Jail, error) {
 	return jails, nil
 }
 
+func init() {
+	container.RegisterType(container.ContainerType{Name: CONTAINER_TYPE, Emulator: "jail", Action: tui.ACTION_JAILS, Load: LoadContainers})
+}
+
+// Capabilities of the jails
+var _ container.Loginable = (*Jail)(nil)
+var _ container.Snapshotter = (*Jail)(nil)
+var _ container.Cloner = (*Jail)(nil)
+var _ container.Exporter = (*Jail)(nil)
+var _ container.Editor = (*Jail)(nil)
+var _ container.StatusChecker = (*Jail)(nil)
+
+// LoadContainers is the loader of the jail container type
+func LoadContainers(dbname string) ([]container.Container, error) {
+	list, err := GetJailsFromDb(dbname)
+	if err != nil {
+		return make([]container.Container, 0), err
+	}
+	cont := make([]container.Container, len(list))
+	for i := range list {
+		cont[i] = list[i]
+	}
+	return cont, nil
+}
+
 func (jail *Jail) PutJailToDb(dbname string) (bool, error) {
 	db, err := sql.Open("sqlite3", dbname)
 	if err != nil {
@@ -420,11 +404,10 @@ func (jail *Jail) GetJailFromDbFull(dbname string, jname string) (bool, error) {
 			       result[i] = string(raw)
 			   }
 			*/
-			if raw != nil {
-				jail.params[cols[i]] = string(raw)
-				result = true
-			}
+			// NULL columns are kept as empty values to be shown and set in the settings editor
+			jail.params[cols[i]] = string(raw)
 		}
+		result = true
 		//fmt.Printf("%#v\n", result)
 	}
 	return result, nil
@@ -485,7 +468,7 @@ func (jail *Jail) Export() {
 	} else {
 		command = host.CBSD_PROGRAM
 	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	jail.jtui.ExecActionCommand(jail.Jname, tui.ACTION_EXPORT, txtheader, command, args)
 }
 
 func (jail *Jail) Destroy() {
@@ -503,52 +486,28 @@ func (jail *Jail) Destroy() {
 	} else {
 		command = host.CBSD_PROGRAM
 	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	jail.jtui.ExecActionCommand(jail.Jname, tui.ACTION_DESTROY, txtheader, command, args)
 	jail.evtRefresh.Emit(nil)
 }
 
-func (jail *Jail) OpenDestroyDialog() {
-	var cbsdDestroyJailDialog *dialog.Widget
-	cbsdDestroyJailDialog = jail.jtui.MakeDialogForJail(
-		jail.Jname,
-		"Destroy jail "+jail.Jname,
-		[]string{"Really destroy jail " + jail.Jname + "??"},
-		nil, nil, nil, nil,
-		func(jname string, boolparams []bool, strparams []string) {
-			cbsdDestroyJailDialog.Close(jail.jtui.App)
-			jail.Destroy()
-		},
-	)
-	cbsdDestroyJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+// GetSnapshotCliArgs returns the cbsd arguments creating the snapshot
+func (jail *Jail) GetSnapshotCliArgs(snapname string) []string {
+	// cbsd jsnapshot mode=create snapname=gettimeofday jname=nim1
+	return jail.GetCliArgs(commandJailSnap, "mode=create", fmt.Sprintf("%s=%s", argSnapName, snapname))
 }
 
 func (jail *Jail) Snapshot(snapname string) {
-	// cbsd jsnapshot mode=create snapname=gettimeofday jname=nim1
-	var command string
-	txtheader := "Creating jail snapshot...\n"
-	args := make([]string, 0)
-	if host.USE_DOAS {
-		args = append(args, host.CBSD_PROGRAM)
-	}
-	args = append(args, commandJailSnap)
-	args = append(args, "mode=create")
-	args = append(args, fmt.Sprintf("%s=%s", argSnapName, snapname))
-	args = append(args, fmt.Sprintf("%s=%s", argJailName, jail.Jname))
-	if host.USE_DOAS {
-		command = host.DOAS_PROGRAM
-	} else {
-		command = host.CBSD_PROGRAM
-	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+	command, args := host.GetCbsdCommand(jail.GetSnapshotCliArgs(snapname)...)
+	jail.jtui.ExecActionCommand(jail.Jname, tui.ACTION_SNAPSHOT, "Creating jail snapshot...\n", command, args)
 }
 
 func (jail *Jail) OpenSnapshotDialog() {
 	var cbsdSnapshotJailDialog *dialog.Widget
-	cbsdSnapshotJailDialog = jail.jtui.MakeDialogForJail(
+	cbsdSnapshotJailDialog = jail.jtui.MakeValidatedDialogForJail(
 		jail.Jname,
 		"Snapshot jail "+jail.Jname,
 		nil, nil, nil,
-		[]string{"Snapshot name: "}, []string{"gettimeofday"},
+		[]tui.StrField{{Caption: "Snapshot name: ", Default: "gettimeofday", Validate: host.ValidateSnapshotName}},
 		func(jname string, boolparams []bool, strparams []string) {
 			cbsdSnapshotJailDialog.Close(jail.jtui.App)
 			jail.Snapshot(strparams[0])
@@ -557,108 +516,83 @@ func (jail *Jail) OpenSnapshotDialog() {
 	cbsdSnapshotJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
 }
 
-func (jail *Jail) Clone(jnewjname string, jnewhname string, newip string) {
-	//log.Infof("Clone %s to %s (%s) IP %s", jname, jnewjname, jnewhname, newip)
+// GetCloneCliArgs returns the cbsd arguments cloning the jail
+func (jail *Jail) GetCloneCliArgs(jnewjname string, jnewhname string, newip string) []string {
 	// cbsd jclone old=jail1 new=jail1clone host_hostname=jail1clone.domain.local ip4_addr=DHCP checkstate=0
-	var command string
-	txtheader := "Cloning jail...\n"
-
-	args := make([]string, 0)
-	if host.USE_DOAS {
-		args = append(args, host.CBSD_PROGRAM)
+	return []string{
+		commandJailClone,
+		fmt.Sprintf("old=%s", jail.Jname),
+		fmt.Sprintf("new=%s", jnewjname),
+		fmt.Sprintf("host_hostname=%s", jnewhname),
+		fmt.Sprintf("ip4_addr=%s", newip),
+		"checkstate=0",
 	}
-	args = append(args, commandJailClone)
-	args = append(args, fmt.Sprintf("old=%s", jail.Jname))
-	args = append(args, fmt.Sprintf("new=%s", jnewjname))
-	args = append(args, fmt.Sprintf("host_hostname=%s", jnewhname))
-	args = append(args, fmt.Sprintf("ip4_addr=%s", newip))
-	args = append(args, "checkstate=0")
+}
 
-	if host.USE_DOAS {
-		command = host.DOAS_PROGRAM
-	} else {
-		command = host.CBSD_PROGRAM
-	}
-	jail.jtui.ExecCommand(txtheader, command, args)
+func (jail *Jail) Clone(jnewjname string, jnewhname string, newip string) {
+	//log.Infof("Clone %s to %s (%s) IP %s", jname, jnewjname, jnewhname, newip)
+	command, args := host.GetCbsdCommand(jail.GetCloneCliArgs(jnewjname, jnewhname, newip)...)
+	jail.jtui.ExecActionCommand(jail.Jname, tui.ACTION_CLONE, "Cloning jail...\n", command, args)
 	jail.evtRefresh.Emit(nil)
 }
 
 func (jail *Jail) OpenCloneDialog() {
 	var cbsdCloneJailDialog *dialog.Widget
-	cbsdCloneJailDialog = jail.jtui.MakeDialogForJail(
+	newip, hint := host.GetIpSuggestion()
+	names, err := host.GetContainerNames(host.GetCbsdDbConnString(false))
+	if err != nil {
+		host.LogError("Cannot get containers names", err)
+	}
+	cbsdCloneJailDialog = jail.jtui.MakeValidatedDialogForJail(
 		jail.Jname,
 		"Clone jail "+jail.Jname,
-		nil, nil, nil,
-		[]string{"New jail name: ", "New host name: ", "New IP address: "},
-		[]string{jail.Jname + "clone", jail.Jname, "DHCP"},
+		[]string{hint}, nil, nil,
+		[]tui.StrField{
+			{Caption: "New jail name: ", Default: jail.Jname + "clone", Validate: tui.ValidateAll(tui.ValidateName, tui.ValidateUnique(names))},
+			{Caption: "New host name: ", Default: jail.Jname, Validate: tui.ValidateHostname},
+			{Caption: "New IP address: ", Default: newip, Validate: host.ValidateIpAddrs},
+		},
 		func(jname string, boolparams []bool, strparams []string) {
 			cbsdCloneJailDialog.Close(jail.jtui.App)
-			jail.Clone(strparams[0], strparams[1], strparams[2])
+			jail.Clone(strings.TrimSpace(strparams[0]), strings.TrimSpace(strparams[1]), strings.TrimSpace(strparams[2]))
 		},
 	)
 	cbsdCloneJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
 }
 
 func (jail *Jail) Edit(astart bool, version string, ip string) {
+	values := make(map[string]string)
+	keys := make([]string, 0)
 	if astart != jail.GetAutoStartBool() {
 		if astart {
-			jail.SetAstart(1)
+			values["astart"] = "1"
 		} else {
-			jail.SetAstart(0)
+			values["astart"] = "0"
 		}
+		keys = append(keys, "astart")
 	}
 	if version != jail.GetVer() {
-		jail.SetVer(version)
+		values["ver"] = version
+		keys = append(keys, "ver")
 	}
 	if ip != "" {
 		if ip != jail.GetAddr() {
-			jail.SetAddr(ip)
+			values["ip4_addr"] = ip
+			keys = append(keys, "ip4_addr")
 		}
 	}
-	_, err := jail.PutJailToDb(host.GetCbsdDbConnString(true))
-	if err != nil {
-		panic(err)
-	}
-	jail.evtUpdated.Emit(jail.Jname)
+	jail.SetSettings(values, keys)
 }
 
 func (jail *Jail) OpenEditDialog() {
-	var cbsdEditJailDialog *dialog.Widget
-	if !jail.IsRunning() {
-		cbsdEditJailDialog = jail.jtui.MakeDialogForJail(
-			jail.Jname,
-			"Edit jail "+jail.Jname,
-			nil,
-			[]string{"Autostart "}, []bool{jail.GetAutoStartBool()},
-			[]string{"Version: ", "IP address: "},
-			[]string{jail.GetVer(), jail.GetAddr()},
-			func(jname string, boolparams []bool, strparams []string) {
-				cbsdEditJailDialog.Close(jail.jtui.App)
-				jail.Edit(boolparams[0], strparams[0], strparams[1])
-			},
-		)
-	} else {
-		cbsdEditJailDialog = jail.jtui.MakeDialogForJail(
-			jail.Jname,
-			"Edit jail "+jail.Jname,
-			nil,
-			[]string{"Autostart "}, []bool{jail.GetAutoStartBool()},
-			[]string{"Version: "},
-			[]string{jail.GetVer()},
-			func(jname string, boolparams []bool, strparams []string) {
-				cbsdEditJailDialog.Close(jail.jtui.App)
-				jail.Edit(boolparams[0], strparams[0], "")
-			},
-		)
-	}
-	cbsdEditJailDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+	jail.OpenSettingsDialog()
 }
 
 func (jail *Jail) View() {
 	viewspace := edit.New(edit.Options{ReadOnly: true})
 	outdlg := jail.jtui.CreateActionsLogDialog(viewspace, jail.jtui.Console.Height())
 	outdlg.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.7}, jail.jtui.App)
-	viewspace.SetText(jail.GetJailViewString(), jail.jtui.App)
+	viewspace.SetText(jail.GetJailViewString()+jail.jtui.GetViewExtra(jail.Jname), jail.jtui.App)
 	jail.jtui.App.RedrawTerminal()
 }
 
@@ -668,10 +602,7 @@ func (jail *Jail) StartStop() {
 	var command string
 
 	if jail.IsRunning() {
-		if tui.CbsdJailConsoleActive == jail.Jname {
-			jail.jtui.SendTerminalCommand("exit")
-			tui.CbsdJailConsoleActive = ""
-		}
+		jail.jtui.DetachConsole(jail.Jname)
 		txtheader = "Stopping jail...\n"
 		if host.USE_DOAS {
 			args = append(args, host.CBSD_PROGRAM)
@@ -684,7 +615,7 @@ func (jail *Jail) StartStop() {
 		} else {
 			command = host.CBSD_PROGRAM
 		}
-		jail.jtui.ExecCommand(txtheader, command, args)
+		jail.jtui.ExecActionCommand(jail.Jname, tui.RUN_STOP, txtheader, command, args)
 	} else if jail.IsRunnable() {
 		txtheader = "Starting jail...\n"
 		command = host.SHELL_PROGRAM
@@ -698,7 +629,7 @@ func (jail *Jail) StartStop() {
 		}
 		defer os.Remove(script)
 		args = append(args, script)
-		jail.jtui.ExecShellCommand(txtheader, command, args, host.LOGFILE_JSTART)
+		jail.jtui.ExecActionShellCommand(jail.Jname, tui.RUN_START, txtheader, command, args, host.LOGFILE_JSTART)
 	}
 	_, _ = jail.UpdateJailFromDb(host.GetCbsdDbConnString(false))
 	jail.evtUpdated.Emit(jail.Jname)
@@ -709,9 +640,8 @@ func (jail *Jail) GetStartCommand() string {
 	return cmd
 }
 
-func (jail *Jail) GetLoginCommand() string {
-	cmd := fmt.Sprintf("%s %s=%s", commandJailLogin, argJailName, jail.Jname)
-	return cmd
+func (jail *Jail) GetLoginArgs() []string {
+	return []string{commandJailLogin, argJailName + "=" + jail.Jname}
 }
 
 func (jail *Jail) CreateScriptStartJail() (string, error) {
@@ -743,87 +673,6 @@ func (jail *Jail) CreateScriptStartJail() (string, error) {
 	return file.Name(), nil
 }
 
-func (jail *Jail) OpenActionDialog() {
-	var cbsdActionsDialog *dialog.Widget
-	var MenuLines []string
-	if jail.IsRunning() {
-		MenuLines = jail.GetStartedActionsMenuItems()
-	} else if jail.IsRunnable() {
-		MenuLines = jail.GetStoppedActionsMenuItems()
-	} else {
-		MenuLines = jail.GetNonRunnableActionsMenuItems()
-	}
-	cbsdActionsDialog = jail.jtui.MakeActionDialogForJail(jail.Jname, "Actions for "+jail.Jname, MenuLines,
-		[]func(jname string){
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.StartStop()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenSnapshotDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenSnapActionsDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.View()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenEditDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenCloneDialog()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.Export()
-			},
-			func(jname string) {
-				cbsdActionsDialog.Close(jail.jtui.App)
-				jail.OpenDestroyDialog()
-			},
-			func(jname string) {},
-		},
-	)
-	cbsdActionsDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
-}
-
-func (jail *Jail) ExecuteActionOnCommand(command string) {
-	switch command {
-	case ACTIONS: // Actions Menu
-		jail.OpenActionDialog()
-	case VIEW: // View
-		jail.View()
-	case EDIT: // Edit
-		jail.OpenEditDialog()
-	case CLONE: // Clone
-		jail.OpenCloneDialog()
-	case EXPORT: // Export
-		jail.Export()
-	case CREATESNAP: // Create Snapshot
-		jail.OpenSnapshotDialog()
-	case DESTROY: // Destroy
-		jail.OpenDestroyDialog()
-	case DELSNAP: // Destroy Snapshots
-		jail.OpenSnapActionsDialog()
-	case STARTSTOP: // Start/Stop
-		jail.StartStop()
-	}
-}
-
-func (jail *Jail) ExecuteActionOnKey(tkey int16) {
-	for i, k := range keysBottomMenu {
-		if int16(k) == tkey {
-			jail.ExecuteActionOnCommand(strBottomMenuText2[i])
-		}
-	}
-}
-
 func (jail *Jail) GetSnapshots() [][2]string {
 	var snap = [2]string{"", ""}
 	retsnap := make([][2]string, 0)
@@ -905,7 +754,7 @@ func (jail *Jail) DestroySnapshot(snapname string) {
 		command = host.CBSD_PROGRAM
 	}
 	if jail.jtui != nil {
-		jail.jtui.ExecCommand(txtheader, command, args)
+		jail.jtui.ExecActionCommand(jail.Jname, tui.RUN_DESTROY_SNAPSHOT, txtheader, command, args)
 	}
 }
 
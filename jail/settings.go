@@ -0,0 +1,378 @@
+package jail
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+
+	"host"
+	"tui"
+)
+
+const (
+	FIELD_STRING = iota
+	FIELD_BOOL
+	FIELD_INT
+)
+
+// JailField describes one editable column of the jails table
+type JailField struct {
+	Name    string
+	Caption string
+	Type    int
+	Live    bool // can be changed while the jail is running
+}
+
+type JailSection struct {
+	Title  string
+	Fields []JailField
+}
+
+const SECTION_OTHER = "Other"
+
+var commandJailSet string = "jset"
+
+var jailSections = []JailSection{
+	{"General", []JailField{
+		{"host_hostname", "Host name: ", FIELD_STRING, false},
+		{"astart", "Autostart ", FIELD_BOOL, true},
+		{"ver", "Version: ", FIELD_STRING, false},
+		{"arch", "Architecture: ", FIELD_STRING, false},
+		{"basename", "Base name: ", FIELD_STRING, false},
+		{"baserw", "Base RW ", FIELD_BOOL, false},
+		{"applytpl", "Apply template ", FIELD_BOOL, false},
+		{"floatresolv", "Float resolv.conf ", FIELD_BOOL, false},
+		{"mkhostsfile", "Make hosts file ", FIELD_BOOL, false},
+		{"protected", "Protected ", FIELD_BOOL, true},
+		{"hidden", "Hidden ", FIELD_BOOL, true},
+	}},
+	{"Network", []JailField{
+		{"ip4_addr", "IP address: ", FIELD_STRING, false},
+		{"interface", "Interface: ", FIELD_STRING, false},
+		{"vnet", "VNET ", FIELD_BOOL, false},
+		{"nic_hwaddr", "NIC MAC address: ", FIELD_STRING, false},
+		{"jdomain", "Domain: ", FIELD_STRING, false},
+		{"allow_raw_sockets", "Allow raw sockets ", FIELD_BOOL, false},
+		{"allow_reserved_ports", "Allow reserved ports ", FIELD_BOOL, false},
+	}},
+	{"Mounts", []JailField{
+		{"mount_fstab", "Fstab file: ", FIELD_STRING, false},
+		{"mount_src", "Mount /usr/src ", FIELD_BOOL, false},
+		{"mount_obj", "Mount /usr/obj ", FIELD_BOOL, false},
+		{"mount_kernel", "Mount /boot/kernel ", FIELD_BOOL, false},
+		{"mount_ports", "Mount /usr/ports ", FIELD_BOOL, false},
+		{"mount_fdescfs", "Mount fdescfs ", FIELD_BOOL, false},
+		{"allow_mount", "Allow mount ", FIELD_BOOL, false},
+		{"allow_nullfs", "Allow nullfs ", FIELD_BOOL, false},
+		{"allow_tmpfs", "Allow tmpfs ", FIELD_BOOL, false},
+		{"allow_zfs", "Allow zfs ", FIELD_BOOL, false},
+		{"allow_fusefs", "Allow fusefs ", FIELD_BOOL, false},
+		{"allow_procfs", "Allow procfs ", FIELD_BOOL, false},
+		{"allow_fdescfs", "Allow fdescfs ", FIELD_BOOL, false},
+		{"allow_linprocfs", "Allow linprocfs ", FIELD_BOOL, false},
+		{"allow_linsysfs", "Allow linsysfs ", FIELD_BOOL, false},
+		{"mdsize", "MD size: ", FIELD_STRING, false},
+	}},
+	{"Limits", []JailField{
+		{"cpuset", "CPU set: ", FIELD_STRING, false},
+		{"childrenmax", "Max children: ", FIELD_INT, false},
+		{"enforce_statfs", "Enforce statfs: ", FIELD_INT, false},
+		{"stop_timeout", "Stop timeout: ", FIELD_INT, true},
+		{"exec_timeout", "Exec timeout: ", FIELD_INT, true},
+		{"exec_fib", "Exec FIB: ", FIELD_INT, false},
+		{"allow_sysvipc", "Allow SysV IPC ", FIELD_BOOL, false},
+		{"allow_kmem", "Allow kmem ", FIELD_BOOL, false},
+		{"allow_vmm", "Allow vmm ", FIELD_BOOL, false},
+		{"allow_read_msgbuf", "Allow read msgbuf ", FIELD_BOOL, false},
+		{"allow_dying", "Allow dying ", FIELD_BOOL, false},
+	}},
+	{"Devfs", []JailField{
+		{"mount_devfs", "Mount devfs ", FIELD_BOOL, false},
+		{"allow_devfs", "Allow devfs ", FIELD_BOOL, false},
+		{"devfs_ruleset", "Devfs ruleset: ", FIELD_INT, false},
+	}},
+	{"Boot order", []JailField{
+		{"bootorder", "Boot order: ", FIELD_INT, true},
+		{"exec_prestart", "Exec prestart: ", FIELD_STRING, true},
+		{"exec_start", "Exec start: ", FIELD_STRING, true},
+		{"exec_poststart", "Exec poststart: ", FIELD_STRING, true},
+		{"exec_prestop", "Exec prestop: ", FIELD_STRING, true},
+		{"exec_stop", "Exec stop: ", FIELD_STRING, true},
+		{"exec_master_prestart", "Exec master prestart: ", FIELD_STRING, true},
+		{"exec_master_poststart", "Exec master poststart: ", FIELD_STRING, true},
+		{"exec_master_prestop", "Exec master prestop: ", FIELD_STRING, true},
+		{"exec_consolelog", "Console log: ", FIELD_STRING, true},
+	}},
+}
+
+// Columns which are managed by cbsd itself and never shown in the editor
+var jailReadOnlyColumns = []string{"jname", "jid", "status", "path", "data", "rcconf", "emulator"}
+
+func isReadOnlyColumn(name string) bool {
+	for _, c := range jailReadOnlyColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findJailField(name string) (JailField, bool) {
+	for _, s := range jailSections {
+		for _, f := range s.Fields {
+			if f.Name == name {
+				return f, true
+			}
+		}
+	}
+	return JailField{}, false
+}
+
+// GetSettingsSections returns the editor sections filtered by the columns
+// really present in the jails table; unknown columns go to the "Other" section
+func (jail *Jail) GetSettingsSections() []JailSection {
+	sections := make([]JailSection, 0)
+	for _, s := range jailSections {
+		fields := make([]JailField, 0)
+		for _, f := range s.Fields {
+			if _, found := jail.params[f.Name]; found {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) > 0 {
+			sections = append(sections, JailSection{Title: s.Title, Fields: fields})
+		}
+	}
+	other := make([]string, 0)
+	for key := range jail.params {
+		if _, found := findJailField(key); found || isReadOnlyColumn(key) {
+			continue
+		}
+		other = append(other, key)
+	}
+	if len(other) > 0 {
+		sort.Strings(other)
+		fields := make([]JailField, 0)
+		for _, key := range other {
+			fields = append(fields, JailField{key, key + ": ", FIELD_STRING, false})
+		}
+		sections = append(sections, JailSection{Title: SECTION_OTHER, Fields: fields})
+	}
+	return sections
+}
+
+func ValidateJailField(f JailField, value string) error {
+	switch f.Type {
+	case FIELD_BOOL:
+		if value != "0" && value != "1" {
+			return fmt.Errorf("%s must be 0 or 1", f.Name)
+		}
+	case FIELD_INT:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be an integer, got '%s'", f.Name, value)
+		}
+	case FIELD_STRING:
+		if strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("%s must be a single line", f.Name)
+		}
+		if f.Name == "ip4_addr" {
+			return host.ValidateIpAddrs(value)
+		}
+		if f.Name == "host_hostname" {
+			return tui.ValidateHostname(value)
+		}
+	}
+	return nil
+}
+
+// MakeFieldValidator checks the value of the field in the settings dialog,
+// an integer not set in the database (NULL) can be left empty
+func MakeFieldValidator(f JailField, current string) tui.Validator {
+	return func(value string) error {
+		if f.Type == FIELD_INT && value == "" && current == "" {
+			return nil
+		}
+		return ValidateJailField(f, value)
+	}
+}
+
+// GetSettingsDiff returns the list of parameters whose new value differs
+// from the current one, in the order of fields
+func (jail *Jail) GetSettingsDiff(fields []JailField, values map[string]string) []string {
+	diff := make([]string, 0)
+	for _, f := range fields {
+		newval, found := values[f.Name]
+		if !found {
+			continue
+		}
+		oldval := jail.params[f.Name]
+		if f.Type == FIELD_BOOL && oldval == "" {
+			// a NULL flag is shown unchecked, leaving it unchecked is no change
+			oldval = "0"
+		}
+		if newval != oldval {
+			diff = append(diff, f.Name)
+		}
+	}
+	return diff
+}
+
+// SetSettings applies the parameters via 'cbsd jset' so that cbsd hooks are executed,
+// the settings shown are read back from the database as jset may fail or reject some values
+func (jail *Jail) SetSettings(values map[string]string, keys []string) {
+	// cbsd jset jname=nim1 astart=1 ver=13.2
+	var command string
+	if len(keys) < 1 {
+		return
+	}
+	txtheader := "Changing jail settings...\n"
+	args := make([]string, 0)
+	if host.USE_DOAS {
+		args = append(args, host.CBSD_PROGRAM)
+	}
+	args = append(args, commandJailSet)
+	args = append(args, fmt.Sprintf("%s=%s", argJailName, jail.Jname))
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", key, values[key]))
+	}
+	if host.USE_DOAS {
+		command = host.DOAS_PROGRAM
+	} else {
+		command = host.CBSD_PROGRAM
+	}
+	if err := jail.jtui.ExecCommand(txtheader, command, args); err != nil {
+		host.LogError("Cannot change settings of jail "+jail.Jname, err)
+	}
+	if _, err := jail.GetJailFromDbFull(host.GetCbsdDbConnString(false), jail.Jname); err != nil {
+		host.LogError("Cannot read jail settings", err)
+	}
+	_, _ = jail.UpdateJailFromDb(host.GetCbsdDbConnString(false))
+	jail.evtUpdated.Emit(jail.Jname)
+}
+
+func (jail *Jail) OpenSettingsDialog() {
+	var cbsdSettingsDialog *dialog.Widget
+	_, err := jail.GetJailFromDbFull(host.GetCbsdDbConnString(false), jail.Jname)
+	if err != nil {
+		host.LogError("Cannot read jail settings", err)
+		return
+	}
+	sections := jail.GetSettingsSections()
+	MakeSectionFunction := func(section JailSection) func(jname string) {
+		return func(jname string) {
+			cbsdSettingsDialog.Close(jail.jtui.App)
+			jail.OpenSettingsSectionDialog(section)
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	for _, s := range sections {
+		menulines = append(menulines, s.Title)
+		cbfunc = append(cbfunc, MakeSectionFunction(s))
+	}
+	cbsdSettingsDialog = jail.jtui.MakeActionDialogForJail(jail.Jname, "Settings of "+jail.Jname, menulines, cbfunc)
+	cbsdSettingsDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+}
+
+func (jail *Jail) OpenSettingsSectionDialog(section JailSection) {
+	var cbsdSectionDialog *dialog.Widget
+	var txt []string
+	var boolfields []JailField
+	var boolnames []string
+	var booldefaults []bool
+	var strfields []JailField
+	var stredits []tui.StrField
+
+	running := jail.IsRunning()
+	for _, f := range section.Fields {
+		value := jail.params[f.Name]
+		if running && !f.Live {
+			txt = append(txt, f.Caption+value+" (jail must be stopped to change)")
+			continue
+		}
+		if f.Type == FIELD_BOOL {
+			boolfields = append(boolfields, f)
+			boolnames = append(boolnames, f.Caption)
+			booldefaults = append(booldefaults, value == "1")
+		} else {
+			strfields = append(strfields, f)
+			stredits = append(stredits, tui.StrField{Caption: f.Caption, Default: value, Validate: MakeFieldValidator(f, value)})
+			if f.Name == "ip4_addr" {
+				_, hint := host.GetIpSuggestion()
+				txt = append(txt, hint)
+			}
+		}
+	}
+	if len(boolfields) < 1 && len(strfields) < 1 {
+		cbsdSectionDialog = jail.jtui.MakeDialogForJail(
+			jail.Jname,
+			section.Title+" settings of "+jail.Jname,
+			txt,
+			nil, nil, nil, nil,
+			nil,
+		)
+		cbsdSectionDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.5}, jail.jtui.App)
+		return
+	}
+	cbsdSectionDialog = jail.jtui.MakeValidatedDialogForJail(
+		jail.Jname,
+		section.Title+" settings of "+jail.Jname,
+		txt,
+		boolnames, booldefaults,
+		stredits,
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdSectionDialog.Close(jail.jtui.App)
+			values := make(map[string]string)
+			fields := make([]JailField, 0)
+			for i, f := range boolfields {
+				if boolparams[i] {
+					values[f.Name] = "1"
+				} else {
+					values[f.Name] = "0"
+				}
+				fields = append(fields, f)
+			}
+			for i, f := range strfields {
+				values[f.Name] = strings.TrimSpace(strparams[i])
+				fields = append(fields, f)
+			}
+			jail.OpenSettingsDiffDialog(fields, values)
+		},
+	)
+	cbsdSectionDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.5}, jail.jtui.App)
+}
+
+func (jail *Jail) OpenSettingsDiffDialog(fields []JailField, values map[string]string) {
+	var cbsdDiffDialog *dialog.Widget
+	diff := jail.GetSettingsDiff(fields, values)
+	if len(diff) < 1 {
+		cbsdDiffDialog = jail.jtui.MakeDialogForJail(
+			jail.Jname,
+			"Settings of "+jail.Jname,
+			[]string{"Nothing to change"},
+			nil, nil, nil, nil,
+			nil,
+		)
+		cbsdDiffDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, jail.jtui.App)
+		return
+	}
+	txt := []string{"The following settings will be changed:"}
+	for _, key := range diff {
+		txt = append(txt, fmt.Sprintf("%s: '%s' -> '%s'", key, jail.params[key], values[key]))
+	}
+	cbsdDiffDialog = jail.jtui.MakeDialogForJail(
+		jail.Jname,
+		"Apply settings to "+jail.Jname,
+		txt,
+		nil, nil, nil, nil,
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdDiffDialog.Close(jail.jtui.App)
+			jail.SetSettings(values, diff)
+		},
+	)
+	cbsdDiffDialog.Open(jail.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.5}, jail.jtui.App)
+}
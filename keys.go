@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	"host"
+	"tui"
+)
+
+var keyMap *tui.KeyMap
+
+// LoadKeyMap makes the key map from the preset and the key bindings of the configuration
+func LoadKeyMap() {
+	var errs []error
+	keyMap, errs = tui.NewKeyMap(host.Cfg.Keymap, host.Cfg.Keys)
+	for _, err := range errs {
+		host.LogError("Key map error in "+host.CONFIG_FILE_NAME, err)
+	}
+}
+
+func GetHelpText() string {
+	lines := make([]string, 0)
+	lines = append(lines, txtHelpHeader...)
+	for _, ad := range tui.ActionDescriptions {
+		keys := keyMap.GetKeyNames(ad.Action)
+		if keys == "" {
+			continue
+		}
+		lines = append(lines, "- "+ad.Description+" use "+keys)
+	}
+	lines = append(lines, txtHelpFooter...)
+	return strings.Join(lines, "\n")
+}
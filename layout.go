@@ -0,0 +1,289 @@
+package main
+
+import (
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/columns"
+	"github.com/gcla/gowid/widgets/fill"
+	"github.com/gcla/gowid/widgets/holder"
+	"github.com/gcla/gowid/widgets/pile"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gdamore/tcell/v2"
+	log "github.com/sirupsen/logrus"
+
+	"host"
+	"tui"
+)
+
+const LAYOUT_VERTICAL string = "vertical"     // list above terminal
+const LAYOUT_HORIZONTAL string = "horizontal" // list and terminal side by side
+
+const (
+	MAXIMIZE_NONE int = iota
+	MAXIMIZE_LIST
+	MAXIMIZE_TERMINAL
+)
+
+// Size of the list pane is (50 + offset) percents of the screen
+const LAYOUT_MAX_OFFSET int = 40
+const LAYOUT_RESIZE_STEP int = 5
+
+type LayoutState struct {
+	Layout    string `json:"layout"`
+	Offset    int    `json:"offset"`
+	Maximized int    `json:"maximized"`
+}
+
+// UiState is saved in host.STATE_FILE_NAME between runs
+type UiState struct {
+	Layout             LayoutState `json:"layout"`
+	Theme              string      `json:"theme,omitempty"`
+	ThemeConfig        string      `json:"theme_config,omitempty"` // configured theme when Theme was chosen
+	DashboardCollapsed bool        `json:"dashboard_collapsed,omitempty"`
+	GroupByTag         bool        `json:"group_by_tag,omitempty"`
+	CollapsedTags      []string    `json:"collapsed_tags,omitempty"`
+}
+
+var uiState = UiState{Layout: LayoutState{Layout: LAYOUT_VERTICAL}}
+
+func LoadUiState() {
+	err := host.LoadState(&uiState)
+	if err != nil {
+		host.LogError("Cannot load UI state from "+host.GetStateFileName(), err)
+	}
+}
+
+func SaveUiState() {
+	err := host.SaveState(&uiState)
+	if err != nil {
+		host.LogError("Cannot save UI state to "+host.GetStateFileName(), err)
+	}
+}
+
+// SeparatorWidget resizes the panes with mouse wheel or dragging
+type SeparatorWidget struct {
+	gowid.IWidget
+	layout *LayoutWidget
+}
+
+func (w *SeparatorWidget) UserInput(ev interface{}, size gowid.IRenderSize, focus gowid.Selector, app gowid.IApp) bool {
+	if w.IWidget.UserInput(ev, size, focus, app) {
+		return true
+	}
+	evm, ok := ev.(*tcell.EventMouse)
+	if !ok {
+		return false
+	}
+	switch {
+	case evm.Buttons()&tcell.WheelUp != 0:
+		w.layout.Resize(app, LAYOUT_RESIZE_STEP)
+		return true
+	case evm.Buttons()&tcell.WheelDown != 0:
+		w.layout.Resize(app, -LAYOUT_RESIZE_STEP)
+		return true
+	case evm.Buttons()&tcell.Button1 != 0:
+		w.layout.dragging = true
+		return true
+	}
+	return false
+}
+
+// LayoutWidget arranges the containers list, the bottom menu, the status line
+// and the terminal according to LayoutState, tui.FOCUS_ON_LIST and
+// tui.FOCUS_ON_TERMINAL are used as focus positions whatever the layout is
+type LayoutWidget struct {
+	*holder.Widget
+	List     gowid.IWidget
+	Menu     gowid.IWidget
+	Status   gowid.IWidget
+	Terminal gowid.IWidget
+	State    *LayoutState
+	focus    int
+	dragging bool
+	root     *pile.Widget
+	body     *columns.Widget
+	right    *pile.Widget
+}
+
+func NewLayout(list gowid.IWidget, menu gowid.IWidget, status gowid.IWidget, term gowid.IWidget, state *LayoutState) *LayoutWidget {
+	res := &LayoutWidget{
+		List:     list,
+		Menu:     menu,
+		Status:   status,
+		Terminal: term,
+		State:    state,
+		focus:    tui.FOCUS_ON_LIST,
+	}
+	if res.State.Layout != LAYOUT_HORIZONTAL {
+		res.State.Layout = LAYOUT_VERTICAL
+	}
+	res.State.Offset = clampOffset(res.State.Offset)
+	res.Widget = holder.New(res.build())
+	return res
+}
+
+func clampOffset(offset int) int {
+	if offset > LAYOUT_MAX_OFFSET {
+		return LAYOUT_MAX_OFFSET
+	}
+	if offset < -LAYOUT_MAX_OFFSET {
+		return -LAYOUT_MAX_OFFSET
+	}
+	return offset
+}
+
+func (w *LayoutWidget) build() gowid.IWidget {
+	listDim := gowid.RenderWithWeight{W: 50 + w.State.Offset}
+	termDim := gowid.RenderWithWeight{W: 50 - w.State.Offset}
+	w.body = nil
+	w.right = nil
+	switch {
+	case w.State.Maximized == MAXIMIZE_LIST:
+		w.root = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.List, D: gowid.RenderWithWeight{W: 1}},
+			&gowid.ContainerWidget{IWidget: w.Menu, D: gowid.RenderWithUnits{U: 1}},
+		})
+	case w.State.Maximized == MAXIMIZE_TERMINAL:
+		w.root = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.Status, D: gowid.RenderWithUnits{U: 1}},
+			&gowid.ContainerWidget{IWidget: w.Terminal, D: gowid.RenderWithWeight{W: 1}},
+		})
+	case w.State.Layout == LAYOUT_HORIZONTAL:
+		vline := &SeparatorWidget{IWidget: styled.New(fill.New('│'), gowid.MakePaletteRef("line")), layout: w}
+		w.right = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.Status, D: gowid.RenderWithUnits{U: 1}},
+			&gowid.ContainerWidget{IWidget: w.Terminal, D: gowid.RenderWithWeight{W: 1}},
+		})
+		w.body = columns.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.List, D: listDim},
+			&gowid.ContainerWidget{IWidget: vline, D: gowid.RenderWithUnits{U: 1}},
+			&gowid.ContainerWidget{IWidget: w.right, D: termDim},
+		})
+		w.root = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.body, D: gowid.RenderWithWeight{W: 1}},
+			&gowid.ContainerWidget{IWidget: w.Menu, D: gowid.RenderWithUnits{U: 1}},
+		})
+	default:
+		w.root = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: w.List, D: listDim},
+			&gowid.ContainerWidget{IWidget: w.Menu, D: gowid.RenderWithUnits{U: 1}},
+			&gowid.ContainerWidget{IWidget: &SeparatorWidget{IWidget: w.Status, layout: w}, D: gowid.RenderWithUnits{U: 1}},
+			&gowid.ContainerWidget{IWidget: w.Terminal, D: termDim},
+		})
+	}
+	return w.root
+}
+
+func (w *LayoutWidget) rebuild(app gowid.IApp) {
+	w.Widget.SetSubWidget(w.build(), app)
+	w.applyFocus(app)
+	SaveUiState()
+}
+
+func (w *LayoutWidget) applyFocus(app gowid.IApp) {
+	switch {
+	case w.State.Maximized == MAXIMIZE_LIST:
+		w.root.SetFocus(app, 0)
+	case w.State.Maximized == MAXIMIZE_TERMINAL:
+		w.root.SetFocus(app, 1)
+	case w.State.Layout == LAYOUT_HORIZONTAL:
+		w.root.SetFocus(app, 0)
+		if w.focus == tui.FOCUS_ON_TERMINAL {
+			w.body.SetFocus(app, 2)
+			w.right.SetFocus(app, 1)
+		} else {
+			w.body.SetFocus(app, 0)
+		}
+	default:
+		if w.focus == tui.FOCUS_ON_TERMINAL {
+			w.root.SetFocus(app, 3)
+		} else {
+			w.root.SetFocus(app, 0)
+		}
+	}
+}
+
+// SetFocus moves focus to the list or to the terminal,
+// the maximized pane is restored if the other one gets focus
+func (w *LayoutWidget) SetFocus(app gowid.IApp, i int) {
+	w.focus = i
+	if (i == tui.FOCUS_ON_TERMINAL && w.State.Maximized == MAXIMIZE_LIST) ||
+		(i == tui.FOCUS_ON_LIST && w.State.Maximized == MAXIMIZE_TERMINAL) {
+		w.State.Maximized = MAXIMIZE_NONE
+		w.rebuild(app)
+		return
+	}
+	w.applyFocus(app)
+}
+
+func (w *LayoutWidget) Focus() int {
+	return w.focus
+}
+
+func (w *LayoutWidget) SwitchFocus(app gowid.IApp) {
+	if w.focus == tui.FOCUS_ON_LIST {
+		w.SetFocus(app, tui.FOCUS_ON_TERMINAL)
+	} else {
+		w.SetFocus(app, tui.FOCUS_ON_LIST)
+	}
+}
+
+// Resize grows the list pane by delta percents (shrinks if delta is negative)
+func (w *LayoutWidget) Resize(app gowid.IApp, delta int) {
+	w.SetOffset(app, w.State.Offset+delta)
+}
+
+func (w *LayoutWidget) SetOffset(app gowid.IApp, offset int) {
+	offset = clampOffset(offset)
+	if offset == w.State.Offset && w.State.Maximized == MAXIMIZE_NONE {
+		return
+	}
+	w.State.Offset = offset
+	w.State.Maximized = MAXIMIZE_NONE
+	w.rebuild(app)
+}
+
+func (w *LayoutWidget) ToggleLayout(app gowid.IApp) {
+	if w.State.Layout == LAYOUT_HORIZONTAL {
+		w.State.Layout = LAYOUT_VERTICAL
+	} else {
+		w.State.Layout = LAYOUT_HORIZONTAL
+	}
+	w.State.Maximized = MAXIMIZE_NONE
+	log.Infof("Layout changed to %s", w.State.Layout)
+	w.rebuild(app)
+}
+
+// ToggleMaximize maximizes the focused pane or restores both panes
+func (w *LayoutWidget) ToggleMaximize(app gowid.IApp) {
+	switch {
+	case w.State.Maximized != MAXIMIZE_NONE:
+		w.State.Maximized = MAXIMIZE_NONE
+	case w.focus == tui.FOCUS_ON_TERMINAL:
+		w.State.Maximized = MAXIMIZE_TERMINAL
+	default:
+		w.State.Maximized = MAXIMIZE_LIST
+	}
+	w.rebuild(app)
+}
+
+// Drag moves the separator to the mouse position while the left button
+// pressed on the separator is held, it returns false if no drag is active
+func (w *LayoutWidget) Drag(app gowid.IApp, evm *tcell.EventMouse) bool {
+	if !w.dragging {
+		return false
+	}
+	if evm.Buttons()&tcell.Button1 == 0 {
+		w.dragging = false
+		return true
+	}
+	width, height := app.GetScreen().Size()
+	x, y := evm.Position()
+	if w.State.Layout == LAYOUT_HORIZONTAL {
+		if width > 0 {
+			w.SetOffset(app, x*100/width-50)
+		}
+	} else if height > 0 {
+		w.SetOffset(app, y*100/height-50)
+	}
+	return true
+}
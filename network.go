@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+	_ "github.com/mattn/go-sqlite3"
+
+	"container"
+	"host"
+)
+
+// NetworkEntry is one address of a container from its ip4_addr value
+type NetworkEntry struct {
+	Jname     string
+	Emulator  string
+	Addr      host.IpAddr
+	Interface string
+	Vnet      bool
+	Problems  []string
+}
+
+// GetNetworkEntries reads the addresses of the containers of all types,
+// a container without address gets one empty entry
+func GetNetworkEntries(dbname string) ([]NetworkEntry, error) {
+	entries := make([]NetworkEntry, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return entries, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT jname,emulator,IFNULL(ip4_addr,''),IFNULL(interface,''),IFNULL(vnet,0) FROM jails ORDER BY emulator,jname")
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jname, emulator, ip4addr, iface, vnet string
+		if err = rows.Scan(&jname, &emulator, &ip4addr, &iface, &vnet); err != nil {
+			return entries, err
+		}
+		entry := NetworkEntry{Jname: jname, Emulator: emulator, Interface: iface, Vnet: vnet == "1"}
+		addrs := host.ParseIpAddrs(ip4addr)
+		if len(addrs) == 0 {
+			entries = append(entries, entry)
+			continue
+		}
+		for _, addr := range addrs {
+			entry.Addr = addr
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// CheckNetworkEntries flags the duplicate and invalid addresses, the addresses outside
+// of the pools of the same IP version and the DHCP placeholders, returns the number of conflicts
+func CheckNetworkEntries(entries []NetworkEntry, pool string) int {
+	pools, _ := host.ParseIpPools(pool)
+	has4, has6 := false, false
+	for _, p := range pools {
+		if p.IP.To4() != nil {
+			has4 = true
+		} else {
+			has6 = true
+		}
+	}
+	used := make(map[string]int)
+	for _, e := range entries {
+		if e.Addr.Ip != nil {
+			used[e.Addr.Ip.String()]++
+		}
+	}
+	conflicts := 0
+	for i := range entries {
+		e := &entries[i]
+		switch {
+		case e.Addr.Value == "":
+			continue
+		case e.Addr.Placeholder:
+			e.Problems = append(e.Problems, "DHCP placeholder")
+			continue
+		case e.Addr.Ip == nil:
+			e.Problems = append(e.Problems, "invalid address")
+			conflicts++
+			continue
+		}
+		if used[e.Addr.Ip.String()] > 1 {
+			e.Problems = append(e.Problems, "duplicate")
+			conflicts++
+		}
+		is4 := e.Addr.Ip.To4() != nil
+		if ((is4 && has4) || (!is4 && has6)) && !host.IsInPools(e.Addr.Ip, pools) {
+			e.Problems = append(e.Problems, "outside nodeippool")
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+func GetNetworkLine(e NetworkEntry) string {
+	addr := e.Addr.Value
+	if addr == "" {
+		addr = "-"
+	}
+	iface := e.Interface
+	if e.Addr.Interface != "" {
+		iface = e.Addr.Interface
+	}
+	if iface == "" {
+		iface = "-"
+	}
+	vnet := ""
+	if e.Vnet {
+		vnet = "vnet"
+	}
+	line := fmt.Sprintf("%-20s %-8s %-28s %-10s %-4s", e.Jname, e.Emulator, addr, iface, vnet)
+	if len(e.Problems) > 0 {
+		line += "  ! " + strings.Join(e.Problems, ", ")
+	}
+	return line
+}
+
+// JumpToContainer switches the list to the type of the container and selects it
+func JumpToContainer(emulator string, jname string) {
+	ct, found := container.GetTypeByEmulator(emulator)
+	if !found {
+		return
+	}
+	SwitchContainerType(ct.Name)
+	SelectJail(jname)
+}
+
+// OpenNetworkDialog lists the addresses of all the containers with their problems,
+// the selected line jumps to its container
+func OpenNetworkDialog() {
+	var networkDialog *dialog.Widget
+	dbname := host.GetCbsdDbConnString(false)
+	entries, err := GetNetworkEntries(dbname)
+	if err != nil {
+		mainTui.OpenInfoDialog("Network", []string{"Cannot read containers addresses: " + err.Error()})
+		return
+	}
+	pool, err := host.GetNodeIpPool(dbname)
+	if err != nil {
+		host.LogError("Cannot get nodeippool", err)
+	}
+	conflicts := CheckNetworkEntries(entries, pool)
+	menulines := make([]string, 0)
+	cbfunc := make([]func(jname string), 0)
+	if pool != "" {
+		menulines = append(menulines, "nodeippool: "+pool)
+	} else {
+		menulines = append(menulines, "nodeippool is not set")
+	}
+	cbfunc = append(cbfunc, func(jname string) {})
+	if _, invalid := host.ParseIpPools(pool); len(invalid) > 0 {
+		menulines = append(menulines, "invalid networks in nodeippool: "+strings.Join(invalid, " "))
+		cbfunc = append(cbfunc, func(jname string) {})
+	}
+	for _, e := range entries {
+		e := e
+		menulines = append(menulines, GetNetworkLine(e))
+		cbfunc = append(cbfunc, func(jname string) {
+			networkDialog.Close(app)
+			JumpToContainer(e.Emulator, e.Jname)
+		})
+	}
+	title := "Network: " + strconv.Itoa(len(entries)) + " entries, " + strconv.Itoa(conflicts) + " conflicts"
+	networkDialog = mainTui.MakeActionDialogForJail("", title, menulines, cbfunc)
+	networkDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.9}, app)
+}
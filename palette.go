@@ -0,0 +1,184 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gcla/gowid"
+
+	"host"
+	"tui"
+)
+
+// Containers marked by name, the command palette runs container commands
+// on the marked containers instead of the selected one
+var markedJails = make(map[string]bool)
+
+func ToggleMark(jname string) {
+	if markedJails[jname] {
+		delete(markedJails, jname)
+	} else {
+		markedJails[jname] = true
+	}
+	UpdateJailLine(GetJailByName(jname))
+}
+
+func ClearMarks() {
+	marked := markedJails
+	markedJails = make(map[string]bool)
+	for jname := range marked {
+		if jail := GetJailByName(jname); jail != nil {
+			UpdateJailLine(jail)
+		}
+	}
+}
+
+// PruneMarks removes the marks of the containers not in the list anymore
+func PruneMarks() {
+	for jname := range markedJails {
+		if GetJailByName(jname) == nil {
+			delete(markedJails, jname)
+		}
+	}
+}
+
+// GetTargetJails returns the marked containers in the list order or the selected one
+func GetTargetJails() []Container {
+	targets := make([]Container, 0)
+	for i := range Containers {
+		if markedJails[Containers[i].GetName()] {
+			targets = append(targets, Containers[i])
+		}
+	}
+	if len(targets) == 0 {
+		if curjail := GetSelectedJail(); curjail != nil {
+			targets = append(targets, curjail)
+		}
+	}
+	return targets
+}
+
+func GetActionName(description string) string {
+	name := []rune(strings.TrimPrefix(description, "To "))
+	if len(name) > 0 {
+		name[0] = unicode.ToUpper(name[0])
+	}
+	return string(name)
+}
+
+// HasCliAction tells if the action can be run from the command line on one of the containers
+func HasCliAction(targets []Container, action string) bool {
+	for _, target := range targets {
+		for _, cliaction := range target.GetCliActions() {
+			if cliaction == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetPaletteItems lists the menu actions of the container type first,
+// then the global actions of the key map, the actions of other container types are skipped;
+// for several targets only the actions run from the command line are listed, without dialogs
+func GetPaletteItems() []tui.PaletteItem {
+	items := make([]tui.PaletteItem, 0)
+	targets := GetTargetJails()
+	if len(Containers) > 0 {
+		for _, action := range Containers[0].GetMenuActions() {
+			action := action
+			if len(targets) > 1 && !HasCliAction(targets, action) {
+				continue
+			}
+			items = append(items, tui.PaletteItem{Name: Containers[0].GetActionCaption(action), Keys: keyMap.GetKeyNames(action), Run: func() {
+				targets := GetTargetJails()
+				if len(targets) == 1 {
+					targets[0].ExecuteAction(action)
+					return
+				}
+				RunMarkedAction(targets, action)
+			}})
+		}
+	}
+	for _, ad := range tui.ActionDescriptions {
+		action := ad.Action
+		if action == tui.ACTION_PALETTE || tui.IsContainerAction(action) {
+			continue
+		}
+		items = append(items, tui.PaletteItem{Name: GetActionName(ad.Description), Keys: keyMap.GetKeyNames(action), Run: func() {
+			RunAction(action)
+		}})
+	}
+	return items
+}
+
+func OpenCommandPalette() {
+	title := "Commands"
+	targets := GetTargetJails()
+	if len(markedJails) > 0 {
+		title += " for " + strconv.Itoa(len(targets)) + " marked"
+	} else if len(targets) > 0 {
+		title += " for " + targets[0].GetName()
+	}
+	mainTui.OpenCommandPalette(title, GetPaletteItems())
+}
+
+// GetRunAction returns the action told to the exec observers for the action run on c
+func GetRunAction(c Container, action string) string {
+	if action != tui.ACTION_STARTSTOP {
+		return action
+	}
+	if c.IsRunning() {
+		return tui.RUN_STOP
+	}
+	return tui.RUN_START
+}
+
+// RunMarkedAction runs the action on the targets one by one from the command line
+// in one log dialog, the confirmations of the targets are asked once
+func RunMarkedAction(targets []Container, action string) {
+	title := GetActionName(targets[0].GetActionCaption(action))
+	names := make([]string, 0, len(targets))
+	questions := make([]string, 0)
+	for _, target := range targets {
+		names = append(names, target.GetName())
+		if question := target.GetActionConfirmText(action); question != "" {
+			questions = append(questions, question)
+		}
+	}
+	run := func() {
+		for _, name := range names {
+			tui.NotifyActionObservers(name, action)
+		}
+		logLine := mainTui.OpenLogDialog(title + ": " + strings.Join(names, ", ") + "\n")
+		go func() {
+			defer app.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) { RefreshJailList() }))
+			for _, target := range targets {
+				args := target.GetActionCliArgs(action)
+				if args == nil {
+					logLine(title + " is not available for " + target.GetName() + ", skipped")
+					continue
+				}
+				logLine(title + " " + target.GetName() + "...")
+				runaction, started := GetRunAction(target, action), time.Now()
+				out, err := host.RunCbsd(args...)
+				tui.NotifyExecObservers(target.GetName(), runaction, time.Since(started), err)
+				if out != "" {
+					logLine(out)
+				}
+				if err != nil {
+					host.LogError(title+" "+target.GetName()+" failed", err)
+					logLine(err.Error())
+				}
+			}
+			logLine("Done")
+		}()
+	}
+	if len(questions) == 0 {
+		run()
+		return
+	}
+	mainTui.OpenConfirmDialog("", title+" "+strconv.Itoa(len(targets))+" marked", strings.Join(questions, "\n"), run)
+}
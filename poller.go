@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"host"
+)
+
+// ContainerPoller loads all the containers periodically in background and passes the same
+// snapshot to the state watcher and the health checker, the loaders already ask cbsd
+// for the status of the containers so it is trusted as is
+type ContainerPoller struct {
+	mu          sync.Mutex
+	subscribers []*pollSubscriber
+}
+
+type pollSubscriber struct {
+	interval time.Duration
+	last     time.Time
+	fn       func(all map[string]Container)
+}
+
+func NewContainerPoller() *ContainerPoller {
+	return &ContainerPoller{subscribers: make([]*pollSubscriber, 0)}
+}
+
+// Subscribe calls fn in the poller goroutine with a snapshot at most every interval
+func (cp *ContainerPoller) Subscribe(interval time.Duration, fn func(all map[string]Container)) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.subscribers = append(cp.subscribers, &pollSubscriber{interval: interval, fn: fn})
+}
+
+// GetInterval returns the shortest interval of the subscribers
+func (cp *ContainerPoller) GetInterval() time.Duration {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	var interval time.Duration
+	for _, s := range cp.subscribers {
+		if interval == 0 || s.interval < interval {
+			interval = s.interval
+		}
+	}
+	return interval
+}
+
+// poll loads the containers once if a subscriber is due and passes them to the due ones,
+// nothing is passed if a type of containers failed to load
+func (cp *ContainerPoller) poll() {
+	cp.mu.Lock()
+	due := make([]*pollSubscriber, 0, len(cp.subscribers))
+	now := time.Now()
+	for _, s := range cp.subscribers {
+		// a little slack so that a subscriber is not skipped for a round by the load time
+		if now.Sub(s.last) >= s.interval-time.Second {
+			s.last = now
+			due = append(due, s)
+		}
+	}
+	cp.mu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+	all, errs := GetAllContainers(host.GetCbsdDbConnString(false))
+	if len(errs) > 0 {
+		// the containers of a type which failed to load would look removed,
+		// the round is skipped and the subscribers keep their last snapshot
+		return
+	}
+	for _, s := range due {
+		s.fn(all)
+	}
+}
+
+// Start polls now and then periodically, nothing is done without subscribers
+func (cp *ContainerPoller) Start() {
+	interval := cp.GetInterval()
+	if interval == 0 {
+		return
+	}
+	go func() {
+		for {
+			cp.poll()
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,20 @@
+package qemu
+
+import (
+	"tui"
+	"vm"
+)
+
+// Emulator of QEMU VMs, qemu table holds their settings
+var Emulator = &vm.Emulator{
+	Name:         "qemu",
+	Title:        "QEMU VM",
+	Action:       tui.ACTION_QEMU,
+	CommandStart: "qstart",
+	CommandStop:  "qstop",
+	CommandLogin: "qlogin",
+}
+
+func init() {
+	vm.Register(Emulator)
+}
@@ -0,0 +1,10 @@
+module recorder
+
+go 1.19
+
+require (
+	github.com/creack/pty v1.1.15
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+)
+
+require golang.org/x/sys v0.0.0-20220318055525-2edf467146b5 // indirect
@@ -0,0 +1,332 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// Asciicast v2 format, see https://docs.asciinema.org/manual/asciicast/v2/
+const ASCIICAST_VERSION int = 2
+const EVENT_OUTPUT string = "o"
+const EVENT_INPUT string = "i"
+const FILE_EXTENSION string = ".cast"
+
+// Pauses longer than MAX_IDLE_TIME are shortened on replay
+const MAX_IDLE_TIME float64 = 2.0
+const MIN_SPEED float64 = 0.125
+const MAX_SPEED float64 = 16.0
+
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+type Writer struct {
+	mu    sync.Mutex
+	out   *bufio.Writer
+	start time.Time
+}
+
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	res := &Writer{
+		out:   bufio.NewWriter(w),
+		start: time.Now(),
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	_, err = res.out.Write(append(data, '\n'))
+	if err != nil {
+		return nil, err
+	}
+	return res, res.out.Flush()
+}
+
+func (w *Writer) WriteEvent(etype string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ev, err := json.Marshal([]interface{}{time.Since(w.start).Seconds(), etype, string(data)})
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(append(ev, '\n'))
+	if err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// eventCopy copies src to dst and records the data as events of etype,
+// incomplete UTF-8 sequences are kept until the next read
+func (w *Writer) eventCopy(dst io.Writer, src io.Reader, etype string) error {
+	buf := make([]byte, 32*1024)
+	var pending []byte
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			pending = append(pending, buf[:n]...)
+			valid := validUtf8Prefix(pending)
+			if valid > 0 {
+				if werr := w.WriteEvent(etype, pending[:valid]); werr != nil {
+					return werr
+				}
+				pending = append([]byte{}, pending[valid:]...)
+			}
+		}
+		if err != nil {
+			if len(pending) > 0 {
+				w.WriteEvent(etype, pending)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// validUtf8Prefix returns the length of data without a trailing incomplete rune
+func validUtf8Prefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// Number of the suffixes tried by Create when a recording of the same name exists
+const MAX_NAME_SUFFIX int = 100
+
+// Create creates a new recording file at path, a numbered suffix is added before
+// the extension if a recording of the same name exists already
+func Create(path string) (*os.File, error) {
+	base := strings.TrimSuffix(path, FILE_EXTENSION)
+	for i := 0; ; i++ {
+		name := path
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d%s", base, i, FILE_EXTENSION)
+		}
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil || !errors.Is(err, fs.ErrExist) || i >= MAX_NAME_SUFFIX {
+			return f, err
+		}
+	}
+}
+
+// Record runs the command in a new pseudo-terminal connected to the standard
+// input and output, recording the session to the file f created by Create
+func Record(f *os.File, title string, command []string) error {
+	if len(command) < 1 {
+		return errors.New("No command to record")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	chwinch := make(chan os.Signal, 1)
+	signal.Notify(chwinch, syscall.SIGWINCH)
+	go func() {
+		for range chwinch {
+			pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	chwinch <- syscall.SIGWINCH
+	defer func() { signal.Stop(chwinch); close(chwinch) }()
+
+	rows, cols := 24, 80
+	if ws, err := pty.GetsizeFull(os.Stdin); err == nil {
+		rows, cols = int(ws.Rows), int(ws.Cols)
+	}
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err == nil {
+		defer term.Restore(int(os.Stdin.Fd()), oldState)
+	}
+
+	w, err := NewWriter(f, Header{
+		Version:   ASCIICAST_VERSION,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: time.Now().Unix(),
+		Title:     title,
+		Env:       map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+	})
+	if err != nil {
+		return err
+	}
+	go w.eventCopy(ptmx, os.Stdin, EVENT_INPUT)
+	w.eventCopy(os.Stdout, ptmx, EVENT_OUTPUT)
+	return cmd.Wait()
+}
+
+func ReadHeader(path string) (Header, error) {
+	var header Header
+	f, err := os.Open(path)
+	if err != nil {
+		return header, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return header, err
+	}
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		return header, err
+	}
+	if header.Version != ASCIICAST_VERSION {
+		return header, fmt.Errorf("Unsupported asciicast version %d", header.Version)
+	}
+	return header, nil
+}
+
+// Play replays the output events of the recording path to the standard output,
+// keys '+' and '-' change the speed, 'space' pauses, 'q' stops the replay
+func Play(path string, speed float64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	var header Header
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		return err
+	}
+	if header.Version != ASCIICAST_VERSION {
+		return fmt.Errorf("Unsupported asciicast version %d", header.Version)
+	}
+
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err == nil {
+		defer term.Restore(int(os.Stdin.Fd()), oldState)
+	}
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			if n > 0 {
+				keys <- buf[0]
+			}
+		}
+	}()
+
+	paused := false
+	// handleKey returns false if the replay must be stopped
+	handleKey := func(k byte) bool {
+		switch k {
+		case 'q', 'Q', 0x03:
+			return false
+		case '+':
+			if speed < MAX_SPEED {
+				speed *= 2
+			}
+		case '-':
+			if speed > MIN_SPEED {
+				speed /= 2
+			}
+		case ' ':
+			paused = !paused
+		}
+		return true
+	}
+
+	last := 0.0
+	for {
+		line, err = reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var ev []interface{}
+			if jerr := json.Unmarshal(line, &ev); jerr == nil && len(ev) == 3 {
+				etime, _ := ev[0].(float64)
+				etype, _ := ev[1].(string)
+				edata, _ := ev[2].(string)
+				delay := etime - last
+				if delay > MAX_IDLE_TIME {
+					delay = MAX_IDLE_TIME
+				}
+				last = etime
+				// the remaining delay is recalculated when speed changes
+				remaining := time.Duration(delay * float64(time.Second))
+				for remaining > 0 || paused {
+					step := time.Duration(float64(remaining) / speed)
+					if paused {
+						step = time.Hour
+					}
+					started := time.Now()
+					select {
+					case k, ok := <-keys:
+						if !ok {
+							// no input, keep replaying with the current speed
+							keys = nil
+							paused = false
+							continue
+						}
+						if !handleKey(k) {
+							return nil
+						}
+						if !paused {
+							remaining -= time.Duration(float64(time.Since(started)) * speed)
+						}
+					case <-time.After(step):
+						remaining = 0
+					}
+				}
+				if etype == EVENT_OUTPUT {
+					os.Stdout.Write([]byte(edata))
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if keys == nil {
+		return nil
+	}
+	os.Stdout.Write([]byte("\r\n[End of recording, press 'q' to close]\r\n"))
+	for k := range keys {
+		if !handleKey(k) {
+			break
+		}
+	}
+	return nil
+}
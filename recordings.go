@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+
+	"host"
+	"recorder"
+	"tui"
+)
+
+const CMD_RECORD string = "record"
+const CMD_PLAY string = "play"
+
+// GetRecordDir returns the directory of the session records of the current user,
+// a subdirectory of the configured one so that the operators do not see each other's
+func GetRecordDir() string {
+	name := strconv.Itoa(os.Getuid())
+	if curuser, err := user.Current(); err == nil {
+		name = curuser.Username
+	}
+	return filepath.Join(host.Cfg.RecordDir, name)
+}
+
+// MakeRecordDir creates the directory of the session records of the current user,
+// the configured directory is created writable by all with the sticky bit
+func MakeRecordDir() (string, error) {
+	if _, err := os.Stat(host.Cfg.RecordDir); errors.Is(err, fs.ErrNotExist) {
+		if err = os.MkdirAll(host.Cfg.RecordDir, 0755); err != nil {
+			return "", err
+		}
+		if err = os.Chmod(host.Cfg.RecordDir, fs.ModeSticky|0777); err != nil {
+			return "", err
+		}
+	}
+	dir := GetRecordDir()
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	return dir, nil
+}
+
+// GetRecordedCommand wraps the login command, the program and its arguments, to record
+// the session of jname if the recording is enabled in the configuration; the command is
+// returned unwrapped with the error if the session cannot be recorded
+func GetRecordedCommand(jname string, command []string) ([]string, error) {
+	if host.Cfg.RecordDir == "" {
+		return command, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		host.LogError("Cannot find executable to record session", err)
+		return command, err
+	}
+	dir, err := MakeRecordDir()
+	if err != nil {
+		host.LogError("Cannot create directory for session records", err)
+		return command, err
+	}
+	file := filepath.Join(dir, jname+"-"+time.Now().Format("20060102-150405")+recorder.FILE_EXTENSION)
+	return append([]string{exe, CMD_RECORD, file, jname}, command...), nil
+}
+
+// RunRecorded records the session of the command to file, the command is run unrecorded
+// after a warning if the file cannot be created so that the login is not blocked
+func RunRecorded(file string, title string, command []string) error {
+	f, err := recorder.Create(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the session is not recorded: %v\r\n", err)
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+	defer f.Close()
+	return recorder.Record(f, title, command)
+}
+
+// GetRecordings returns the session records of the current user, and the ones recorded
+// in the configured directory itself before the records were kept by user, newest first
+func GetRecordings() []string {
+	files := make([]string, 0)
+	for _, dir := range []string{GetRecordDir(), host.Cfg.RecordDir} {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+recorder.FILE_EXTENSION))
+		if err == nil {
+			files = append(files, matches...)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return filepath.Base(files[i]) > filepath.Base(files[j]) })
+	return files
+}
+
+func OpenRecordingsDialog() {
+	var RecordingsDialog *dialog.Widget
+	if host.Cfg.RecordDir == "" {
+		RecordingsDialog = mainTui.MakeDialogForJail(
+			"",
+			"Session records",
+			[]string{"Session recording is disabled, set 'record_dir' in " + host.CONFIG_FILE_NAME},
+			nil, nil, nil, nil,
+			nil,
+		)
+		RecordingsDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
+		return
+	}
+	MakePlayFunction := func(file string) func(jname string) {
+		return func(jname string) {
+			RecordingsDialog.Close(app)
+			OpenPlayDialog(file)
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	for _, file := range GetRecordings() {
+		line := filepath.Base(file)
+		if header, err := recorder.ReadHeader(file); err == nil {
+			line += fmt.Sprintf(" (%dx%d)", header.Width, header.Height)
+		}
+		menulines = append(menulines, line)
+		cbfunc = append(cbfunc, MakePlayFunction(file))
+	}
+	RecordingsDialog = mainTui.MakeActionDialogForJail("", "Session records in "+host.Cfg.RecordDir, menulines, cbfunc)
+	RecordingsDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
+}
+
+func OpenPlayDialog(file string) {
+	var PlayDialog *dialog.Widget
+	PlayDialog = mainTui.MakeValidatedDialogForJail(
+		"",
+		"Replay "+filepath.Base(file),
+		nil, nil, nil,
+		[]tui.StrField{{Caption: "Speed: ", Default: "1.0", Validate: tui.ValidateFloatRange(recorder.MIN_SPEED, recorder.MAX_SPEED)}},
+		func(jname string, boolparams []bool, strparams []string) {
+			PlayDialog.Close(app)
+			speed, _ := strconv.ParseFloat(strings.TrimSpace(strparams[0]), 64)
+			exe, err := os.Executable()
+			if err != nil {
+				host.LogError("Cannot find executable to replay session", err)
+				return
+			}
+			command := []string{exe, CMD_PLAY, file, strconv.FormatFloat(speed, 'g', -1, 64)}
+			mainTui.AttachConsole("rec:"+filepath.Base(file), command, tui.CONSOLE_PLAYER,
+				"Replay of "+filepath.Base(file)+": '+'/'-' speed, 'Space' pause, 'q' close")
+		},
+	)
+	PlayDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.3}, app)
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"container"
+	"host"
+	"tui"
+)
+
+const CMD_SERVE string = "serve"
+const API_LISTEN_DEFAULT string = "127.0.0.1:8089"
+const API_TOKEN_ENV string = "CBSD_TUI_API_TOKEN"
+const API_PREFIX string = "/api/"
+
+// Actions of the API besides the command line actions of the container types
+const (
+	API_ACTION_START    string = tui.RUN_START
+	API_ACTION_STOP     string = tui.RUN_STOP
+	API_ACTION_SNAPSHOT string = "snapshot"
+	API_ACTION_CLONE    string = "clone"
+	API_ACTION_DESTROY  string = "destroy"
+)
+
+type ContainerInfo struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Running   bool              `json:"running"`
+	Status    int               `json:"status"`
+	Autostart bool              `json:"autostart"`
+	Params    map[string]string `json:"params"`  // list columns by header title
+	Actions   []string          `json:"actions"` // command line actions available now
+	Tags      []string          `json:"tags"`
+}
+
+type SnapshotInfo struct {
+	Name    string `json:"name"`
+	Created string `json:"created"`
+}
+
+type ContainerDetails struct {
+	ContainerInfo
+	Snapshots []SnapshotInfo `json:"snapshots,omitempty"`
+	Depends   []string       `json:"depends,omitempty"`
+}
+
+// ActionRequest is the optional JSON body of the action requests
+type ActionRequest struct {
+	Name     string `json:"name"`     // snapshot name or name of the clone
+	Hostname string `json:"hostname"` // host name of the clone, the clone name by default
+	Ip       string `json:"ip"`       // address of the clone, the suggested free address by default
+	Confirm  bool   `json:"confirm"`  // needed for the actions asking for confirmation in the TUI
+}
+
+type ApiError struct {
+	Status  int
+	Message string
+}
+
+func (e *ApiError) Error() string {
+	return e.Message
+}
+
+func NewApiError(status int, format string, a ...any) *ApiError {
+	return &ApiError{Status: status, Message: fmt.Sprintf(format, a...)}
+}
+
+func GetApiToken() string {
+	if token := os.Getenv(API_TOKEN_ENV); token != "" {
+		return token
+	}
+	return host.Cfg.ApiToken
+}
+
+// RunServer serves the HTTP/JSON API until it fails:
+//
+//	cbsd-tui serve [--listen address]
+func RunServer(args []string) error {
+	flags := flag.NewFlagSet(CMD_SERVE, flag.ContinueOnError)
+	listen := flags.String("listen", API_LISTEN_DEFAULT, "address to listen on")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	token := GetApiToken()
+	if token == "" {
+		return fmt.Errorf("set api_token in %s or %s environment variable to use the API", host.CONFIG_FILE_NAME, API_TOKEN_ENV)
+	}
+	if _, err := host.NeedDoAs(); err != nil {
+		return err
+	}
+	host.AddJobObserver(func(job host.JobInfo) {
+		if job.Action == API_ACTION_DESTROY && job.Error == "" {
+			PruneContainerState()
+		}
+	})
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           RequireToken(token, NewApiHandler()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the API on http://%s%s\n", *listen, API_PREFIX)
+	return server.ListenAndServe()
+}
+
+// RequireToken passes the requests with the bearer token, the events of a job accept
+// the token query parameter too as browsers cannot set headers of SSE requests
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var given string
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			given = strings.TrimPrefix(auth, "Bearer ")
+		} else if IsJobEventsRequest(r) {
+			given = r.URL.Query().Get("token")
+		}
+		if given == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			WriteJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsJobEventsRequest tells if the request is GET /api/jobs/<id>/events
+func IsJobEventsRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, API_PREFIX+"jobs/") {
+		return false
+	}
+	parts := GetPathParts(r.URL.Path, API_PREFIX+"jobs/")
+	return len(parts) == 2 && parts[1] == "events"
+}
+
+func NewApiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(API_PREFIX+"containers", HandleContainers)
+	mux.HandleFunc(API_PREFIX+"containers/", HandleContainer)
+	mux.HandleFunc(API_PREFIX+"jobs", HandleJobs)
+	mux.HandleFunc(API_PREFIX+"jobs/", HandleJob)
+	return mux
+}
+
+func WriteJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		host.LogError("Cannot write API response", err)
+	}
+}
+
+func WriteError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if apierr, ok := err.(*ApiError); ok {
+		status = apierr.Status
+	}
+	WriteJson(w, status, map[string]string{"error": err.Error()})
+}
+
+// GetPathParts splits the path after the prefix
+func GetPathParts(path string, prefix string) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+}
+
+func GetContainerInfo(c Container, tags map[string][]string) ContainerInfo {
+	info := ContainerInfo{
+		Name:      c.GetName(),
+		Type:      c.GetType(),
+		Running:   c.IsRunning(),
+		Status:    c.GetStatus(),
+		Autostart: c.GetAstart() == 1,
+		Params:    make(map[string]string),
+		Actions:   c.GetCliActions(),
+		Tags:      tags[c.GetName()],
+	}
+	titles := c.GetHeaderTitles()
+	for i, param := range c.GetAllParams() {
+		if i+1 < len(titles) {
+			info.Params[titles[i+1]] = param
+		}
+	}
+	return info
+}
+
+func LoadApiTags() map[string][]string {
+	tags, err := host.GetTags()
+	if err != nil {
+		host.LogError("Cannot read tags from "+host.STATE_DB_NAME, err)
+	}
+	return tags
+}
+
+// HandleContainers lists the containers of all the types:
+//
+//	GET /api/containers
+func HandleContainers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, NewApiError(http.StatusMethodNotAllowed, "use GET"))
+		return
+	}
+	tags := LoadApiTags()
+	res := make([]ContainerInfo, 0)
+	for _, ct := range container.GetTypes() {
+		containers, err := ct.Load(host.GetCbsdDbConnString(false))
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		for _, c := range containers {
+			res = append(res, GetContainerInfo(c, tags))
+		}
+	}
+	WriteJson(w, http.StatusOK, res)
+}
+
+// HandleContainer shows a container or runs an action on it:
+//
+//	GET /api/containers/<name>
+//	POST /api/containers/<name>/actions/<action>
+func HandleContainer(w http.ResponseWriter, r *http.Request) {
+	parts := GetPathParts(r.URL.Path, API_PREFIX+"containers/")
+	all, errs := GetAllContainers(host.GetCbsdDbConnString(false))
+	c, found := all[parts[0]]
+	if !found && len(errs) > 0 {
+		WriteError(w, NewApiError(http.StatusServiceUnavailable, "cannot load the containers: %s", errs[0]))
+		return
+	}
+	if !found {
+		WriteError(w, NewApiError(http.StatusNotFound, "no jail or VM '%s'", parts[0]))
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		WriteJson(w, http.StatusOK, GetContainerDetails(c))
+	case len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodPost:
+		var req ActionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				WriteError(w, NewApiError(http.StatusBadRequest, "invalid JSON body: %s", err))
+				return
+			}
+		}
+		args, err := GetApiActionArgs(c, parts[2], req)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		// the job and its statistics tell whether startstop started or stopped the container
+		job, started := host.StartJob(c.GetName(), GetRunAction(c, parts[2]), args)
+		if !started {
+			WriteError(w, NewApiError(http.StatusConflict, "job %s (%s) of %s is still running", job.Id, job.Action, c.GetName()))
+			return
+		}
+		WriteJson(w, http.StatusAccepted, job.GetInfo(false))
+	default:
+		WriteError(w, NewApiError(http.StatusNotFound, "unknown request %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func GetContainerDetails(c Container) ContainerDetails {
+	details := ContainerDetails{ContainerInfo: GetContainerInfo(c, LoadApiTags())}
+	if snapshotter, ok := c.(container.Snapshotter); ok {
+		for _, snap := range snapshotter.GetSnapshots() {
+			details.Snapshots = append(details.Snapshots, SnapshotInfo{Name: snap[0], Created: snap[1]})
+		}
+	}
+	if depends, err := host.LoadDepends(); err == nil {
+		details.Depends = depends[c.GetName()]
+	}
+	return details
+}
+
+// GetApiActionArgs returns the cbsd arguments of the action on the container
+func GetApiActionArgs(c Container, action string, req ActionRequest) ([]string, error) {
+	var args []string
+	confirm := c.GetActionConfirmText(action)
+	switch action {
+	case API_ACTION_START, API_ACTION_STOP:
+		if action == API_ACTION_START && c.IsRunning() {
+			return nil, NewApiError(http.StatusConflict, "%s is already running", c.GetName())
+		}
+		if action == API_ACTION_STOP && !c.IsRunning() {
+			return nil, NewApiError(http.StatusConflict, "%s is already stopped", c.GetName())
+		}
+		args = c.GetActionCliArgs(tui.ACTION_STARTSTOP)
+		confirm = c.GetActionConfirmText(tui.ACTION_STARTSTOP)
+	case API_ACTION_SNAPSHOT:
+		snapshotter, ok := c.(container.Snapshotter)
+		if !ok {
+			return nil, NewApiError(http.StatusBadRequest, "%s has no snapshots", c.GetName())
+		}
+		if req.Name == "" {
+			req.Name = "gettimeofday"
+		}
+		if err := host.ValidateSnapshotName(req.Name); err != nil {
+			return nil, NewApiError(http.StatusBadRequest, "snapshot name: %s", err)
+		}
+		args = snapshotter.GetSnapshotCliArgs(req.Name)
+	case API_ACTION_CLONE:
+		cloner, ok := c.(container.Cloner)
+		if !ok {
+			return nil, NewApiError(http.StatusBadRequest, "%s cannot be cloned", c.GetName())
+		}
+		if err := ValidateCloneRequest(&req); err != nil {
+			return nil, err
+		}
+		PruneContainerState()
+		args = cloner.GetCloneCliArgs(req.Name, req.Hostname, req.Ip)
+	default:
+		args = c.GetActionCliArgs(action)
+	}
+	if args == nil {
+		return nil, NewApiError(http.StatusNotFound, "action '%s' is not available for %s", action, c.GetName())
+	}
+	if confirm != "" && !req.Confirm {
+		return nil, NewApiError(http.StatusPreconditionRequired, "%s, send {\"confirm\": true} to confirm", confirm)
+	}
+	return args, nil
+}
+
+// ValidateCloneRequest checks the clone parameters as the clone dialogs do and sets the defaults
+func ValidateCloneRequest(req *ActionRequest) error {
+	names, err := host.GetContainerNames(host.GetCbsdDbConnString(false))
+	if err != nil {
+		return err
+	}
+	if err = tui.ValidateAll(tui.ValidateName, tui.ValidateUnique(names))(req.Name); err != nil {
+		return NewApiError(http.StatusBadRequest, "name: %s", err)
+	}
+	if req.Hostname == "" {
+		req.Hostname = req.Name
+	}
+	if err = tui.ValidateHostname(req.Hostname); err != nil {
+		return NewApiError(http.StatusBadRequest, "hostname: %s", err)
+	}
+	if req.Ip == "" {
+		req.Ip, _ = host.GetIpSuggestion()
+	}
+	if err = host.ValidateIpAddrs(req.Ip); err != nil {
+		return NewApiError(http.StatusBadRequest, "ip: %s", err)
+	}
+	return nil
+}
+
+// HandleJobs lists the jobs from the newest:
+//
+//	GET /api/jobs
+func HandleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, NewApiError(http.StatusMethodNotAllowed, "use GET"))
+		return
+	}
+	res := make([]host.JobInfo, 0)
+	for _, job := range host.GetJobs() {
+		res = append(res, job.GetInfo(false))
+	}
+	WriteJson(w, http.StatusOK, res)
+}
+
+// HandleJob shows a job with its output or streams the output with server-sent events,
+// each line is a message and the "done" event with the job state ends the stream:
+//
+//	GET /api/jobs/<id>
+//	GET /api/jobs/<id>/events
+func HandleJob(w http.ResponseWriter, r *http.Request) {
+	parts := GetPathParts(r.URL.Path, API_PREFIX+"jobs/")
+	job, found := host.GetJob(parts[0])
+	switch {
+	case r.Method != http.MethodGet:
+		WriteError(w, NewApiError(http.StatusMethodNotAllowed, "use GET"))
+	case !found:
+		WriteError(w, NewApiError(http.StatusNotFound, "no job '%s'", parts[0]))
+	case len(parts) == 1:
+		WriteJson(w, http.StatusOK, job.GetInfo(true))
+	case len(parts) == 2 && parts[1] == "events":
+		StreamJob(w, r, job)
+	default:
+		WriteError(w, NewApiError(http.StatusNotFound, "unknown request %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func StreamJob(w http.ResponseWriter, r *http.Request, job *host.Job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, NewApiError(http.StatusInternalServerError, "streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	sent := 0
+	for {
+		lines, done, updated := job.Follow(sent)
+		for _, line := range lines {
+			fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+		sent += len(lines)
+		if done {
+			data, _ := json.Marshal(job.GetInfo(false))
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
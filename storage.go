@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+
+	"container"
+	"host"
+	"tui"
+)
+
+const ZFS_COMPRESSION string = "lz4"
+
+// Number of the datasets listed in the confirmation of a clone destroy
+const DESTROY_LIST_MAX int = 20
+
+// IsUnusedClone reports if the dataset is under the cbsd jails-data dataset
+// and belongs to none of the containers
+func IsUnusedClone(dataset string, jailsdata string, names []string) bool {
+	if !strings.HasPrefix(dataset, jailsdata+"/") {
+		return false
+	}
+	for _, name := range names {
+		if host.IsContainerDataset(name, dataset) {
+			return false
+		}
+	}
+	return true
+}
+
+func GetDatasetLine(ds host.Dataset) string {
+	return fmt.Sprintf("%s  used %s  refer %s  quota %s  resv %s  ratio %s",
+		ds.Name, host.FormatSize(ds.Used), host.FormatSize(ds.Referenced),
+		host.FormatZfsSize(ds.Quota, "none"), host.FormatZfsSize(ds.Reservation, "none"), ds.CompressRatio)
+}
+
+// OpenStorageDialog shows the ZFS datasets of the container with their snapshots,
+// the clones of the snapshots not used by any container and the disk images of VMs
+func OpenStorageDialog(c Container) {
+	var storageDialog *dialog.Widget
+	title := "Storage of " + c.GetName()
+	datasets, err := host.GetDatasets()
+	if err != nil {
+		mainTui.OpenInfoDialog(title, []string{"Cannot list ZFS datasets: " + err.Error()})
+		return
+	}
+	names, namesErr := host.GetContainerNames(host.GetCbsdDbConnString(false))
+	if namesErr != nil {
+		host.LogError("Cannot get containers names", namesErr)
+	}
+	// the unused clones are offered only when known to be cbsd ones
+	jailsdata, jailsdataErr := host.GetJailsDataDataset()
+	if jailsdataErr != nil {
+		host.LogError("Cannot find the dataset of "+host.CBSD_JAILS_DATA_DIR, jailsdataErr)
+	}
+	menulines := make([]string, 0)
+	cbfunc := make([]func(jname string), 0)
+	AddLine := func(line string, f func()) {
+		menulines = append(menulines, line)
+		cbfunc = append(cbfunc, func(jname string) {
+			if f != nil {
+				storageDialog.Close(app)
+				f()
+			}
+		})
+	}
+	for _, ds := range host.GetContainerDatasets(c.GetName(), datasets) {
+		ds := ds
+		AddLine(GetDatasetLine(ds), func() { OpenDatasetDialog(ds) })
+		snaps, err := host.GetSnapshots(ds.Name)
+		if err != nil {
+			host.LogError("Cannot list snapshots of "+ds.Name, err)
+		}
+		for _, snap := range snaps {
+			AddLine(fmt.Sprintf("  @%s  used %s  refer %s", strings.SplitN(snap.Name, "@", 2)[1],
+				host.FormatSize(snap.Used), host.FormatSize(snap.Referenced)), nil)
+			for _, clone := range snap.Clones {
+				clone := clone
+				if namesErr == nil && jailsdataErr == nil && IsUnusedClone(clone, jailsdata, names) {
+					AddLine("    unused clone "+clone, func() { OpenDestroyCloneDialog(clone) })
+				}
+			}
+		}
+	}
+	if dl, ok := c.(container.DiskLister); ok {
+		for _, image := range dl.GetDiskImages() {
+			AddLine("disk: "+image, nil)
+		}
+	}
+	if len(menulines) == 0 {
+		mainTui.OpenInfoDialog(title, []string{"No ZFS datasets found for " + c.GetName()})
+		return
+	}
+	storageDialog = mainTui.MakeActionDialogForJail(c.GetName(), title, menulines, cbfunc)
+	storageDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.8}, app)
+}
+
+func SetDatasetProperty(dataset string, property string, value string) {
+	command, args := host.GetZfsCommand("set", property+"="+value, dataset)
+	mainTui.ExecCommand("Setting "+property+" of "+dataset+"...\n", command, args)
+}
+
+// OpenDatasetDialog shows the actions on the dataset
+func OpenDatasetDialog(ds host.Dataset) {
+	var datasetDialog *dialog.Widget
+	menulines := []string{"Set quota...", "Set reservation..."}
+	cbfunc := []func(jname string){
+		func(jname string) {
+			datasetDialog.Close(app)
+			OpenDatasetSizeDialog(ds.Name, "quota", host.FormatZfsSize(ds.Quota, "none"))
+		},
+		func(jname string) {
+			datasetDialog.Close(app)
+			OpenDatasetSizeDialog(ds.Name, "reservation", host.FormatZfsSize(ds.Reservation, "none"))
+		},
+	}
+	if ds.Compression == "off" {
+		menulines = append(menulines, "Enable compression ("+ZFS_COMPRESSION+")")
+		cbfunc = append(cbfunc, func(jname string) {
+			datasetDialog.Close(app)
+			SetDatasetProperty(ds.Name, "compression", ZFS_COMPRESSION)
+		})
+	}
+	datasetDialog = mainTui.MakeActionDialogForJail("", ds.Name, menulines, cbfunc)
+	datasetDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.4}, app)
+}
+
+func OpenDatasetSizeDialog(dataset string, property string, value string) {
+	var sizeDialog *dialog.Widget
+	sizeDialog = mainTui.MakeValidatedDialogForJail(
+		"",
+		"Set "+property+" of "+dataset,
+		nil, nil, nil,
+		[]tui.StrField{{Caption: strings.ToUpper(property[:1]) + property[1:] + " (size or none): ", Default: value, Validate: host.ValidateZfsSize}},
+		func(jname string, boolparams []bool, strparams []string) {
+			sizeDialog.Close(app)
+			SetDatasetProperty(dataset, property, strings.TrimSpace(strparams[0]))
+		},
+	)
+	sizeDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.4}, app)
+}
+
+// OpenDestroyCloneDialog lists what the recursive destroy of the clone removes
+// before asking for the confirmation
+func OpenDestroyCloneDialog(clone string) {
+	title := "Destroy clone " + clone
+	destroyed, err := host.GetDestroyedDatasets(clone)
+	if err != nil {
+		mainTui.OpenInfoDialog(title, []string{"Cannot destroy " + clone + ": " + err.Error()})
+		return
+	}
+	lines := []string{"Really destroy unused clone " + clone + " with:"}
+	for i, name := range destroyed {
+		if i == DESTROY_LIST_MAX {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(destroyed)-DESTROY_LIST_MAX))
+			break
+		}
+		if name != clone {
+			lines = append(lines, "  "+name)
+		}
+	}
+	if len(lines) == 1 {
+		lines[0] = "Really destroy unused clone " + clone + "??"
+	}
+	mainTui.OpenConfirmDialog("", title, strings.Join(lines, "\n"), func() {
+		command, args := host.GetZfsCommand("destroy", "-r", clone)
+		mainTui.ExecCommand("Destroying "+clone+"...\n", command, args)
+	})
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/button"
+	"github.com/gcla/gowid/widgets/cellmod"
+	"github.com/gcla/gowid/widgets/dialog"
+	"github.com/gcla/gowid/widgets/keypress"
+	"github.com/gcla/gowid/widgets/list"
+	"github.com/gcla/gowid/widgets/text"
+
+	"container"
+	"host"
+	"tui"
+)
+
+const TAGS_TITLE string = "TAGS"
+const TAGS_EDIT string = "Tags..."
+
+// Tags of the containers by name, read from the state database on refresh
+var containerTags = make(map[string][]string)
+
+// ListRow is a row of the list: a line of cbsdListLines or a tag section header if Line is -1
+type ListRow struct {
+	Line  int
+	Tag   string
+	Count int // containers in the section
+}
+
+// TagSection lists the indexes in Containers of the containers with the tag,
+// the section with empty tag holds the containers without tags
+type TagSection struct {
+	Tag   string
+	Jails []int
+}
+
+func init() {
+	tui.RegisterExtraAction(tui.ExtraAction{Id: tui.ACTION_TAGS, Label: TAGS_EDIT, Run: OpenTagsDialog})
+}
+
+func LoadContainerTags() {
+	tags, err := host.GetTags()
+	if err != nil {
+		host.LogError("Cannot read tags from "+host.STATE_DB_NAME, err)
+	}
+	containerTags = tags
+}
+
+func HasTag(jname string, tag string) bool {
+	for _, t := range containerTags[jname] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTagSections groups the containers of the list by tag, a container is listed
+// in the sections of all its tags
+func GetTagSections() []TagSection {
+	sections := make([]TagSection, 0)
+	for _, tag := range GetListTags() {
+		section := TagSection{Tag: tag, Jails: make([]int, 0)}
+		for i := range Containers {
+			if HasTag(Containers[i].GetName(), tag) {
+				section.Jails = append(section.Jails, i)
+			}
+		}
+		sections = append(sections, section)
+	}
+	untagged := TagSection{Jails: make([]int, 0)}
+	for i := range Containers {
+		if len(containerTags[Containers[i].GetName()]) == 0 {
+			untagged.Jails = append(untagged.Jails, i)
+		}
+	}
+	if len(untagged.Jails) > 0 {
+		sections = append(sections, untagged)
+	}
+	return sections
+}
+
+// GetListTags returns the sorted tags of the containers of the list
+func GetListTags() []string {
+	tags := make(map[string][]string)
+	for _, c := range Containers {
+		tags[c.GetName()] = containerTags[c.GetName()]
+	}
+	return host.GetAllTags(tags)
+}
+
+func GetTagCaption(tag string) string {
+	if tag == "" {
+		return "(no tags)"
+	}
+	return tag
+}
+
+func IsTagCollapsed(tag string) bool {
+	for _, t := range uiState.CollapsedTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func ToggleTagSection(tag string) {
+	collapsed := make([]string, 0)
+	for _, t := range uiState.CollapsedTags {
+		if t != tag {
+			collapsed = append(collapsed, t)
+		}
+	}
+	if !IsTagCollapsed(tag) {
+		collapsed = append(collapsed, tag)
+	}
+	uiState.CollapsedTags = collapsed
+	SaveUiState()
+	RebuildJailList()
+	SelectTagSection(tag)
+}
+
+func SelectTagSection(tag string) {
+	for i, row := range cbsdListRows {
+		if row.Line < 0 && row.Tag == tag {
+			cbsdListJails.Walker().SetFocus(list.ListPos(i+1), app)
+			return
+		}
+	}
+}
+
+func ToggleGroupByTag() {
+	uiState.GroupByTag = !uiState.GroupByTag
+	SaveUiState()
+	RebuildJailList()
+}
+
+// MakeTagSection makes the header of the section, Enter or click collapses or expands it,
+// the mark key marks all the containers of the section
+func MakeTagSection(tag string, count int) gowid.IWidget {
+	sign := "[-] "
+	if IsTagCollapsed(tag) {
+		sign = "[+] "
+	}
+	txt := text.New(sign+GetTagCaption(tag)+" ("+strconv.Itoa(count)+")", HALIGN_LEFT)
+	btn := button.New(GetStyledWidget(txt, "white"), button.Options{
+		Decoration: button.BareDecoration,
+	})
+	btn.OnClick(gowid.WidgetCallback{Name: "cbt_" + tag, WidgetChangedFunction: func(app gowid.IApp, w gowid.IWidget) {
+		ToggleTagSection(tag)
+	}})
+	kpbtn := keypress.New(
+		cellmod.Opaque(btn),
+		keypress.Options{
+			Keys: keyMap.GetGowidKeys(append(container.GetTypeActions(), tui.ACTION_LOGIN, tui.ACTION_FOCUS,
+				tui.ACTION_REFRESH, tui.ACTION_MARK)...),
+		},
+	)
+	kpbtn.OnKeyPress(keypress.MakeCallback("kpt_"+tag, func(app gowid.IApp, w gowid.IWidget, k gowid.IKey) {
+		TagSectionCallBack(tag, k)
+	}))
+	return kpbtn
+}
+
+func TagSectionCallBack(tag string, key gowid.IKey) {
+	action, found := keyMap.GetAction(key)
+	if !found {
+		return
+	}
+	switch action {
+	case tui.ACTION_LOGIN:
+		ToggleTagSection(tag)
+	case tui.ACTION_MARK:
+		MarkTag(tag)
+	case tui.ACTION_FOCUS:
+		cbsdWidgets.SetFocus(app, tui.FOCUS_ON_TERMINAL)
+		ReleaseFocus()
+	default:
+		RunAction(action)
+	}
+}
+
+// MarkTag marks the containers of the list with the tag (without tags for empty tag),
+// they are unmarked if all of them are marked already
+func MarkTag(tag string) {
+	names := make([]string, 0)
+	allmarked := true
+	for _, c := range Containers {
+		jname := c.GetName()
+		if (tag == "" && len(containerTags[jname]) == 0) || (tag != "" && HasTag(jname, tag)) {
+			names = append(names, jname)
+			allmarked = allmarked && markedJails[jname]
+		}
+	}
+	for _, jname := range names {
+		if allmarked {
+			delete(markedJails, jname)
+		} else {
+			markedJails[jname] = true
+		}
+		UpdateJailLine(GetJailByName(jname))
+	}
+}
+
+// OpenMarkTagDialog lists the tags of the containers in the list to mark the containers with one
+func OpenMarkTagDialog() {
+	var markTagDialog *dialog.Widget
+	menulines := make([]string, 0)
+	cbfunc := make([]func(jname string), 0)
+	for _, section := range GetTagSections() {
+		tag := section.Tag
+		menulines = append(menulines, GetTagCaption(tag)+" ("+strconv.Itoa(len(section.Jails))+")")
+		cbfunc = append(cbfunc, func(jname string) {
+			markTagDialog.Close(app)
+			MarkTag(tag)
+		})
+	}
+	if len(menulines) == 0 {
+		return
+	}
+	markTagDialog = mainTui.MakeActionDialogForJail("", "Mark jails/VMs with tag", menulines, cbfunc)
+	markTagDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.3}, app)
+}
+
+func OpenTagsDialog(jname string) {
+	var tagsDialog *dialog.Widget
+	var hint []string
+	if known := host.GetAllTags(containerTags); len(known) > 0 {
+		hint = []string{"Known tags: " + strings.Join(known, ", ")}
+	}
+	tagsDialog = mainTui.MakeFormDialog(tui.Form{
+		Title: "Tags of " + jname,
+		Text:  hint,
+		Fields: []tui.FormField{
+			{Name: "tags", Caption: "Tags (separated by commas): ", Default: strings.Join(containerTags[jname], ","), Validate: host.ValidateTags},
+		},
+		Submit: func(values tui.FormValues) {
+			tagsDialog.Close(app)
+			if err := host.SetTags(jname, host.ParseTags(values.String("tags"))); err != nil {
+				host.LogError("Cannot save tags of "+jname, err)
+				mainTui.OpenInfoDialog("Tags of "+jname, []string{"Cannot save tags to " + host.STATE_DB_NAME + ": " + err.Error()})
+				return
+			}
+			LoadContainerTags()
+			RebuildJailList()
+		},
+	})
+	tagsDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
+}
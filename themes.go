@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+	log "github.com/sirupsen/logrus"
+
+	"host"
+	"tui"
+)
+
+const CMD_CHECK_THEME string = "check-theme"
+
+// ErrThemeProblems is returned by CheckTheme for a theme with problems, check-theme exits with 2
+var ErrThemeProblems = errors.New("the theme has problems")
+
+// GetThemeName returns the theme chosen at runtime or the configured one,
+// the theme chosen at runtime is used until the configured theme is changed
+func GetThemeName() string {
+	if uiState.Theme != "" && uiState.ThemeConfig == host.Cfg.Theme {
+		return uiState.Theme
+	}
+	if host.Cfg.Theme != "" {
+		return host.Cfg.Theme
+	}
+	return tui.THEME_DEFAULT
+}
+
+func LoadThemeFromPath(path string) (tui.Theme, error) {
+	if strings.HasSuffix(path, tui.THEME_FILE_EXTENSION) {
+		return tui.LoadTheme(filepath.Dir(path), strings.TrimSuffix(filepath.Base(path), tui.THEME_FILE_EXTENSION))
+	}
+	return tui.LoadTheme(host.GetThemeDir(), path)
+}
+
+func GetThemeProblems(theme tui.Theme) []string {
+	problems := make([]string, 0)
+	missing, errs := tui.ValidateTheme(theme)
+	if len(missing) > 0 {
+		problems = append(problems, "Missing styles: "+strings.Join(missing, ", "))
+	}
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// GetPalette makes the palette of the current theme,
+// the default theme is used if the current one cannot be loaded
+func GetPalette() gowid.Palette {
+	name := GetThemeName()
+	theme, err := tui.LoadTheme(host.GetThemeDir(), name)
+	if err != nil {
+		host.LogError("Cannot load theme "+name, err)
+		theme, _ = tui.LoadTheme("", tui.THEME_DEFAULT)
+	}
+	for _, problem := range GetThemeProblems(theme) {
+		log.Warningf("Theme %s: %s", name, problem)
+	}
+	return tui.MakePalette(theme)
+}
+
+// CheckTheme prints the problems of the theme given by name or file path,
+// it is started as 'cbsd-tui check-theme <name|file>'
+func CheckTheme(path string) error {
+	theme, err := LoadThemeFromPath(path)
+	if err != nil {
+		return err
+	}
+	problems := GetThemeProblems(theme)
+	if len(problems) == 0 {
+		fmt.Printf("Theme %s is valid\n", path)
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	return ErrThemeProblems
+}
+
+func OpenThemesDialog() {
+	var ThemesDialog *dialog.Widget
+	MakeThemeFunction := func(name string) func(jname string) {
+		return func(jname string) {
+			ThemesDialog.Close(app)
+			theme, err := tui.LoadTheme(host.GetThemeDir(), name)
+			if err != nil {
+				OpenThemeInfoDialog("Cannot load theme "+name, []string{err.Error()})
+				return
+			}
+			mainTui.SetTheme(theme)
+			uiState.Theme = name
+			uiState.ThemeConfig = host.Cfg.Theme
+			SaveUiState()
+			log.Infof("Theme changed to %s", name)
+			if problems := GetThemeProblems(theme); len(problems) > 0 {
+				problems = append(problems, "Default theme is used for the styles above")
+				OpenThemeInfoDialog("Theme "+name, problems)
+			}
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	current := GetThemeName()
+	for _, name := range tui.GetThemeNames(host.GetThemeDir()) {
+		line := name
+		if name == current {
+			line += " (current)"
+		}
+		menulines = append(menulines, line)
+		cbfunc = append(cbfunc, MakeThemeFunction(name))
+	}
+	ThemesDialog = mainTui.MakeActionDialogForJail("", "Color themes", menulines, cbfunc)
+	ThemesDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.3}, app)
+}
+
+func OpenThemeInfoDialog(title string, txt []string) {
+	var ThemeInfoDialog *dialog.Widget
+	ThemeInfoDialog = mainTui.MakeDialogForJail(
+		"",
+		title,
+		txt,
+		nil, nil, nil, nil,
+		nil,
+	)
+	ThemeInfoDialog.Open(viewHolder, gowid.RenderWithRatio{R: 0.5}, app)
+}
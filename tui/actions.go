@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+)
+
+// Confirm policies of the actions
+const (
+	CONFIRM_NEVER   = iota
+	CONFIRM_ALWAYS  // ask before running the action
+	CONFIRM_RUNNING // ask only if the container is running
+)
+
+// ActionTarget is the container the registered actions are run on
+type ActionTarget interface {
+	GetName() string
+	IsRunning() bool
+}
+
+// Action is an action of a container type, Id is the action name of the key map
+type Action[T ActionTarget] struct {
+	Id          string
+	Label       string
+	Description string // help text of the key map ("To ..."), only for the actions not known by the key map
+	Bottom      bool   // shown in the bottom menu
+	Menu        bool   // shown in the 'Actions' dialog
+	Confirm     int
+	// Optional functions, Label is used and the action is always available if not set
+	StateLabel  func(c T) string
+	Available   func(c T) bool
+	ConfirmText func(c T) string
+	// CliArgs returns the cbsd arguments running the action from the command line,
+	// the actions without CliArgs are available in the TUI only
+	CliArgs func(c T) []string
+	Run     func(c T)
+}
+
+// ActionRegistry holds the actions of a container type in the order of the 'Actions' dialog
+type ActionRegistry[T ActionTarget] struct {
+	actions []*Action[T]
+	byId    map[string]*Action[T]
+}
+
+func NewActionRegistry[T ActionTarget]() *ActionRegistry[T] {
+	return &ActionRegistry[T]{
+		actions: make([]*Action[T], 0),
+		byId:    make(map[string]*Action[T]),
+	}
+}
+
+// Register adds the actions, the actions unknown to the key map are registered there
+// without keys so the users can bind them
+func (r *ActionRegistry[T]) Register(actions ...*Action[T]) {
+	for _, a := range actions {
+		if a.Id == "" || a.Run == nil {
+			panic(fmt.Sprintf("action '%s' has no id or handler", a.Label))
+		}
+		if _, found := r.byId[a.Id]; found {
+			panic(fmt.Sprintf("action '%s' is already registered", a.Id))
+		}
+		RegisterAction(a.Id, a.Description)
+		r.actions = append(r.actions, a)
+		r.byId[a.Id] = a
+	}
+}
+
+func (r *ActionRegistry[T]) Get(id string) (*Action[T], bool) {
+	a, found := r.byId[id]
+	return a, found
+}
+
+func (r *ActionRegistry[T]) GetBottomMenuActions() []string {
+	ids := make([]string, 0)
+	for _, a := range r.actions {
+		if a.Bottom {
+			ids = append(ids, a.Id)
+		}
+	}
+	return ids
+}
+
+func (r *ActionRegistry[T]) GetMenuActions() []string {
+	ids := make([]string, 0)
+	for _, a := range r.actions {
+		if a.Menu {
+			ids = append(ids, a.Id)
+		}
+	}
+	return ids
+}
+
+// GetCliActions returns the actions of c which can be run from the command line
+func (r *ActionRegistry[T]) GetCliActions(c T) []string {
+	ids := make([]string, 0)
+	for _, a := range r.actions {
+		if a.CliArgs != nil && a.IsAvailable(c) {
+			ids = append(ids, a.Id)
+		}
+	}
+	return ids
+}
+
+func (a *Action[T]) GetLabel(c T) string {
+	if a.StateLabel != nil {
+		return a.StateLabel(c)
+	}
+	return a.Label
+}
+
+func (a *Action[T]) IsAvailable(c T) bool {
+	return a.Available == nil || a.Available(c)
+}
+
+// GetConfirmText returns the question to ask before running the action on c,
+// empty if no confirmation is needed
+func (a *Action[T]) GetConfirmText(c T) string {
+	if a.Confirm == CONFIRM_NEVER || (a.Confirm == CONFIRM_RUNNING && !c.IsRunning()) {
+		return ""
+	}
+	if a.ConfirmText != nil {
+		return a.ConfirmText(c)
+	}
+	return "Really " + strings.ToLower(a.GetLabel(c)) + " " + c.GetName() + "?"
+}
+
+// GetCaption returns the label of the action not depending on the container state
+func (r *ActionRegistry[T]) GetCaption(id string) string {
+	if a, found := r.byId[id]; found {
+		return a.Label
+	}
+	return ""
+}
+
+func (r *ActionRegistry[T]) IsAvailable(c T, id string) bool {
+	a, found := r.byId[id]
+	return found && a.IsAvailable(c)
+}
+
+func (r *ActionRegistry[T]) GetConfirmText(c T, id string) string {
+	if a, found := r.byId[id]; found {
+		return a.GetConfirmText(c)
+	}
+	return ""
+}
+
+// GetCliArgs returns nil if the action is unknown, not available or TUI only
+func (r *ActionRegistry[T]) GetCliArgs(c T, id string) []string {
+	a, found := r.byId[id]
+	if !found || a.CliArgs == nil || !a.IsAvailable(c) {
+		return nil
+	}
+	return a.CliArgs(c)
+}
+
+// Execute runs the action on c asking for confirmation if needed,
+// false is returned if the action is unknown or not available
+func (r *ActionRegistry[T]) Execute(t *Tui, c T, id string) bool {
+	a, found := r.byId[id]
+	if !found || !a.IsAvailable(c) {
+		return false
+	}
+	if question := a.GetConfirmText(c); question != "" {
+		t.OpenConfirmDialog(c.GetName(), a.GetLabel(c)+" "+c.GetName(), question, func() {
+			NotifyActionObservers(c.GetName(), id)
+			a.Run(c)
+		})
+		return true
+	}
+	NotifyActionObservers(c.GetName(), id)
+	a.Run(c)
+	return true
+}
+
+// Actions told to the exec observers besides the action ids: the start/stop action
+// is told as a start or a stop and the snapshots action as the destroy of a snapshot
+const (
+	RUN_START            = "start"
+	RUN_STOP             = "stop"
+	RUN_DESTROY_SNAPSHOT = "destroy-snapshot"
+)
+
+// ActionObserver is told about the actions run on the containers from the TUI
+type ActionObserver func(name string, action string)
+
+var actionObservers = make([]ActionObserver, 0)
+
+func AddActionObserver(o ActionObserver) {
+	actionObservers = append(actionObservers, o)
+}
+
+func NotifyActionObservers(name string, action string) {
+	for _, o := range actionObservers {
+		o(name, action)
+	}
+}
+
+// ExtraAction is added by the application to the 'Actions' dialog of all the container types
+type ExtraAction struct {
+	Id    string
+	Label string
+	Run   func(name string)
+}
+
+var extraActions = make([]ExtraAction, 0)
+
+// RegisterExtraAction adds the action after the menu actions of the container types
+func RegisterExtraAction(a ExtraAction) {
+	extraActions = append(extraActions, a)
+}
+
+// OpenActionDialog shows the available menu actions of c
+func (r *ActionRegistry[T]) OpenActionDialog(t *Tui, c T) {
+	var cbsdActionsDialog *dialog.Widget
+	MakeActionFunc := func(id string) func(jname string) {
+		return func(jname string) {
+			cbsdActionsDialog.Close(t.App)
+			r.Execute(t, c, id)
+		}
+	}
+	menulines := make([]string, 0)
+	actionfuncs := make([]func(jname string), 0)
+	for _, a := range r.actions {
+		if !a.Menu || !a.IsAvailable(c) {
+			continue
+		}
+		menulines = append(menulines, a.GetLabel(c))
+		actionfuncs = append(actionfuncs, MakeActionFunc(a.Id))
+	}
+	for _, a := range extraActions {
+		a := a
+		menulines = append(menulines, a.Label)
+		actionfuncs = append(actionfuncs, func(jname string) {
+			cbsdActionsDialog.Close(t.App)
+			a.Run(c.GetName())
+		})
+	}
+	cbsdActionsDialog = t.MakeActionDialogForJail(c.GetName(), "Actions for "+c.GetName(), menulines, actionfuncs)
+	cbsdActionsDialog.Open(t.ViewHolder, gowid.RenderWithRatio{R: 0.3}, t.App)
+}
@@ -0,0 +1,326 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/boxadapter"
+	"github.com/gcla/gowid/widgets/button"
+	"github.com/gcla/gowid/widgets/checkbox"
+	"github.com/gcla/gowid/widgets/columns"
+	"github.com/gcla/gowid/widgets/dialog"
+	"github.com/gcla/gowid/widgets/divider"
+	"github.com/gcla/gowid/widgets/edit"
+	"github.com/gcla/gowid/widgets/framed"
+	"github.com/gcla/gowid/widgets/hpadding"
+	"github.com/gcla/gowid/widgets/pile"
+	"github.com/gcla/gowid/widgets/radio"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gcla/gowid/widgets/text"
+
+	"editwithscrollbar"
+)
+
+// Types of the form fields
+const (
+	FORM_TEXT      = iota // one line text
+	FORM_PASSWORD         // text shown as '*'
+	FORM_CHECKBOX         // value is "1" or "0"
+	FORM_RADIO            // one of Options, all shown
+	FORM_SELECT           // one of Options, chosen in a list
+	FORM_NUMBER           // integer between Min and Max with -/+ buttons
+	FORM_MULTILINE        // text of Rows lines
+)
+
+const FORM_MULTILINE_ROWS int = 5
+
+// FormField declares one field of a form, Default is "1" or "0" for checkboxes
+type FormField struct {
+	Name     string
+	Caption  string
+	Type     int
+	Default  string
+	Options  []string // FORM_RADIO and FORM_SELECT
+	Min, Max int      // FORM_NUMBER
+	Rows     int      // FORM_MULTILINE, FORM_MULTILINE_ROWS by default
+	Validate Validator
+}
+
+// FormValues are the values of the submitted form by field name,
+// text values are trimmed except the passwords and the multiline texts
+type FormValues map[string]string
+
+func (v FormValues) String(name string) string {
+	return v[name]
+}
+
+func (v FormValues) Bool(name string) bool {
+	return v[name] == "1"
+}
+
+func (v FormValues) Int(name string) int {
+	n, _ := strconv.Atoi(v[name])
+	return n
+}
+
+// Form is a dialog with text lines and fields, the dialog without Submit has only 'Close' button
+type Form struct {
+	Title  string
+	Text   []string
+	Fields []FormField
+	Submit func(values FormValues)
+}
+
+// formInput is the widget of a field
+type formInput struct {
+	field  FormField
+	widget gowid.IWidget
+	value  func() string
+	edit   *edit.Widget // text fields are validated while typing
+	errtxt *text.Widget // nil if the field is not validated
+}
+
+func (in *formInput) validate(app gowid.IApp) bool {
+	if in.errtxt == nil {
+		return true
+	}
+	if err := in.field.Validate(in.value()); err != nil {
+		in.errtxt.SetText("  "+err.Error(), app)
+		return false
+	}
+	in.errtxt.SetText("", app)
+	return true
+}
+
+func (tui *Tui) makeEditInput(f FormField, opts edit.Options) (*formInput, *edit.Widget) {
+	e := edit.New(opts)
+	in := &formInput{field: f, widget: styled.New(e, gowid.MakePaletteRef("green")), edit: e}
+	in.value = func() string {
+		if f.Type == FORM_PASSWORD || f.Type == FORM_MULTILINE {
+			return e.Text()
+		}
+		return strings.TrimSpace(e.Text())
+	}
+	return in, e
+}
+
+func (tui *Tui) makeSelectInput(f FormField) *formInput {
+	value := f.Default
+	if value == "" && len(f.Options) > 0 {
+		value = f.Options[0]
+	}
+	btxt := text.New(f.Caption+"["+value+"]", HALIGN_LEFT)
+	btn := button.New(btxt, button.Options{Decoration: button.BareDecoration})
+	btn.OnClick(gowid.MakeWidgetCallback("select_"+f.Name, gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
+		var selectDialog *dialog.Widget
+		cbfunc := make([]func(jname string), len(f.Options))
+		for i := range f.Options {
+			option := f.Options[i]
+			cbfunc[i] = func(jname string) {
+				selectDialog.Close(app)
+				value = option
+				btxt.SetText(f.Caption+"["+value+"]", app)
+			}
+		}
+		selectDialog = tui.MakeActionDialogForJail("", f.Caption, f.Options, cbfunc)
+		selectDialog.Open(tui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, app)
+	})))
+	return &formInput{
+		field:  f,
+		widget: hpadding.New(styled.New(btn, gowid.MakePaletteRef("green")), gowid.HAlignLeft{}, gowid.RenderFixed{}),
+		value:  func() string { return value },
+	}
+}
+
+func (tui *Tui) makeRadioInput(f FormField) *formInput {
+	group := make([]radio.IWidget, 0)
+	buttons := make([]*radio.Widget, len(f.Options))
+	rows := make([]gowid.IContainerWidget, 0)
+	rows = append(rows, &gowid.ContainerWidget{IWidget: styled.New(text.New(f.Caption, HALIGN_LEFT), gowid.MakePaletteRef("green")), D: gowid.RenderFlow{}})
+	for i, option := range f.Options {
+		buttons[i] = radio.New(&group)
+		if option == f.Default {
+			buttons[i].Select(tui.App)
+		}
+		line := columns.NewFixed(buttons[i], text.New(" "+option))
+		rows = append(rows, &gowid.ContainerWidget{IWidget: hpadding.New(styled.New(line, gowid.MakePaletteRef("green")), gowid.HAlignLeft{}, gowid.RenderFixed{}), D: gowid.RenderFlow{}})
+	}
+	return &formInput{
+		field:  f,
+		widget: pile.New(rows),
+		value: func() string {
+			for i, b := range buttons {
+				if b.IsChecked() {
+					return f.Options[i]
+				}
+			}
+			return ""
+		},
+	}
+}
+
+func (tui *Tui) makeNumberInput(f FormField) *formInput {
+	in, e := tui.makeEditInput(f, edit.Options{Caption: f.Caption, Text: f.Default})
+	step := func(d int) gowid.WidgetChangedFunction {
+		return func(app gowid.IApp, w gowid.IWidget) {
+			n, err := strconv.Atoi(in.value())
+			if err != nil {
+				n = f.Min
+			} else {
+				n += d
+			}
+			if n < f.Min {
+				n = f.Min
+			}
+			if n > f.Max {
+				n = f.Max
+			}
+			e.SetText(strconv.Itoa(n), app)
+		}
+	}
+	minus := button.New(text.New("-"))
+	minus.OnClick(gowid.MakeWidgetCallback("minus_"+f.Name, step(-1)))
+	plus := button.New(text.New("+"))
+	plus.OnClick(gowid.MakeWidgetCallback("plus_"+f.Name, step(1)))
+	in.widget = columns.New([]gowid.IContainerWidget{
+		&gowid.ContainerWidget{IWidget: in.widget, D: gowid.RenderWithUnits{U: len(f.Caption) + len(strconv.Itoa(f.Max)) + 2}},
+		&gowid.ContainerWidget{IWidget: styled.New(minus, gowid.MakePaletteRef("green")), D: gowid.RenderFixed{}},
+		&gowid.ContainerWidget{IWidget: styled.New(plus, gowid.MakePaletteRef("green")), D: gowid.RenderFixed{}},
+	})
+	return in
+}
+
+func (tui *Tui) makeFormInput(f FormField) *formInput {
+	switch f.Type {
+	case FORM_PASSWORD:
+		in, _ := tui.makeEditInput(f, edit.Options{Caption: f.Caption, Text: f.Default, Mask: edit.MakeMask('*')})
+		return in
+	case FORM_CHECKBOX:
+		cb := checkbox.New(f.Default == "1")
+		caption := styled.New(text.New(f.Caption, HALIGN_LEFT), gowid.MakePaletteRef("green"))
+		return &formInput{
+			field:  f,
+			widget: hpadding.New(columns.NewFixed(caption, cb), gowid.HAlignLeft{}, gowid.RenderFixed{}),
+			value: func() string {
+				if cb.IsChecked() {
+					return "1"
+				}
+				return "0"
+			},
+		}
+	case FORM_RADIO:
+		return tui.makeRadioInput(f)
+	case FORM_SELECT:
+		return tui.makeSelectInput(f)
+	case FORM_NUMBER:
+		return tui.makeNumberInput(f)
+	case FORM_MULTILINE:
+		rows := f.Rows
+		if rows < 1 {
+			rows = FORM_MULTILINE_ROWS
+		}
+		in, e := tui.makeEditInput(f, edit.Options{Text: f.Default})
+		in.widget = pile.New([]gowid.IContainerWidget{
+			&gowid.ContainerWidget{IWidget: styled.New(text.New(f.Caption, HALIGN_LEFT), gowid.MakePaletteRef("green")), D: gowid.RenderFlow{}},
+			&gowid.ContainerWidget{IWidget: boxadapter.New(styled.New(editwithscrollbar.NewEditWithScrollbar(e), gowid.MakePaletteRef("white")), rows), D: gowid.RenderFlow{}},
+		})
+		return in
+	default:
+		in, _ := tui.makeEditInput(f, edit.Options{Caption: f.Caption, Text: f.Default})
+		return in
+	}
+}
+
+// GetValidator returns the validator of the field, the range of numbers is always checked
+func (f FormField) GetValidator() Validator {
+	if f.Type == FORM_NUMBER {
+		if f.Validate != nil {
+			return ValidateAll(ValidateIntRange(f.Min, f.Max), f.Validate)
+		}
+		return ValidateIntRange(f.Min, f.Max)
+	}
+	return f.Validate
+}
+
+// MakeFormDialog makes the dialog of the form, the errors of the validated fields are shown
+// under them and 'OK' does nothing until all the fields are valid
+func (tui *Tui) MakeFormDialog(form Form) *dialog.Widget {
+	var retdialog *dialog.Widget
+	containers := make([]gowid.IContainerWidget, 0)
+	containers = append(containers, &gowid.ContainerWidget{IWidget: styled.New(text.New(form.Title, HALIGN_MIDDLE), gowid.MakePaletteRef("magenta")), D: gowid.RenderFlow{}})
+	containers = append(containers, &gowid.ContainerWidget{IWidget: divider.NewUnicode(), D: gowid.RenderFlow{}})
+	for _, t := range form.Text {
+		containers = append(containers, &gowid.ContainerWidget{IWidget: styled.New(text.New(t, HALIGN_LEFT), gowid.MakePaletteRef("green")), D: gowid.RenderFlow{}})
+	}
+
+	inputs := make([]*formInput, 0)
+	for _, f := range form.Fields {
+		f.Validate = f.GetValidator()
+		in := tui.makeFormInput(f)
+		inputs = append(inputs, in)
+		containers = append(containers, &gowid.ContainerWidget{IWidget: in.widget, D: gowid.RenderFlow{}})
+		if f.Validate != nil {
+			in.errtxt = text.New("", HALIGN_LEFT)
+			containers = append(containers, &gowid.ContainerWidget{IWidget: styled.New(in.errtxt, gowid.MakePaletteRef("error")), D: gowid.RenderFlow{}})
+		}
+	}
+	for _, in := range inputs {
+		in := in
+		if in.edit != nil && in.errtxt != nil {
+			in.edit.OnTextSet(gowid.MakeWidgetCallback("validate_"+in.field.Name, gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
+				in.validate(app)
+			})))
+		}
+	}
+
+	btncancel := dialog.Button{
+		Msg: "Cancel",
+		Action: gowid.MakeWidgetCallback("execsetfocus", gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
+			tui.SetFocus(FOCUS_ON_LIST)
+			retdialog.Close(tui.App)
+		})),
+	}
+	buttons := make([]dialog.Button, 0)
+	if form.Submit == nil {
+		btncancel.Msg = "Close"
+	} else {
+		buttons = append(buttons, dialog.Button{
+			Msg: "OK",
+			Action: gowid.MakeWidgetCallback("execokfunc", gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
+				valid := true
+				for _, in := range inputs {
+					if !in.validate(app) {
+						valid = false
+					}
+				}
+				if !valid {
+					return
+				}
+				values := make(FormValues)
+				for _, in := range inputs {
+					values[in.field.Name] = in.value()
+				}
+				tui.SetFocus(FOCUS_ON_LIST)
+				form.Submit(values)
+			})),
+		})
+	}
+	buttons = append(buttons, btncancel)
+
+	retdialog = dialog.New(
+		framed.NewSpace(
+			pile.New(containers),
+		),
+		dialog.Options{
+			Buttons:         buttons,
+			NoShadow:        true,
+			BackgroundStyle: gowid.MakePaletteRef("bluebg"),
+			BorderStyle:     gowid.MakePaletteRef("dialog"),
+			ButtonStyle:     gowid.MakePaletteRef("white-focus"),
+			Modal:           true,
+			FocusOnWidget:   true,
+		},
+	)
+	return retdialog
+}
@@ -0,0 +1,495 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gcla/gowid"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Named actions bound to key chords by KeyMap
+const (
+	ACTION_HELP       = "help"
+	ACTION_ACTIONS    = "actions"
+	ACTION_VIEW       = "view"
+	ACTION_EDIT       = "edit"
+	ACTION_CLONE      = "clone"
+	ACTION_EXPORT     = "export"
+	ACTION_SNAPSHOT   = "snapshot"
+	ACTION_DESTROY    = "destroy"
+	ACTION_EXIT       = "exit"
+	ACTION_SNAPSHOTS  = "snapshots"
+	ACTION_STARTSTOP  = "startstop"
+	ACTION_LOGIN      = "login"
+	ACTION_REFRESH    = "refresh"
+	ACTION_JAILS      = "jails"
+	ACTION_VMS        = "vms"
+	ACTION_XEN        = "xen"
+	ACTION_QEMU       = "qemu"
+	ACTION_FOCUS      = "focus"
+	ACTION_RECORDINGS = "recordings"
+	ACTION_NEXT_TAB   = "next-tab"
+	ACTION_CLOSE_TAB  = "close-tab"
+	ACTION_THEMES     = "themes"
+	ACTION_LAYOUT     = "layout"
+	ACTION_MAXIMIZE   = "maximize"
+	ACTION_GROW       = "grow"
+	ACTION_SHRINK     = "shrink"
+	ACTION_PALETTE    = "palette"
+	ACTION_MARK       = "mark"
+	ACTION_UNMARK     = "unmark"
+	ACTION_DASHBOARD  = "dashboard"
+	ACTION_STORAGE    = "storage"
+	ACTION_NETWORK    = "network"
+	ACTION_BOOTORDER  = "bootorder"
+	ACTION_GROUPSTART = "group-start"
+	ACTION_GROUPSTOP  = "group-stop"
+	ACTION_TAGS       = "tags"
+	ACTION_GROUPBYTAG = "group-by-tag"
+	ACTION_MARKTAG    = "mark-tag"
+	ACTION_NOTICES    = "notifications"
+)
+
+const KEYMAP_DEFAULT string = "default"
+
+type ActionDescription struct {
+	Action      string
+	Description string
+}
+
+// ActionDescriptions are shown in the help in this order
+var ActionDescriptions = []ActionDescription{
+	{ACTION_HELP, "To show this help"},
+	{ACTION_PALETTE, "To search and run a command in the command palette"},
+	{ACTION_ACTIONS, "To open 'Actions' menu for the selected jail/VM"},
+	{ACTION_LOGIN, "To login into the selected jail/VM (or switch to its terminal tab)"},
+	{ACTION_STARTSTOP, "To start or stop the selected jail/VM"},
+	{ACTION_VIEW, "To view the parameters of the selected jail/VM"},
+	{ACTION_EDIT, "To edit the selected jail/VM"},
+	{ACTION_CLONE, "To clone the selected jail/VM"},
+	{ACTION_EXPORT, "To export the selected jail/VM"},
+	{ACTION_SNAPSHOT, "To create a snapshot of the selected jail/VM"},
+	{ACTION_SNAPSHOTS, "To list and destroy the snapshots of the selected jail/VM"},
+	{ACTION_DESTROY, "To destroy the selected jail/VM"},
+	{ACTION_STORAGE, "To show the ZFS storage of the selected jail/VM"},
+	{ACTION_GROUPSTART, "To start the selected or marked jails/VMs with their dependencies in boot order"},
+	{ACTION_GROUPSTOP, "To stop the selected or marked jails/VMs with their dependents in reverse boot order"},
+	{ACTION_MARK, "To mark or unmark the selected jail/VM for the command palette"},
+	{ACTION_UNMARK, "To unmark all jails/VMs"},
+	{ACTION_MARKTAG, "To mark all jails/VMs with a tag"},
+	{ACTION_TAGS, "To edit the tags of the selected jail/VM"},
+	{ACTION_GROUPBYTAG, "To group the list by tag or show it flat"},
+	{ACTION_REFRESH, "To refresh the list"},
+	{ACTION_DASHBOARD, "To collapse or expand the host summary"},
+	{ACTION_NETWORK, "To show the addresses of all jails/VMs and their conflicts"},
+	{ACTION_BOOTORDER, "To show and edit the boot order and dependencies of all jails/VMs"},
+	{ACTION_NOTICES, "To show and dismiss the notifications of unexpected stops"},
+	{ACTION_JAILS, "To switch to jails management"},
+	{ACTION_VMS, "To switch to Bhyve VMs management"},
+	{ACTION_XEN, "To switch to XEN VMs management"},
+	{ACTION_QEMU, "To switch to QEMU VMs management"},
+	{ACTION_FOCUS, "To switch between the list and the terminal"},
+	{ACTION_NEXT_TAB, "To switch to the next terminal tab"},
+	{ACTION_CLOSE_TAB, "To close the active terminal tab"},
+	{ACTION_RECORDINGS, "To replay recorded login sessions"},
+	{ACTION_THEMES, "To change the color theme"},
+	{ACTION_LAYOUT, "To switch between vertical and side by side layout"},
+	{ACTION_MAXIMIZE, "To maximize the focused pane or restore both panes"},
+	{ACTION_GROW, "To grow the list pane"},
+	{ACTION_SHRINK, "To shrink the list pane"},
+	{ACTION_EXIT, "To exit"},
+}
+
+var keymapDefault = map[string][]string{
+	ACTION_HELP:       {"F1"},
+	ACTION_ACTIONS:    {"F2"},
+	ACTION_VIEW:       {"F3"},
+	ACTION_EDIT:       {"F4"},
+	ACTION_CLONE:      {"F5"},
+	ACTION_EXPORT:     {"F6"},
+	ACTION_SNAPSHOT:   {"F7"},
+	ACTION_DESTROY:    {"F8"},
+	ACTION_EXIT:       {"F10", "Ctrl-C", "Esc"},
+	ACTION_SNAPSHOTS:  {"F11"},
+	ACTION_STARTSTOP:  {"F12"},
+	ACTION_LOGIN:      {"Enter"},
+	ACTION_REFRESH:    {"Ctrl-R"},
+	ACTION_JAILS:      {"Ctrl-J"},
+	ACTION_VMS:        {"Ctrl-B"},
+	ACTION_XEN:        {"Ctrl-E"},
+	ACTION_QEMU:       {"Ctrl-Q"},
+	ACTION_FOCUS:      {"Tab"},
+	ACTION_RECORDINGS: {"F9"},
+	ACTION_NEXT_TAB:   {"Ctrl-N"},
+	ACTION_CLOSE_TAB:  {"Ctrl-W"},
+	ACTION_THEMES:     {"Ctrl-T"},
+	ACTION_LAYOUT:     {"Ctrl-L"},
+	ACTION_MAXIMIZE:   {"Ctrl-X"},
+	ACTION_GROW:       {"+", "Alt-Down", "Alt-Right", "Ctrl-Down", "Ctrl-Right"},
+	ACTION_SHRINK:     {"-", "Alt-Up", "Alt-Left", "Ctrl-Up", "Ctrl-Left"},
+	ACTION_PALETTE:    {"Ctrl-P"},
+	ACTION_MARK:       {"Insert", "Space"},
+	ACTION_UNMARK:     {"Ctrl-U"},
+	ACTION_DASHBOARD:  {"Ctrl-D"},
+	ACTION_STORAGE:    {"Ctrl-S"},
+	ACTION_NETWORK:    {"Ctrl-A"},
+	ACTION_BOOTORDER:  {"Ctrl-G"},
+	ACTION_GROUPSTART: {},
+	ACTION_GROUPSTOP:  {},
+	ACTION_TAGS:       {},
+	ACTION_GROUPBYTAG: {"Ctrl-K"},
+	ACTION_MARKTAG:    {},
+	ACTION_NOTICES:    {"Ctrl-F"},
+}
+
+// Actions registered by the container types
+var containerActions = make(map[string]bool)
+
+// IsContainerAction reports if the action is run on a container
+func IsContainerAction(action string) bool {
+	return containerActions[action]
+}
+
+// RegisterAction makes an action of a container type known to the key map without keys,
+// it is listed in the help before the global actions
+func RegisterAction(action string, description string) {
+	containerActions[action] = true
+	if _, found := keymapDefault[action]; found {
+		return
+	}
+	keymapDefault[action] = []string{}
+	pos := len(ActionDescriptions)
+	for i, ad := range ActionDescriptions {
+		if ad.Action == ACTION_MARK {
+			pos = i
+			break
+		}
+	}
+	ActionDescriptions = append(ActionDescriptions[:pos], append([]ActionDescription{{action, description}}, ActionDescriptions[pos:]...)...)
+}
+
+// Presets override the default key map, F-keys are kept as the second choice
+var keymapPresets = map[string]map[string][]string{
+	KEYMAP_DEFAULT: {},
+	"vim": {
+		ACTION_HELP:       {"?", "F1"},
+		ACTION_ACTIONS:    {"m", "F2"},
+		ACTION_VIEW:       {"v", "F3"},
+		ACTION_EDIT:       {"e", "F4"},
+		ACTION_CLONE:      {"y", "F5"},
+		ACTION_EXPORT:     {"w", "F6"},
+		ACTION_SNAPSHOT:   {"s", "F7"},
+		ACTION_DESTROY:    {"d", "F8"},
+		ACTION_EXIT:       {"q", "F10", "Ctrl-C"},
+		ACTION_SNAPSHOTS:  {"S", "F11"},
+		ACTION_STARTSTOP:  {"x", "F12"},
+		ACTION_LOGIN:      {"Enter", "i"},
+		ACTION_REFRESH:    {"r", "Ctrl-R"},
+		ACTION_JAILS:      {"J", "Ctrl-J"},
+		ACTION_VMS:        {"B", "Ctrl-B"},
+		ACTION_XEN:        {"X", "Ctrl-E"},
+		ACTION_QEMU:       {"Q", "Ctrl-Q"},
+		ACTION_RECORDINGS: {"R", "F9"},
+		ACTION_NEXT_TAB:   {"t", "Ctrl-N"},
+		ACTION_THEMES:     {"T", "Ctrl-T"},
+		ACTION_LAYOUT:     {"L", "Ctrl-L"},
+		ACTION_MAXIMIZE:   {"z", "Ctrl-X"},
+		ACTION_PALETTE:    {":", "Ctrl-P"},
+		ACTION_DASHBOARD:  {"D", "Ctrl-D"},
+		ACTION_NETWORK:    {"N", "Ctrl-A"},
+		ACTION_BOOTORDER:  {"O", "Ctrl-G"},
+		ACTION_GROUPBYTAG: {"g", "Ctrl-K"},
+		ACTION_NOTICES:    {"!", "Ctrl-F"},
+	},
+	"emacs": {
+		ACTION_HELP:       {"F1", "Alt-?"},
+		ACTION_ACTIONS:    {"Alt-x", "F2"},
+		ACTION_VIEW:       {"Alt-v", "F3"},
+		ACTION_EDIT:       {"Alt-e", "F4"},
+		ACTION_CLONE:      {"Alt-c", "F5"},
+		ACTION_EXPORT:     {"Alt-w", "F6"},
+		ACTION_SNAPSHOT:   {"Alt-s", "F7"},
+		ACTION_DESTROY:    {"Alt-d", "F8"},
+		ACTION_EXIT:       {"Alt-q", "F10", "Ctrl-C"},
+		ACTION_SNAPSHOTS:  {"Alt-l", "F11"},
+		ACTION_STARTSTOP:  {"Alt-t", "F12"},
+		ACTION_REFRESH:    {"Alt-g", "Ctrl-R"},
+		ACTION_FOCUS:      {"Tab", "Ctrl-O"},
+		ACTION_RECORDINGS: {"Alt-r", "F9"},
+		ACTION_NEXT_TAB:   {"Alt-n", "Ctrl-N"},
+		ACTION_CLOSE_TAB:  {"Alt-k", "Ctrl-W"},
+		ACTION_PALETTE:    {"Alt-p", "Ctrl-P"},
+		ACTION_MARK:       {"Ctrl-Space", "Insert"},
+	},
+}
+
+// KeyChord is a key with modifiers, Ctrl is implied by the key for Ctrl-letter
+// keys, Shift is implied by the rune for printable characters
+type KeyChord struct {
+	Key  tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+var keyNames = map[string]tcell.Key{
+	"Enter":     tcell.KeyEnter,
+	"Tab":       tcell.KeyTab,
+	"Backtab":   tcell.KeyBacktab,
+	"Esc":       tcell.KeyEsc,
+	"Backspace": tcell.KeyBackspace2,
+	"Delete":    tcell.KeyDelete,
+	"Insert":    tcell.KeyInsert,
+	"Home":      tcell.KeyHome,
+	"End":       tcell.KeyEnd,
+	"PgUp":      tcell.KeyPgUp,
+	"PgDn":      tcell.KeyPgDn,
+	"Up":        tcell.KeyUp,
+	"Down":      tcell.KeyDown,
+	"Left":      tcell.KeyLeft,
+	"Right":     tcell.KeyRight,
+}
+
+// MakeKeyChord normalizes the key event to KeyChord
+func MakeKeyChord(k gowid.IKey) KeyChord {
+	switch {
+	case k.Key() == tcell.KeyRune:
+		return KeyChord{Key: tcell.KeyRune, Rune: k.Rune(), Mod: k.Modifiers() & tcell.ModAlt}
+	case isCtrlLetter(k.Key()):
+		return KeyChord{Key: k.Key(), Mod: k.Modifiers() & tcell.ModAlt}
+	}
+	return KeyChord{Key: k.Key(), Mod: k.Modifiers() & (tcell.ModAlt | tcell.ModCtrl | tcell.ModShift)}
+}
+
+func isCtrlLetter(key tcell.Key) bool {
+	if key == tcell.KeyTab || key == tcell.KeyEnter || key == tcell.KeyBackspace {
+		return false
+	}
+	return key == tcell.KeyCtrlSpace || (key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ)
+}
+
+// ParseKeyChord parses key names like "F2", "Ctrl-R", "Alt-x", "Alt-Down", "Enter" or "?"
+func ParseKeyChord(s string) (KeyChord, error) {
+	var res KeyChord
+	name := s
+	prefixes := []struct {
+		prefix string
+		mod    tcell.ModMask
+	}{{"Alt-", tcell.ModAlt}, {"Ctrl-", tcell.ModCtrl}, {"Shift-", tcell.ModShift}}
+	for i := 0; i < len(prefixes); i++ {
+		p := prefixes[i]
+		if strings.HasPrefix(name, p.prefix) && len(name) > len(p.prefix) {
+			res.Mod |= p.mod
+			name = name[len(p.prefix):]
+			i = -1
+		}
+	}
+	if key, found := keyNames[name]; found {
+		res.Key = key
+		return res, nil
+	}
+	if len(name) > 1 && name[0] == 'F' {
+		if n, err := strconv.Atoi(name[1:]); err == nil && n >= 1 && n <= 64 {
+			res.Key = tcell.KeyF1 + tcell.Key(n-1)
+			return res, nil
+		}
+	}
+	if name == "Space" {
+		name = " "
+	}
+	if utf8.RuneCountInString(name) != 1 {
+		return res, fmt.Errorf("unknown key '%s'", s)
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	if res.Mod&tcell.ModCtrl != 0 && r == ' ' {
+		res.Key = tcell.KeyCtrlSpace
+		res.Mod &^= tcell.ModCtrl
+		return res, nil
+	}
+	if res.Mod&tcell.ModCtrl != 0 {
+		lr := r | 0x20
+		if lr < 'a' || lr > 'z' {
+			return res, fmt.Errorf("unknown key '%s'", s)
+		}
+		res.Key = tcell.KeyCtrlA + tcell.Key(lr-'a')
+		res.Mod &^= tcell.ModCtrl
+		return res, nil
+	}
+	res.Key = tcell.KeyRune
+	res.Rune = r
+	res.Mod &= tcell.ModAlt
+	return res, nil
+}
+
+func (k KeyChord) String() string {
+	var sb strings.Builder
+	if k.Mod&tcell.ModAlt != 0 {
+		sb.WriteString("Alt-")
+	}
+	if k.Mod&tcell.ModCtrl != 0 {
+		sb.WriteString("Ctrl-")
+	}
+	if k.Mod&tcell.ModShift != 0 {
+		sb.WriteString("Shift-")
+	}
+	switch {
+	case k.Key == tcell.KeyRune && k.Rune == ' ':
+		sb.WriteString("Space")
+	case k.Key == tcell.KeyRune:
+		sb.WriteRune(k.Rune)
+	case k.Key >= tcell.KeyF1 && k.Key <= tcell.KeyF64:
+		sb.WriteString(fmt.Sprintf("F%d", k.Key-tcell.KeyF1+1))
+	case k.Key == tcell.KeyCtrlSpace:
+		sb.WriteString("Ctrl-Space")
+	case isCtrlLetter(k.Key):
+		sb.WriteString(fmt.Sprintf("Ctrl-%c", 'A'+rune(k.Key-tcell.KeyCtrlA)))
+	default:
+		for name, key := range keyNames {
+			if key == k.Key {
+				sb.WriteString(name)
+				break
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Label is shown in the bottom menu, F-keys are shown by their numbers
+func (k KeyChord) Label() string {
+	if k.Mod == 0 && k.Key >= tcell.KeyF1 && k.Key <= tcell.KeyF64 {
+		return fmt.Sprintf(" %d", k.Key-tcell.KeyF1+1)
+	}
+	return " " + k.String()
+}
+
+func (k KeyChord) GowidKey() gowid.IKey {
+	return gowid.MakeKeyExt2(k.Mod, k.Key, k.Rune)
+}
+
+type KeyMap struct {
+	actions map[KeyChord]string
+	keys    map[string][]KeyChord
+}
+
+func GetKeyMapPresets() []string {
+	names := make([]string, 0)
+	for name := range keymapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewKeyMap makes the key map of the preset with the bindings overridden by keys
+// (action name -> list of key names), errors are returned for unknown presets,
+// actions and keys and for the keys bound by the user to several actions
+func NewKeyMap(preset string, keys map[string][]string) (*KeyMap, []error) {
+	errs := make([]error, 0)
+	km := &KeyMap{
+		actions: make(map[KeyChord]string),
+		keys:    make(map[string][]KeyChord),
+	}
+	if preset == "" {
+		preset = KEYMAP_DEFAULT
+	}
+	presetkeys, found := keymapPresets[preset]
+	if !found {
+		errs = append(errs, fmt.Errorf("unknown key map preset '%s'", preset))
+	}
+	bindings := make(map[string][]string)
+	for action, names := range keymapDefault {
+		bindings[action] = names
+	}
+	for action, names := range presetkeys {
+		bindings[action] = names
+	}
+	for action, names := range keys {
+		if _, found := keymapDefault[action]; !found {
+			errs = append(errs, fmt.Errorf("unknown action '%s'", action))
+			continue
+		}
+		bindings[action] = names
+	}
+	// the keys set by the user are bound first and take precedence over the preset
+	for _, user := range []bool{true, false} {
+		for _, ad := range ActionDescriptions {
+			if _, found := keys[ad.Action]; found != user {
+				continue
+			}
+			for _, name := range bindings[ad.Action] {
+				chord, err := ParseKeyChord(name)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", ad.Action, err))
+					continue
+				}
+				if other, found := km.actions[chord]; found {
+					if user {
+						errs = append(errs, fmt.Errorf("%s: key '%s' is already bound to %s", ad.Action, name, other))
+					}
+					continue
+				}
+				km.actions[chord] = ad.Action
+				km.keys[ad.Action] = append(km.keys[ad.Action], chord)
+			}
+		}
+	}
+	return km, errs
+}
+
+// GetAction returns the action bound to the key
+func (km *KeyMap) GetAction(k gowid.IKey) (string, bool) {
+	action, found := km.actions[MakeKeyChord(k)]
+	return action, found
+}
+
+func (km *KeyMap) GetKeys(action string) []KeyChord {
+	return km.keys[action]
+}
+
+// GetKeyName returns the name of the first key bound to the action
+func (km *KeyMap) GetKeyName(action string) string {
+	keys := km.keys[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0].String()
+}
+
+func (km *KeyMap) GetKeyLabel(action string) string {
+	keys := km.keys[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0].Label()
+}
+
+// GetKeyNames returns all the keys bound to the action for the help, like "'F10', 'Ctrl-C' or 'Esc'"
+func (km *KeyMap) GetKeyNames(action string) string {
+	keys := km.keys[action]
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = "'" + k.String() + "'"
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// GetGowidKeys returns the keys bound to the actions to be caught by keypress widgets
+func (km *KeyMap) GetGowidKeys(actions ...string) []gowid.IKey {
+	res := make([]gowid.IKey, 0)
+	for _, action := range actions {
+		for _, k := range km.keys[action] {
+			res = append(res, k.GowidKey())
+			if isCtrlLetter(k.Key) {
+				// terminals may report Ctrl-letter keys with or without Ctrl modifier
+				res = append(res, gowid.MakeKeyExt2(k.Mod|tcell.ModCtrl, k.Key, k.Rune))
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeyChord(t *testing.T) {
+	tests := []struct {
+		name string
+		want KeyChord
+	}{
+		{"F2", KeyChord{Key: tcell.KeyF2}},
+		{"F64", KeyChord{Key: tcell.KeyF64}},
+		{"Enter", KeyChord{Key: tcell.KeyEnter}},
+		{"Esc", KeyChord{Key: tcell.KeyEsc}},
+		{"?", KeyChord{Key: tcell.KeyRune, Rune: '?'}},
+		{"-", KeyChord{Key: tcell.KeyRune, Rune: '-'}},
+		{"x", KeyChord{Key: tcell.KeyRune, Rune: 'x'}},
+		{"Space", KeyChord{Key: tcell.KeyRune, Rune: ' '}},
+		{"Ctrl-R", KeyChord{Key: tcell.KeyCtrlR}},
+		{"Ctrl-r", KeyChord{Key: tcell.KeyCtrlR}},
+		{"Ctrl-Space", KeyChord{Key: tcell.KeyCtrlSpace}},
+		{"Alt-x", KeyChord{Key: tcell.KeyRune, Rune: 'x', Mod: tcell.ModAlt}},
+		{"Alt-Down", KeyChord{Key: tcell.KeyDown, Mod: tcell.ModAlt}},
+		{"Alt-F5", KeyChord{Key: tcell.KeyF5, Mod: tcell.ModAlt}},
+		{"Shift-Tab", KeyChord{Key: tcell.KeyTab, Mod: tcell.ModShift}},
+		{"Ctrl-Alt-Down", KeyChord{Key: tcell.KeyDown, Mod: tcell.ModAlt | tcell.ModCtrl}},
+		{"Alt-Ctrl-a", KeyChord{Key: tcell.KeyCtrlA, Mod: tcell.ModAlt}},
+		// the terminals report no shift for the characters
+		{"Shift-a", KeyChord{Key: tcell.KeyRune, Rune: 'a'}},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeyChord(tt.name)
+		if err != nil {
+			t.Errorf("ParseKeyChord(%q) failed: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeyChord(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyChordInvalid(t *testing.T) {
+	for _, name := range []string{"", "F0", "F65", "Foo", "Alt-", "Ctrl-1", "Ctrl-Down-x", "Hyper-x"} {
+		if got, err := ParseKeyChord(name); err == nil {
+			t.Errorf("ParseKeyChord(%q) = %+v, want an error", name, got)
+		}
+	}
+}
+
+func TestKeyChordStringRoundTrip(t *testing.T) {
+	for _, name := range []string{"F12", "Enter", "?", "Space", "Ctrl-R", "Ctrl-Space", "Alt-x", "Alt-Down", "Alt-Ctrl-A", "Shift-Tab"} {
+		k, err := ParseKeyChord(name)
+		if err != nil {
+			t.Errorf("ParseKeyChord(%q) failed: %v", name, err)
+			continue
+		}
+		if got := k.String(); got != name {
+			t.Errorf("ParseKeyChord(%q).String() = %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/button"
+	"github.com/gcla/gowid/widgets/columns"
+	"github.com/gcla/gowid/widgets/dialog"
+	"github.com/gcla/gowid/widgets/divider"
+	"github.com/gcla/gowid/widgets/edit"
+	"github.com/gcla/gowid/widgets/framed"
+	"github.com/gcla/gowid/widgets/keypress"
+	"github.com/gcla/gowid/widgets/list"
+	"github.com/gcla/gowid/widgets/pile"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gcla/gowid/widgets/text"
+	"github.com/gdamore/tcell/v2"
+)
+
+type PaletteItem struct {
+	Name string
+	Keys string
+	Run  func()
+}
+
+// FuzzyScore matches the pattern characters in order (ignoring case) in s,
+// matches at word starts and consecutive matches get higher score
+func FuzzyScore(pattern string, s string) (int, bool) {
+	pr := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+	sr := []rune(strings.ToLower(s))
+	if len(pr) == 0 {
+		return 0, true
+	}
+	score := 0
+	pi := 0
+	prev := -2
+	for si := 0; si < len(sr) && pi < len(pr); si++ {
+		if sr[si] != pr[pi] {
+			continue
+		}
+		score++
+		if si == prev+1 {
+			score += 2
+		}
+		if si == 0 || !unicode.IsLetter(sr[si-1]) {
+			score += 3
+		}
+		prev = si
+		pi++
+	}
+	if pi < len(pr) {
+		return 0, false
+	}
+	return score, true
+}
+
+// FilterPaletteItems returns the items matching the pattern, the best matches first
+func FilterPaletteItems(items []PaletteItem, pattern string) []PaletteItem {
+	type scored struct {
+		item  PaletteItem
+		score int
+	}
+	matched := make([]scored, 0)
+	for _, item := range items {
+		if score, ok := FuzzyScore(pattern, item.Name); ok {
+			matched = append(matched, scored{item, score})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].score > matched[j].score })
+	res := make([]PaletteItem, len(matched))
+	for i := range matched {
+		res[i] = matched[i].item
+	}
+	return res
+}
+
+// OpenCommandPalette shows the items filtered by the typed text,
+// 'Enter' in the search field runs the best match
+func (tui *Tui) OpenCommandPalette(title string, items []PaletteItem) {
+	var paletteDialog *dialog.Widget
+	filtered := items
+	RunItem := func(item PaletteItem) {
+		paletteDialog.Close(tui.App)
+		item.Run()
+	}
+	MakeWalker := func(items []PaletteItem) *list.SimpleListWalker {
+		lines := make([]gowid.IWidget, 0)
+		for _, item := range items {
+			item := item
+			line := columns.New([]gowid.IContainerWidget{
+				&gowid.ContainerWidget{IWidget: text.New(item.Name, HALIGN_LEFT), D: gowid.RenderWithWeight{W: 1}},
+				&gowid.ContainerWidget{IWidget: text.New(" "+item.Keys, HALIGN_LEFT), D: gowid.RenderFixed{}},
+			})
+			btn := button.New(GetStyledWidget(line, "white"), button.Options{Decoration: button.BareDecoration})
+			btn.OnClick(gowid.WidgetCallback{Name: "cb_" + item.Name, WidgetChangedFunction: func(app gowid.IApp, w gowid.IWidget) {
+				RunItem(item)
+			}})
+			lines = append(lines, btn)
+		}
+		return list.NewSimpleListWalker(lines)
+	}
+	itemlist := list.New(MakeWalker(filtered))
+	search := edit.New(edit.Options{Caption: "> "})
+	search.OnTextSet(gowid.WidgetCallback{Name: "cbpalettesearch", WidgetChangedFunction: func(app gowid.IApp, w gowid.IWidget) {
+		filtered = FilterPaletteItems(items, search.Text())
+		itemlist.SetWalker(MakeWalker(filtered), app)
+	}})
+	searchkp := keypress.New(search, keypress.Options{Keys: []gowid.IKey{gowid.MakeKeyExt(tcell.KeyEnter)}})
+	searchkp.OnKeyPress(keypress.MakeCallback("kppalettesearch", func(app gowid.IApp, w gowid.IWidget, k gowid.IKey) {
+		if len(filtered) > 0 {
+			RunItem(filtered[0])
+		}
+	}))
+	lines := pile.New([]gowid.IContainerWidget{
+		&gowid.ContainerWidget{IWidget: styled.New(text.New(title, HALIGN_MIDDLE), gowid.MakePaletteRef("magenta")), D: gowid.RenderFlow{}},
+		&gowid.ContainerWidget{IWidget: divider.NewUnicode(), D: gowid.RenderFlow{}},
+		&gowid.ContainerWidget{IWidget: styled.New(searchkp, gowid.MakePaletteRef("green")), D: gowid.RenderFlow{}},
+		&gowid.ContainerWidget{IWidget: divider.NewUnicode(), D: gowid.RenderFlow{}},
+		&gowid.ContainerWidget{IWidget: styled.New(itemlist, gowid.MakePaletteRef("green")), D: gowid.RenderWithWeight{W: 1}},
+	})
+	btnclose := dialog.Button{
+		Msg: "Close",
+		Action: gowid.MakeWidgetCallback("execsetfocus", gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
+			tui.SetFocus(FOCUS_ON_LIST)
+			paletteDialog.Close(tui.App)
+		})),
+	}
+	paletteDialog = dialog.New(
+		framed.NewSpace(
+			lines,
+		),
+		dialog.Options{
+			Buttons:         []dialog.Button{btnclose},
+			Modal:           true,
+			NoShadow:        true,
+			TabToButtons:    true,
+			BackgroundStyle: gowid.MakePaletteRef("bluebg"),
+			BorderStyle:     gowid.MakePaletteRef("dialog"),
+			ButtonStyle:     gowid.MakePaletteRef("white-focus"),
+			FocusOnWidget:   true,
+		},
+	)
+	paletteDialog.Open(tui.ViewHolder, gowid.RenderWithRatio{R: 0.6}, tui.App)
+}
@@ -0,0 +1,245 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/button"
+	"github.com/gcla/gowid/widgets/columns"
+	"github.com/gcla/gowid/widgets/holder"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gcla/gowid/widgets/terminal"
+	"github.com/gcla/gowid/widgets/text"
+	tcell "github.com/gdamore/tcell/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CONSOLE_SHELL = iota
+	CONSOLE_LOGIN
+	CONSOLE_SERIAL
+	CONSOLE_PLAYER
+)
+
+// Backends cbsd bhyve-console attaches the serial console with
+const (
+	SERIAL_CONSOLE_CU   string = "cu"
+	SERIAL_CONSOLE_TMUX string = "tmux"
+)
+
+// SerialDetach is the way to leave the serial console of a backend
+type SerialDetach struct {
+	Sequence string // written to the terminal to detach
+	Keys     string // shown in the status line
+}
+
+// cu(1) escape sequence and the default tmux prefix followed by 'd'
+var serialDetach = map[string]SerialDetach{
+	SERIAL_CONSOLE_CU:   {Sequence: "\n~.", Keys: "'~.' at line start"},
+	SERIAL_CONSOLE_TMUX: {Sequence: "\x02d", Keys: "'Ctrl-B' 'd'"},
+}
+
+const SHELL_TAB_TITLE string = "shell"
+
+// TerminalTab is a terminal session, the first tab is the local shell,
+// the other ones are attached to containers
+type TerminalTab struct {
+	Name   string
+	Title  string
+	Type   int
+	Status string
+	Term   *terminal.Widget
+}
+
+func NewTerminal(command []string) (*terminal.Widget, error) {
+	return terminal.NewExt(terminal.Options{
+		Command:           command,
+		HotKey:            terminal.HotKey{K: tcell.KeyCtrlZ},
+		HotKeyPersistence: &terminal.HotKeyDuration{D: time.Second * 2},
+		Scrollbar:         true,
+		Scrollback:        1000,
+	})
+}
+
+func GetShellCommand() []string {
+	return strings.Split(os.Getenv("SHELL"), " ")
+}
+
+// SetStatusHolder sets the widget used to show the terminal tabs and status line,
+// its current content is shown when there is nothing to show
+func (tui *Tui) SetStatusHolder(h *holder.Widget) {
+	tui.StatusHolder = h
+	tui.StatusDefault = h.SubWidget()
+}
+
+// SetTerminalHolder sets the widget holding the terminal of the active tab
+func (tui *Tui) SetTerminalHolder(h *holder.Widget) {
+	tui.TerminalHolder = h
+}
+
+func (tui *Tui) SetTerminalStatus(status string) {
+	tui.Tabs[tui.ActiveTab].Status = status
+	tui.UpdateStatusLine()
+}
+
+func (tui *Tui) UpdateStatusLine() {
+	if tui.StatusHolder == nil {
+		return
+	}
+	status := tui.Tabs[tui.ActiveTab].Status
+	if len(tui.Tabs) < 2 && status == "" {
+		tui.StatusHolder.SetSubWidget(tui.StatusDefault, tui.App)
+		return
+	}
+	MakeTabFunction := func(i int) gowid.WidgetChangedFunction {
+		return func(app gowid.IApp, w gowid.IWidget) {
+			tui.SwitchTab(i)
+		}
+	}
+	widgets := make([]gowid.IContainerWidget, 0)
+	for i, tab := range tui.Tabs {
+		style := "graydgreen"
+		if i == tui.ActiveTab {
+			style = "blackgreen"
+		}
+		ttext := text.New(fmt.Sprintf(" %d:%s ", i+1, tab.Title), HALIGN_LEFT)
+		tbtn := button.New(styled.New(ttext, gowid.MakePaletteRef(style)), button.Options{Decoration: button.BareDecoration})
+		tbtn.OnClick(gowid.WidgetCallback{Name: "cbtab_" + tab.Title, WidgetChangedFunction: MakeTabFunction(i)})
+		widgets = append(widgets, &gowid.ContainerWidget{IWidget: tbtn, D: gowid.RenderFixed{}})
+	}
+	stext := styled.New(text.New(" "+status, HALIGN_LEFT), gowid.MakePaletteRef("white"))
+	widgets = append(widgets, &gowid.ContainerWidget{IWidget: stext, D: gowid.RenderWithWeight{W: 1}})
+	tui.StatusHolder.SetSubWidget(columns.New(widgets, columns.Options{DoNotSetSelected: true}), tui.App)
+}
+
+// FindTab returns the position of the tab of the container name with console
+// type ctype (any type if ctype is negative), -1 if there is no such tab
+func (tui *Tui) FindTab(name string, ctype int) int {
+	if name == "" {
+		return -1
+	}
+	for i, tab := range tui.Tabs {
+		if tab.Name == name && (ctype < 0 || tab.Type == ctype) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tui *Tui) HasConsole(name string) bool {
+	return tui.FindTab(name, -1) >= 0
+}
+
+// GetActiveConsole returns the container name of the active tab, empty for the shell
+func (tui *Tui) GetActiveConsole() string {
+	return tui.Tabs[tui.ActiveTab].Name
+}
+
+func (tui *Tui) SwitchTab(i int) {
+	if i < 0 || i >= len(tui.Tabs) {
+		return
+	}
+	tui.ActiveTab = i
+	tui.Console = tui.Tabs[i].Term
+	if tui.TerminalHolder != nil {
+		tui.TerminalHolder.SetSubWidget(tui.Console, tui.App)
+	}
+	tui.UpdateStatusLine()
+	tui.SetFocus(FOCUS_ON_TERMINAL)
+	tui.EvtConsoleChanged.Emit(tui.Tabs[i].Name)
+}
+
+func (tui *Tui) NextTab() {
+	tui.SwitchTab((tui.ActiveTab + 1) % len(tui.Tabs))
+}
+
+func (tui *Tui) PrevTab() {
+	tui.SwitchTab((tui.ActiveTab + len(tui.Tabs) - 1) % len(tui.Tabs))
+}
+
+// AttachConsole opens a new terminal tab running the command, the program and its arguments,
+// for the container name, if the container already has a tab it is activated
+func (tui *Tui) AttachConsole(name string, command []string, ctype int, status string) {
+	if i := tui.FindTab(name, ctype); i >= 0 {
+		tui.SwitchTab(i)
+		return
+	}
+	term, err := NewTerminal(command)
+	if err != nil {
+		log.Errorf("Cannot create terminal for %s: %v", name, err)
+		return
+	}
+	title := name
+	if ctype == CONSOLE_SERIAL {
+		title += "(serial)"
+	}
+	tab := &TerminalTab{Name: name, Title: title, Type: ctype, Status: status, Term: term}
+	term.OnProcessExited(gowid.WidgetCallback{Name: "cbexit_" + name, WidgetChangedFunction: func(app gowid.IApp, w gowid.IWidget) {
+		app.Run(gowid.RunFunction(func(app gowid.IApp) {
+			tui.CloseTab(tab)
+		}))
+	}})
+	tui.Tabs = append(tui.Tabs, tab)
+	tui.SwitchTab(len(tui.Tabs) - 1)
+}
+
+// DetachConsole leaves all the consoles of the container name and closes their tabs
+func (tui *Tui) DetachConsole(name string) {
+	for i := tui.FindTab(name, -1); i >= 0; i = tui.FindTab(name, -1) {
+		tui.DetachTab(tui.Tabs[i])
+	}
+}
+
+// GetSerialDetach returns the way to leave the serial consoles with the backend
+// of SerialConsole, cu if it is not set or unknown
+func (tui *Tui) GetSerialDetach() SerialDetach {
+	if detach, found := serialDetach[tui.SerialConsole]; found {
+		return detach
+	}
+	return serialDetach[SERIAL_CONSOLE_CU]
+}
+
+func (tui *Tui) DetachTab(tab *TerminalTab) {
+	if tab.Type == CONSOLE_SERIAL {
+		tab.Term.Write([]byte(tui.GetSerialDetach().Sequence))
+		time.Sleep(200 * time.Millisecond)
+	}
+	tui.CloseTab(tab)
+}
+
+// CloseActiveTab closes the active tab unless it is the shell
+func (tui *Tui) CloseActiveTab() {
+	if tui.ActiveTab > 0 {
+		tui.DetachTab(tui.Tabs[tui.ActiveTab])
+	}
+}
+
+func (tui *Tui) CloseTab(tab *TerminalTab) {
+	pos := -1
+	for i, t := range tui.Tabs {
+		if t == tab {
+			pos = i
+			break
+		}
+	}
+	if pos <= 0 {
+		return
+	}
+	tab.Term.Signal(syscall.SIGKILL)
+	tui.Tabs = append(tui.Tabs[:pos], tui.Tabs[pos+1:]...)
+	active := tui.ActiveTab
+	if active >= pos {
+		active--
+	}
+	if active < 0 {
+		active = 0
+	}
+	tui.SwitchTab(active)
+	if active == 0 {
+		tui.SetFocus(FOCUS_ON_LIST)
+	}
+}
@@ -0,0 +1,307 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gcla/gowid"
+)
+
+const THEME_DEFAULT string = "default"
+const THEME_FILE_EXTENSION string = ".json"
+
+// Every palette entry referenced by the widgets of main, tui and the container packages,
+// jail status styles are used with "-focus" and "-nofocus" suffixes (see GetStyledWidget)
+var StyleNames = []string{
+	"red-focus", "red-nofocus", // stopped jail with autostart
+	"green-focus", "green-nofocus", // running jail
+	"white-focus", "white-nofocus", // stopped jail, list header, action menu items
+	"gray-focus", "gray-nofocus", // jail in unknown state
+	"inactive-focus", "inactive-nofocus", // selected jail while the terminal has focus
+	"marked-focus", "marked-nofocus", // jail marked for the command palette
+	"green",      // dialog fields, action list
+	"white",      // dialog text, terminal status text
+	"magenta",    // dialog header
+	"blackgreen", // bottom menu keys, active terminal tab
+	"graydgreen", // bottom menu labels, inactive terminal tabs
+	"bluebg",     // dialog background
+	"dialog",     // dialog border
+	"line",       // separator lines
+	"dashboard",  // host summary header
+	"error",      // validation errors of dialog fields
+}
+
+// ThemeEntry is a palette entry, colors are names like "black", "darkgreen", "lightgray",
+// gray levels "g0".."g100" or RGB "#rgb"; empty color is "none" (terminal default)
+type ThemeEntry struct {
+	Fg    string `json:"fg"`
+	Bg    string `json:"bg"`
+	Style string `json:"style,omitempty"` // bold, reverse, underline, blink or dim
+}
+
+type Theme map[string]ThemeEntry
+
+var colorNames = map[string]gowid.IColor{
+	"none":      gowid.ColorNone,
+	"default":   gowid.ColorDefault,
+	"black":     gowid.ColorBlack,
+	"white":     gowid.ColorWhite,
+	"red":       gowid.ColorRed,
+	"darkred":   gowid.ColorDarkRed,
+	"green":     gowid.ColorGreen,
+	"darkgreen": gowid.ColorDarkGreen,
+	"blue":      gowid.ColorBlue,
+	"darkblue":  gowid.ColorDarkBlue,
+	"yellow":    gowid.ColorYellow,
+	"cyan":      gowid.ColorCyan,
+	"magenta":   gowid.ColorMagenta,
+	"purple":    gowid.ColorPurple,
+	"orange":    gowid.ColorOrange,
+	"lightgray": gowid.ColorLightGray,
+	"darkgray":  gowid.ColorDarkGray,
+}
+
+var styleNames = map[string]gowid.StyleAttrs{
+	"":          gowid.StyleNone,
+	"bold":      gowid.StyleBold,
+	"reverse":   gowid.StyleReverse,
+	"underline": gowid.StyleUnderline,
+	"blink":     gowid.StyleBlink,
+	"dim":       gowid.StyleDim,
+}
+
+var builtinThemes = map[string]Theme{
+	THEME_DEFAULT: {
+		"red-focus":        {Fg: "black", Bg: "purple"},
+		"red-nofocus":      {Fg: "purple", Bg: "none"},
+		"green-focus":      {Fg: "black", Bg: "green"},
+		"green-nofocus":    {Fg: "green", Bg: "none"},
+		"white-focus":      {Fg: "black", Bg: "white"},
+		"white-nofocus":    {Fg: "white", Bg: "none"},
+		"gray-focus":       {Fg: "black", Bg: "lightgray"},
+		"gray-nofocus":     {Fg: "lightgray", Bg: "none"},
+		"inactive-focus":   {Fg: "white", Bg: "g23"},
+		"inactive-nofocus": {Fg: "white", Bg: "g23"},
+		"marked-focus":     {Fg: "black", Bg: "yellow"},
+		"marked-nofocus":   {Fg: "yellow", Bg: "none"},
+		"green":            {Fg: "green", Bg: "none"},
+		"white":            {Fg: "white", Bg: "none"},
+		"magenta":          {Fg: "magenta", Bg: "none"},
+		"blackgreen":       {Fg: "black", Bg: "green"},
+		"graydgreen":       {Fg: "lightgray", Bg: "darkgreen"},
+		"bluebg":           {Fg: "white", Bg: "cyan"},
+		"dialog":           {Fg: "white", Bg: "cyan"},
+		"line":             {Fg: "lightgray", Bg: "none"},
+		"dashboard":        {Fg: "white", Bg: "darkblue"},
+		"error":            {Fg: "red", Bg: "none"},
+	},
+	"dark": {
+		"red-focus":        {Fg: "black", Bg: "red"},
+		"red-nofocus":      {Fg: "red", Bg: "black"},
+		"green-focus":      {Fg: "black", Bg: "green"},
+		"green-nofocus":    {Fg: "green", Bg: "black"},
+		"white-focus":      {Fg: "black", Bg: "lightgray"},
+		"white-nofocus":    {Fg: "lightgray", Bg: "black"},
+		"gray-focus":       {Fg: "black", Bg: "darkgray"},
+		"gray-nofocus":     {Fg: "darkgray", Bg: "black"},
+		"inactive-focus":   {Fg: "lightgray", Bg: "g15"},
+		"inactive-nofocus": {Fg: "lightgray", Bg: "g15"},
+		"marked-focus":     {Fg: "black", Bg: "yellow"},
+		"marked-nofocus":   {Fg: "yellow", Bg: "black"},
+		"green":            {Fg: "green", Bg: "g11"},
+		"white":            {Fg: "lightgray", Bg: "g11"},
+		"magenta":          {Fg: "purple", Bg: "g11"},
+		"blackgreen":       {Fg: "black", Bg: "darkgreen"},
+		"graydgreen":       {Fg: "lightgray", Bg: "g19"},
+		"bluebg":           {Fg: "lightgray", Bg: "g11"},
+		"dialog":           {Fg: "darkgreen", Bg: "g11"},
+		"line":             {Fg: "darkgray", Bg: "black"},
+		"dashboard":        {Fg: "lightgray", Bg: "darkgray"},
+		"error":            {Fg: "red", Bg: "g11"},
+	},
+	"light": {
+		"red-focus":        {Fg: "white", Bg: "darkred"},
+		"red-nofocus":      {Fg: "darkred", Bg: "white"},
+		"green-focus":      {Fg: "white", Bg: "darkgreen"},
+		"green-nofocus":    {Fg: "darkgreen", Bg: "white"},
+		"white-focus":      {Fg: "white", Bg: "black"},
+		"white-nofocus":    {Fg: "black", Bg: "white"},
+		"gray-focus":       {Fg: "white", Bg: "darkgray"},
+		"gray-nofocus":     {Fg: "darkgray", Bg: "white"},
+		"inactive-focus":   {Fg: "black", Bg: "g85"},
+		"inactive-nofocus": {Fg: "black", Bg: "g85"},
+		"marked-focus":     {Fg: "white", Bg: "darkblue"},
+		"marked-nofocus":   {Fg: "darkblue", Bg: "white"},
+		"green":            {Fg: "darkgreen", Bg: "g93"},
+		"white":            {Fg: "black", Bg: "g93"},
+		"magenta":          {Fg: "purple", Bg: "g93"},
+		"blackgreen":       {Fg: "white", Bg: "darkgreen"},
+		"graydgreen":       {Fg: "black", Bg: "g85"},
+		"bluebg":           {Fg: "black", Bg: "g93"},
+		"dialog":           {Fg: "darkblue", Bg: "g93"},
+		"line":             {Fg: "darkgray", Bg: "white"},
+		"dashboard":        {Fg: "black", Bg: "lightgray"},
+		"error":            {Fg: "darkred", Bg: "g93"},
+	},
+	"high-contrast": {
+		"red-focus":        {Fg: "black", Bg: "red", Style: "bold"},
+		"red-nofocus":      {Fg: "red", Bg: "black", Style: "bold"},
+		"green-focus":      {Fg: "black", Bg: "green", Style: "bold"},
+		"green-nofocus":    {Fg: "green", Bg: "black", Style: "bold"},
+		"white-focus":      {Fg: "black", Bg: "white", Style: "bold"},
+		"white-nofocus":    {Fg: "white", Bg: "black", Style: "bold"},
+		"gray-focus":       {Fg: "black", Bg: "yellow", Style: "bold"},
+		"gray-nofocus":     {Fg: "yellow", Bg: "black", Style: "bold"},
+		"inactive-focus":   {Fg: "white", Bg: "black", Style: "underline"},
+		"inactive-nofocus": {Fg: "white", Bg: "black", Style: "underline"},
+		"marked-focus":     {Fg: "black", Bg: "cyan", Style: "bold"},
+		"marked-nofocus":   {Fg: "cyan", Bg: "black", Style: "bold"},
+		"green":            {Fg: "yellow", Bg: "black", Style: "bold"},
+		"white":            {Fg: "white", Bg: "black", Style: "bold"},
+		"magenta":          {Fg: "cyan", Bg: "black", Style: "bold"},
+		"blackgreen":       {Fg: "black", Bg: "yellow", Style: "bold"},
+		"graydgreen":       {Fg: "white", Bg: "black", Style: "bold"},
+		"bluebg":           {Fg: "white", Bg: "black"},
+		"dialog":           {Fg: "yellow", Bg: "black", Style: "bold"},
+		"line":             {Fg: "white", Bg: "black"},
+		"dashboard":        {Fg: "black", Bg: "white", Style: "bold"},
+		"error":            {Fg: "red", Bg: "black", Style: "bold"},
+	},
+	"monochrome": {
+		"red-focus":        {Fg: "default", Bg: "default", Style: "reverse"},
+		"red-nofocus":      {Fg: "default", Bg: "default", Style: "bold"},
+		"green-focus":      {Fg: "default", Bg: "default", Style: "reverse"},
+		"green-nofocus":    {Fg: "default", Bg: "default", Style: "underline"},
+		"white-focus":      {Fg: "default", Bg: "default", Style: "reverse"},
+		"white-nofocus":    {Fg: "default", Bg: "default"},
+		"gray-focus":       {Fg: "default", Bg: "default", Style: "reverse"},
+		"gray-nofocus":     {Fg: "default", Bg: "default", Style: "dim"},
+		"inactive-focus":   {Fg: "default", Bg: "default", Style: "underline"},
+		"inactive-nofocus": {Fg: "default", Bg: "default", Style: "underline"},
+		"marked-focus":     {Fg: "default", Bg: "default", Style: "reverse"},
+		"marked-nofocus":   {Fg: "default", Bg: "default", Style: "bold"},
+		"green":            {Fg: "default", Bg: "default"},
+		"white":            {Fg: "default", Bg: "default"},
+		"magenta":          {Fg: "default", Bg: "default", Style: "bold"},
+		"blackgreen":       {Fg: "default", Bg: "default", Style: "reverse"},
+		"graydgreen":       {Fg: "default", Bg: "default"},
+		"bluebg":           {Fg: "default", Bg: "default"},
+		"dialog":           {Fg: "default", Bg: "default", Style: "bold"},
+		"line":             {Fg: "default", Bg: "default", Style: "dim"},
+		"dashboard":        {Fg: "default", Bg: "default", Style: "reverse"},
+		"error":            {Fg: "default", Bg: "default", Style: "underline"},
+	},
+}
+
+func MakeColor(name string) (gowid.IColor, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return gowid.ColorNone, nil
+	}
+	if color, ok := colorNames[name]; ok {
+		return color, nil
+	}
+	return gowid.MakeColorSafe(name)
+}
+
+func (entry ThemeEntry) MakePaletteEntry() (gowid.PaletteEntry, error) {
+	var res gowid.PaletteEntry
+	fg, err := MakeColor(entry.Fg)
+	if err != nil {
+		return res, fmt.Errorf("invalid foreground color '%s': %w", entry.Fg, err)
+	}
+	bg, err := MakeColor(entry.Bg)
+	if err != nil {
+		return res, fmt.Errorf("invalid background color '%s': %w", entry.Bg, err)
+	}
+	style, ok := styleNames[strings.ToLower(strings.TrimSpace(entry.Style))]
+	if !ok {
+		return res, fmt.Errorf("invalid style '%s'", entry.Style)
+	}
+	return gowid.MakeStyledPaletteEntry(fg, bg, style), nil
+}
+
+// GetThemeNames returns the names of the built-in themes and of the theme files found in dir
+func GetThemeNames(dir string) []string {
+	names := make([]string, 0)
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	if dir != "" {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+THEME_FILE_EXTENSION))
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), THEME_FILE_EXTENSION)
+			if _, found := builtinThemes[name]; !found {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme reads the theme name from dir, the theme file is a JSON object
+// mapping style names to ThemeEntry, built-in themes can be overridden by files
+func LoadTheme(dir string, name string) (Theme, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name+THEME_FILE_EXTENSION))
+		if err == nil {
+			theme := make(Theme)
+			err = json.Unmarshal(data, &theme)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse theme %s: %w", name, err)
+			}
+			return theme, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	theme, found := builtinThemes[name]
+	if !found {
+		return nil, fmt.Errorf("theme %s not found", name)
+	}
+	return theme, nil
+}
+
+// ValidateTheme returns the style names missing in the theme
+// and the errors in its entries
+func ValidateTheme(theme Theme) ([]string, []error) {
+	missing := make([]string, 0)
+	errs := make([]error, 0)
+	for _, name := range StyleNames {
+		if _, found := theme[name]; !found {
+			missing = append(missing, name)
+		}
+	}
+	for name, entry := range theme {
+		if _, err := entry.MakePaletteEntry(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return missing, errs
+}
+
+// MakePalette converts the theme to gowid palette,
+// missing and invalid entries are taken from the default theme
+func MakePalette(theme Theme) gowid.Palette {
+	palette := make(gowid.Palette)
+	for name, entry := range builtinThemes[THEME_DEFAULT] {
+		palette[name], _ = entry.MakePaletteEntry()
+	}
+	for name, entry := range theme {
+		if pentry, err := entry.MakePaletteEntry(); err == nil {
+			palette[name] = pentry
+		}
+	}
+	return palette
+}
+
+func (tui *Tui) SetTheme(theme Theme) {
+	tui.App.SetPalette(MakePalette(theme))
+	tui.App.Redraw()
+}
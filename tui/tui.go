@@ -15,6 +15,7 @@ import (
 	"bufio"
 	"os"
 	"os/exec"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -28,18 +29,16 @@ import (
 	"github.com/gcla/gowid/widgets/list"
 	"github.com/gcla/gowid/widgets/terminal"
 
-	"github.com/gcla/gowid/widgets/checkbox"
-	"github.com/gcla/gowid/widgets/columns"
 	"github.com/gcla/gowid/widgets/dialog"
 	"github.com/gcla/gowid/widgets/divider"
 	"github.com/gcla/gowid/widgets/edit"
 
 	"github.com/gcla/gowid/widgets/framed"
-	"github.com/gcla/gowid/widgets/hpadding"
 	"github.com/gcla/gowid/widgets/pile"
 	"github.com/gcla/gowid/widgets/styled"
 
 	"github.com/gcla/gowid/widgets/text"
+	"github.com/quasilyte/gsignal"
 	log "github.com/sirupsen/logrus"
 
 	"editwithscrollbar"
@@ -51,143 +50,92 @@ var HALIGN_LEFT text.Options = text.Options{Align: gowid.HAlignLeft{}}
 const FOCUS_ON_LIST int = 0
 const FOCUS_ON_TERMINAL int = 3
 
-var CbsdJailConsoleActive = ""
+// IPanes is the main widget holding the containers list and the terminal,
+// it receives FOCUS_ON_LIST or FOCUS_ON_TERMINAL as focus position
+type IPanes interface {
+	SetFocus(app gowid.IApp, i int)
+}
 
 type Tui struct {
-	App           *gowid.App
-	ViewHolder    *holder.Widget
-	Console       *terminal.Widget
-	LogText       string
-	TuiMainWidget *pile.Widget
+	App               *gowid.App
+	ViewHolder        *holder.Widget
+	Console           *terminal.Widget
+	LogText           string
+	TuiMainWidget     IPanes
+	StatusHolder      *holder.Widget
+	StatusDefault     gowid.IWidget
+	TerminalHolder    *holder.Widget
+	Tabs              []*TerminalTab
+	ActiveTab         int
+	EvtConsoleChanged gsignal.Event[string]
+	KeyMap            *KeyMap
+	ViewExtra         func(name string) string // optional text added by the application to the View dialogs
+	SerialConsole     string                   // backend of the serial consoles, SERIAL_CONSOLE_CU by default
+}
+
+// GetViewExtra returns the text added to the View dialog of the container
+func (tui *Tui) GetViewExtra(name string) string {
+	if tui.ViewExtra == nil {
+		return ""
+	}
+	return tui.ViewExtra(name)
 }
 
-func NewTui(app *gowid.App, view_holder *holder.Widget, console *terminal.Widget, main *pile.Widget) *Tui {
+func NewTui(app *gowid.App, view_holder *holder.Widget, console *terminal.Widget, main IPanes) *Tui {
+	keymap, _ := NewKeyMap(KEYMAP_DEFAULT, nil)
 	res := &Tui{
 		App:           app,
 		ViewHolder:    view_holder,
 		Console:       console,
 		LogText:       "",
 		TuiMainWidget: main,
+		Tabs:          []*TerminalTab{{Name: "", Title: SHELL_TAB_TITLE, Type: CONSOLE_SHELL, Term: console}},
+		ActiveTab:     0,
+		KeyMap:        keymap,
 	}
 	return res
 }
 
+// MakeDialogForJail makes the dialog with text lines, checkboxes and text fields,
+// okfunc receives their values in the same order
 func (tui *Tui) MakeDialogForJail(jname string, title string, txt []string,
 	boolparnames []string, boolpardefaults []bool,
 	strparnames []string, strpardefaults []string,
 	okfunc func(jname string, boolparams []bool, strparams []string)) *dialog.Widget {
+	return tui.MakeValidatedDialogForJail(jname, title, txt, boolparnames, boolpardefaults,
+		MakeStrFields(strparnames, strpardefaults), okfunc)
+}
 
-	var lines *pile.Widget
-	var containers []gowid.IContainerWidget
-
-	var ntxt int = 0
-	var nboolparams = 0
-	var nstrparams = 0
-
-	if txt != nil {
-		ntxt = len(txt)
-	}
-	if boolparnames != nil {
-		nboolparams = len(boolparnames)
-	}
-	if strparnames != nil {
-		nstrparams = len(strparnames)
-	}
-
-	var widtxt []*text.Widget
-	var widtxtst []*styled.Widget
-
-	var widchecktxt []*text.Widget
-	var widchecktxtst []*styled.Widget
-	var widcheck []*checkbox.Widget
-	var widcheckgrp []*hpadding.Widget
-
-	var wideditparams []*edit.Widget
-	var wideditstparams []*styled.Widget
-
-	var strparams []string
-	var boolparams []bool
-
-	var btncancel dialog.Button
-	var btnok dialog.Button
-	buttons := make([]dialog.Button, 0)
-	var retdialog *dialog.Widget = nil
-
-	htxt := text.New(title, HALIGN_MIDDLE)
-	htxtst := styled.New(htxt, gowid.MakePaletteRef("magenta"))
-	containers = append(containers, &gowid.ContainerWidget{IWidget: htxtst, D: gowid.RenderFlow{}})
-	containers = append(containers, &gowid.ContainerWidget{IWidget: divider.NewUnicode(), D: gowid.RenderFlow{}})
-
-	for i := 0; i < ntxt; i++ {
-		widtxt = append(widtxt, text.New(txt[i], HALIGN_LEFT))
-		widtxtst = append(widtxtst, styled.New(widtxt[i], gowid.MakePaletteRef("green")))
-		containers = append(containers, &gowid.ContainerWidget{IWidget: widtxtst[i], D: gowid.RenderFlow{}})
-	}
-
-	for i := 0; i < nboolparams; i++ {
-		widchecktxt = append(widchecktxt, text.New(boolparnames[i], HALIGN_LEFT))
-		widchecktxtst = append(widchecktxtst, styled.New(widchecktxt[i], gowid.MakePaletteRef("green")))
-		widcheck = append(widcheck, checkbox.New(boolpardefaults[i]))
-		widcheckgrp = append(widcheckgrp, hpadding.New(columns.NewFixed(widchecktxtst[i], widcheck[i]), gowid.HAlignLeft{}, gowid.RenderFixed{}))
-		containers = append(containers, &gowid.ContainerWidget{IWidget: widcheckgrp[i], D: gowid.RenderFlow{}})
+// MakeValidatedDialogForJail is MakeDialogForJail with text fields checked by their validators
+func (tui *Tui) MakeValidatedDialogForJail(jname string, title string, txt []string,
+	boolparnames []string, boolpardefaults []bool,
+	strfields []StrField,
+	okfunc func(jname string, boolparams []bool, strparams []string)) *dialog.Widget {
+	form := Form{Title: title, Text: txt}
+	for i, name := range boolparnames {
+		value := "0"
+		if boolpardefaults[i] {
+			value = "1"
+		}
+		form.Fields = append(form.Fields, FormField{Name: "bool" + strconv.Itoa(i), Caption: name, Type: FORM_CHECKBOX, Default: value})
 	}
-
-	for i := 0; i < nstrparams; i++ {
-		wideditparams = append(wideditparams, edit.New(edit.Options{Caption: strparnames[i], Text: strpardefaults[i]}))
-		wideditstparams = append(wideditstparams, styled.New(wideditparams[i], gowid.MakePaletteRef("green")))
-		containers = append(containers, &gowid.ContainerWidget{IWidget: wideditstparams[i], D: gowid.RenderFlow{}})
+	for i, f := range strfields {
+		form.Fields = append(form.Fields, FormField{Name: "str" + strconv.Itoa(i), Caption: f.Caption, Type: FORM_TEXT, Default: f.Default, Validate: f.Validate})
 	}
-
-	lines = pile.New(containers)
-
-	btnok = dialog.Button{
-		Msg: "OK",
-		Action: gowid.MakeWidgetCallback("execokfunc", gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
-			for i := 0; i < nboolparams; i++ {
-				boolparams = append(boolparams, widcheck[i].IsChecked())
+	if okfunc != nil {
+		form.Submit = func(values FormValues) {
+			boolparams := make([]bool, len(boolparnames))
+			for i := range boolparnames {
+				boolparams[i] = values.Bool("bool" + strconv.Itoa(i))
 			}
-			for i := 0; i < nstrparams; i++ {
-				strparams = append(strparams, wideditparams[i].Text())
+			strparams := make([]string, len(strfields))
+			for i := range strfields {
+				strparams[i] = values.String("str" + strconv.Itoa(i))
 			}
-			tui.SetFocus(FOCUS_ON_LIST)
 			okfunc(jname, boolparams, strparams)
-		})),
-	}
-
-	btncancel = dialog.Button{
-		Msg: "(ToSet)",
-		Action: gowid.MakeWidgetCallback("execsetfocus", gowid.WidgetChangedFunction(func(app gowid.IApp, w gowid.IWidget) {
-			tui.SetFocus(FOCUS_ON_LIST)
-			retdialog.Close(tui.App)
-		})),
-	}
-
-	if nboolparams < 1 && nstrparams < 1 && okfunc == nil {
-		btncancel.Msg = "Close"
-		buttons = append(buttons, btncancel)
-	} else {
-		btncancel.Msg = "Cancel"
-		buttons = append(buttons, btnok)
-		buttons = append(buttons, btncancel)
+		}
 	}
-
-	retdialog = dialog.New(
-		framed.NewSpace(
-			lines,
-		),
-		dialog.Options{
-			Buttons:         buttons,
-			NoShadow:        true,
-			BackgroundStyle: gowid.MakePaletteRef("bluebg"),
-			BorderStyle:     gowid.MakePaletteRef("dialog"),
-			ButtonStyle:     gowid.MakePaletteRef("white-focus"),
-			Modal:           true,
-			FocusOnWidget:   true,
-		},
-	)
-
-	//	retdialog.Options.Buttons = buttons
-	return retdialog
+	return tui.MakeFormDialog(form)
 }
 
 func (tui *Tui) CreateActionsLogDialog(editWidget *edit.Widget, height int) *dialog.Widget {
@@ -222,7 +170,9 @@ func (tui *Tui) CreateActionsLogDialog(editWidget *edit.Widget, height int) *dia
 	return actionlogdialog
 }
 
-func (tui *Tui) ExecCommand(title string, command string, args []string) {
+// ExecCommand runs the command showing its output in a log dialog,
+// the error is returned if the command could not start or failed
+func (tui *Tui) ExecCommand(title string, command string, args []string) error {
 	var cmd *exec.Cmd
 	logspace := edit.New(edit.Options{ReadOnly: true})
 	outdlg := tui.CreateActionsLogDialog(logspace, tui.Console.Height())
@@ -263,12 +213,53 @@ func (tui *Tui) ExecCommand(title string, command string, args []string) {
 	err = cmd.Start()
 	if err != nil {
 		log.Errorf("cmd.Start() failed with %s\n", err)
+		return err
 	}
 	wg.Wait()
 	err = cmd.Wait()
 	if err != nil {
 		log.Errorf("cmd.Wait() failed with %s\n", err)
 	}
+	return err
+}
+
+// ExecActionCommand runs the command of the action on the container with ExecCommand
+// and tells the exec observers how long it took and whether it failed
+func (tui *Tui) ExecActionCommand(name string, action string, title string, command string, args []string) error {
+	started := time.Now()
+	err := tui.ExecCommand(title, command, args)
+	NotifyExecObservers(name, action, time.Since(started), err)
+	return err
+}
+
+// ExecObserver is told about the commands run for the actions on the containers when they finish,
+// err is nil if the command succeeded
+type ExecObserver func(name string, action string, duration time.Duration, err error)
+
+var execObservers = make([]ExecObserver, 0)
+
+func AddExecObserver(o ExecObserver) {
+	execObservers = append(execObservers, o)
+}
+
+func NotifyExecObservers(name string, action string, duration time.Duration, err error) {
+	for _, o := range execObservers {
+		o(name, action, duration, err)
+	}
+}
+
+// OpenLogDialog opens an empty log dialog and returns the function appending
+// a line to it, the function can be called from any goroutine
+func (tui *Tui) OpenLogDialog(title string) func(line string) {
+	logspace := edit.New(edit.Options{ReadOnly: true, Text: title})
+	outdlg := tui.CreateActionsLogDialog(logspace, tui.Console.Height())
+	outdlg.Open(tui.ViewHolder, gowid.RenderWithRatio{R: 0.7}, tui.App)
+	return func(line string) {
+		tui.App.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) {
+			logspace.SetText(logspace.Text()+line+"\n", app)
+			logspace.SetCursorPos(utf8.RuneCountInString(logspace.Text()), app)
+		}))
+	}
 }
 
 func GetStyledWidget(w gowid.IWidget, color string) *styled.Widget {
@@ -280,6 +271,29 @@ func GetStyledWidget(w gowid.IWidget, color string) *styled.Widget {
 	)
 }
 
+// OpenInfoDialog shows the lines of text with an OK button
+func (tui *Tui) OpenInfoDialog(title string, txt []string) {
+	var infoDialog *dialog.Widget
+	infoDialog = tui.MakeDialogForJail("", title, txt, nil, nil, nil, nil, nil)
+	infoDialog.Open(tui.ViewHolder, gowid.RenderWithRatio{R: 0.5}, tui.App)
+}
+
+// OpenConfirmDialog asks the question and runs onyes if confirmed
+func (tui *Tui) OpenConfirmDialog(jname string, title string, question string, onyes func()) {
+	var confirmDialog *dialog.Widget
+	confirmDialog = tui.MakeDialogForJail(
+		jname,
+		title,
+		[]string{question},
+		nil, nil, nil, nil,
+		func(jname string, boolparams []bool, strparams []string) {
+			confirmDialog.Close(tui.App)
+			onyes()
+		},
+	)
+	confirmDialog.Open(tui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, tui.App)
+}
+
 func (tui *Tui) MakeActionDialogForJail(jname string, title string, actions []string, actionfunc []func(jname string)) *dialog.Widget {
 	MakeWidgetChangedFunction := func(actionfunc []func(jname string), ind int, jname string) gowid.WidgetChangedFunction {
 		return func(app gowid.IApp, w gowid.IWidget) { actionfunc[ind](jname) }
@@ -340,7 +354,9 @@ func (tui *Tui) MakeActionDialogForJail(jname string, title string, actions []st
 	return retdialog
 }
 
-func (tui *Tui) ExecShellCommand(title string, command string, args []string, logfile string) {
+// ExecShellCommand runs the command showing the output it writes to logfile in a log dialog,
+// the error is returned if the command could not start or failed
+func (tui *Tui) ExecShellCommand(title string, command string, args []string, logfile string) error {
 	var cmd *exec.Cmd
 	var file *os.File
 	var err error
@@ -427,6 +443,16 @@ func (tui *Tui) ExecShellCommand(title string, command string, args []string, lo
 		log.Errorf("cmd.Wait() failed with %s\n", err)
 	}
 	chanfread <- 1
+	return err
+}
+
+// ExecActionShellCommand runs the command of the action on the container with ExecShellCommand
+// and tells the exec observers how long it took and whether it failed
+func (tui *Tui) ExecActionShellCommand(name string, action string, title string, command string, args []string, logfile string) error {
+	started := time.Now()
+	err := tui.ExecShellCommand(title, command, args, logfile)
+	NotifyExecObservers(name, action, time.Since(started), err)
+	return err
 }
 
 func (tui *Tui) SendTerminalCommand(cmd string) {
@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Validator checks the value of a dialog field without leading and trailing spaces,
+// the error is shown under the field
+type Validator func(value string) error
+
+// StrField is a text field of a dialog with optional validator
+type StrField struct {
+	Caption  string
+	Default  string
+	Validate Validator
+}
+
+// MakeStrFields makes the fields without validators from captions and default values
+func MakeStrFields(captions []string, defaults []string) []StrField {
+	fields := make([]StrField, len(captions))
+	for i := range captions {
+		fields[i] = StrField{Caption: captions[i], Default: defaults[i]}
+	}
+	return fields
+}
+
+// ValidateAll runs the validators in order and returns the first error
+func ValidateAll(validators ...Validator) Validator {
+	return func(value string) error {
+		for _, v := range validators {
+			if err := v(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ValidateOptional skips the validator for empty values
+func ValidateOptional(v Validator) Validator {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+		return v(value)
+	}
+}
+
+func ValidateNotEmpty(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("cannot be empty")
+	}
+	return nil
+}
+
+// ValidateRegexp checks the value matches the expression, msg describes the expected value
+func ValidateRegexp(expr string, msg string) Validator {
+	re := regexp.MustCompile(expr)
+	return func(value string) error {
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+}
+
+// ValidateIntRange checks the value is an integer between min and max
+func ValidateIntRange(min int, max int) Validator {
+	return func(value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("'%s' is not an integer", value)
+		}
+		if n < min || n > max {
+			return fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+// ValidateFloatRange checks the value is a number between min and max
+func ValidateFloatRange(min float64, max float64) Validator {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", value)
+		}
+		if f < min || f > max {
+			return fmt.Errorf("must be between %g and %g", min, max)
+		}
+		return nil
+	}
+}
+
+func ValidateInt(value string) error {
+	if _, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("'%s' is not an integer", value)
+	}
+	return nil
+}
+
+func ValidatePort(value string) error {
+	return ValidateIntRange(1, 65535)(value)
+}
+
+func ValidateIp(value string) error {
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("'%s' is not an IPv4 or IPv6 address", value)
+	}
+	return nil
+}
+
+var reHostname = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
+// ValidateHostname checks the host name (RFC 1123), IP addresses are accepted too
+func ValidateHostname(value string) error {
+	if net.ParseIP(value) != nil {
+		return nil
+	}
+	if len(value) > 253 || !reHostname.MatchString(value) {
+		return fmt.Errorf("'%s' is not a valid host name", value)
+	}
+	return nil
+}
+
+// ValidateHostPort checks the "host:port" address, IPv6 addresses are enclosed in brackets
+func ValidateHostPort(value string) error {
+	h, p, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("'%s' is not a host:port address", value)
+	}
+	if err = ValidateHostname(h); err != nil {
+		return err
+	}
+	return ValidatePort(p)
+}
+
+// ValidateName checks the name of a jail/VM as accepted by cbsd
+var ValidateName = ValidateRegexp(`^[A-Za-z][A-Za-z0-9_]*$`, "must start with a letter and contain only letters, digits and '_'")
+
+// ValidateOneOf checks the value is one of the allowed ones
+func ValidateOneOf(allowed []string) Validator {
+	return func(value string) error {
+		for _, a := range allowed {
+			if a == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", strings.Join(allowed, ", "))
+	}
+}
+
+// ValidateUnique checks the value is not one of the existing ones
+func ValidateUnique(existing []string) Validator {
+	return func(value string) error {
+		for _, e := range existing {
+			if e == value {
+				return fmt.Errorf("'%s' already exists", value)
+			}
+		}
+		return nil
+	}
+}
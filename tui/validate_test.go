@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidators(t *testing.T) {
+	longLabel := strings.Repeat("a", 64)
+	tests := []struct {
+		name     string
+		validate Validator
+		value    string
+		valid    bool
+	}{
+		{"NotEmpty", ValidateNotEmpty, "x", true},
+		{"NotEmpty", ValidateNotEmpty, "  ", false},
+		{"IntRange", ValidateIntRange(1, 10), "5", true},
+		{"IntRange", ValidateIntRange(1, 10), " 10 ", true},
+		{"IntRange", ValidateIntRange(1, 10), "0", false},
+		{"IntRange", ValidateIntRange(1, 10), "11", false},
+		{"IntRange", ValidateIntRange(1, 10), "5.0", false},
+		{"FloatRange", ValidateFloatRange(0.125, 16), "1.0", true},
+		{"FloatRange", ValidateFloatRange(0.125, 16), "16", true},
+		{"FloatRange", ValidateFloatRange(0.125, 16), "0.1", false},
+		{"FloatRange", ValidateFloatRange(0.125, 16), "fast", false},
+		{"Int", ValidateInt, "-3", true},
+		{"Int", ValidateInt, "three", false},
+		{"Port", ValidatePort, "1", true},
+		{"Port", ValidatePort, "65535", true},
+		{"Port", ValidatePort, "0", false},
+		{"Port", ValidatePort, "65536", false},
+		{"Ip", ValidateIp, "10.0.0.1", true},
+		{"Ip", ValidateIp, "fd00::1", true},
+		{"Ip", ValidateIp, "10.0.0.256", false},
+		{"Ip", ValidateIp, "10.0.0.1/24", false},
+		{"Hostname", ValidateHostname, "host", true},
+		{"Hostname", ValidateHostname, "web-1.example.org", true},
+		{"Hostname", ValidateHostname, "10.0.0.1", true},
+		{"Hostname", ValidateHostname, "fd00::1", true},
+		{"Hostname", ValidateHostname, "", false},
+		{"Hostname", ValidateHostname, "-host", false},
+		{"Hostname", ValidateHostname, "host-", false},
+		{"Hostname", ValidateHostname, "a..b", false},
+		{"Hostname", ValidateHostname, "under_score", false},
+		{"Hostname", ValidateHostname, longLabel, false},
+		{"Hostname", ValidateHostname, longLabel[1:], true},
+		{"HostPort", ValidateHostPort, "host:22", true},
+		{"HostPort", ValidateHostPort, "10.0.0.1:5900", true},
+		{"HostPort", ValidateHostPort, "[fd00::1]:5900", true},
+		{"HostPort", ValidateHostPort, "host", false},
+		{"HostPort", ValidateHostPort, "host:0", false},
+		{"HostPort", ValidateHostPort, "fd00::1:22", false},
+		{"HostPort", ValidateHostPort, "bad_host:22", false},
+		{"Name", ValidateName, "jail1", true},
+		{"Name", ValidateName, "my_jail", true},
+		{"Name", ValidateName, "", false},
+		{"Name", ValidateName, "1jail", false},
+		{"Name", ValidateName, "_jail", false},
+		{"Name", ValidateName, "my-jail", false},
+		{"OneOf", ValidateOneOf([]string{"on", "off"}), "off", true},
+		{"OneOf", ValidateOneOf([]string{"on", "off"}), "Off", false},
+		{"Unique", ValidateUnique([]string{"jail1", "jail2"}), "jail3", true},
+		{"Unique", ValidateUnique([]string{"jail1", "jail2"}), "jail2", false},
+		{"Optional", ValidateOptional(ValidateInt), "", true},
+		{"Optional", ValidateOptional(ValidateInt), "x", false},
+		{"All", ValidateAll(ValidateName, ValidateUnique([]string{"jail1"})), "jail2", true},
+		{"All", ValidateAll(ValidateName, ValidateUnique([]string{"jail1"})), "jail1", false},
+		{"All", ValidateAll(), "anything", true},
+	}
+	for _, tt := range tests {
+		err := tt.validate(tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("%s(%q) failed: %v", tt.name, tt.value, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s(%q) passed, want an error", tt.name, tt.value)
+		}
+	}
+}
+
+func TestValidateAllReturnsFirstError(t *testing.T) {
+	err := ValidateAll(ValidateNotEmpty, ValidateName)("")
+	if err == nil || err.Error() != "cannot be empty" {
+		t.Errorf("ValidateAll returned %v, want the error of the first validator", err)
+	}
+}
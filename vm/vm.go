@@ -0,0 +1,523 @@
+package vm
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/dialog"
+	"github.com/gcla/gowid/widgets/edit"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/quasilyte/gsignal"
+
+	"container"
+	"host"
+	"tui"
+)
+
+// Emulator describes a VM type managed by cbsd with the x*/q* like commands,
+// the VMs are the rows of jails table with the emulator and its settings table
+type Emulator struct {
+	Name         string // jails.emulator and the settings table, also the container type
+	Title        string // like "XEN VM"
+	Action       string // key map action switching to the type
+	CommandStart string
+	CommandStop  string
+	CommandLogin string
+	actions      *tui.ActionRegistry[*Vm]
+}
+
+type Vm struct {
+	Vname      string
+	Status     int
+	Astart     int
+	OsType     string
+	Cpus       string
+	Ram        string
+	params     map[string]string
+	emu        *Emulator
+	jtui       *tui.Tui
+	evtUpdated gsignal.Event[string]
+	evtRefresh gsignal.Event[any]
+}
+
+const (
+	START      = "Start"
+	STOP       = "Stop"
+	STARTSTOP  = "Start/Stop"
+	CREATESNAP = "Create Snap."
+	DELSNAP    = "Destroy Snap."
+	VIEW       = "View"
+	ACTIONS    = "Actions..."
+)
+
+var strStatus = []string{"Off", "On", "Slave", "Unknown(3)", "Unknown(4)", "Unknown(5)"}
+var strAutoStart = []string{"Off", "On"}
+var strHeaderTitles = []string{"NAME", "STATUS", "AUTOSTART", "OS_TYPE", "CPUS", "RAM"}
+
+var commandJailSnap string = "jsnapshot"
+var commandJailStatus string = "jstatus"
+var argJailName = "jname"
+var argSnapName = "snapname"
+
+// Capabilities of the VMs
+var _ container.Loginable = (*Vm)(nil)
+var _ container.Snapshotter = (*Vm)(nil)
+var _ container.StatusChecker = (*Vm)(nil)
+
+// Register makes the actions of the emulator and registers it as a container type
+func Register(emu *Emulator) {
+	emu.actions = tui.NewActionRegistry[*Vm]()
+	emu.actions.Register(
+		&tui.Action[*Vm]{
+			Id: tui.ACTION_ACTIONS, Label: ACTIONS, Bottom: true,
+			Run: func(vm *Vm) { vm.emu.actions.OpenActionDialog(vm.jtui, vm) },
+		},
+		&tui.Action[*Vm]{
+			Id: tui.ACTION_STARTSTOP, Label: STARTSTOP, Bottom: true, Menu: true,
+			StateLabel: func(vm *Vm) string {
+				if vm.IsRunning() {
+					return STOP
+				}
+				return START
+			},
+			Available: func(vm *Vm) bool { return vm.IsRunning() || vm.IsRunnable() },
+			CliArgs: func(vm *Vm) []string {
+				if vm.IsRunning() {
+					return vm.GetCliArgs(vm.emu.CommandStop, "inter=1")
+				}
+				return vm.GetCliArgs(vm.emu.CommandStart, "inter=1")
+			},
+			Run: func(vm *Vm) { vm.StartStop() },
+		},
+		&tui.Action[*Vm]{
+			Id: tui.ACTION_SNAPSHOT, Label: CREATESNAP, Bottom: true, Menu: true,
+			CliArgs: func(vm *Vm) []string {
+				return vm.GetSnapshotCliArgs("gettimeofday")
+			},
+			Run: func(vm *Vm) { vm.OpenSnapshotDialog() },
+		},
+		&tui.Action[*Vm]{
+			Id: tui.ACTION_SNAPSHOTS, Label: DELSNAP, Bottom: true, Menu: true,
+			Run: func(vm *Vm) { vm.OpenSnapActionsDialog() },
+		},
+		&tui.Action[*Vm]{
+			Id: tui.ACTION_VIEW, Label: VIEW, Bottom: true, Menu: true,
+			Run: func(vm *Vm) { vm.View() },
+		},
+	)
+	container.RegisterType(container.ContainerType{
+		Name:     emu.Name,
+		Emulator: emu.Name,
+		Action:   emu.Action,
+		Load: func(dbname string) ([]container.Container, error) {
+			vms, err := GetVmsFromDb(emu, dbname)
+			cont := make([]container.Container, len(vms))
+			for i := range vms {
+				cont[i] = vms[i]
+			}
+			return cont, err
+		},
+	})
+}
+
+func New(emu *Emulator) Vm {
+	return Vm{
+		emu:    emu,
+		params: make(map[string]string),
+	}
+}
+
+func (vm *Vm) GetType() string {
+	return vm.emu.Name
+}
+
+func (vm *Vm) GetSignalUpdated() *gsignal.Event[string] {
+	return &vm.evtUpdated
+}
+
+func (vm *Vm) GetSignalRefresh() *gsignal.Event[any] {
+	return &vm.evtRefresh
+}
+
+func (vm *Vm) SetTui(t *tui.Tui) {
+	vm.jtui = t
+}
+
+// GetCliArgs returns the cbsd arguments of the command for the VM
+func (vm *Vm) GetCliArgs(command string, args ...string) []string {
+	res := append([]string{command}, args...)
+	return append(res, fmt.Sprintf("%s=%s", argJailName, vm.Vname))
+}
+
+func (vm *Vm) GetBottomMenuActions() []string {
+	return vm.emu.actions.GetBottomMenuActions()
+}
+
+func (vm *Vm) GetMenuActions() []string {
+	return vm.emu.actions.GetMenuActions()
+}
+
+func (vm *Vm) GetActionCaption(action string) string {
+	return vm.emu.actions.GetCaption(action)
+}
+
+func (vm *Vm) IsActionAvailable(action string) bool {
+	return vm.emu.actions.IsAvailable(vm, action)
+}
+
+func (vm *Vm) GetCliActions() []string {
+	return vm.emu.actions.GetCliActions(vm)
+}
+
+func (vm *Vm) GetActionCliArgs(action string) []string {
+	return vm.emu.actions.GetCliArgs(vm, action)
+}
+
+func (vm *Vm) GetActionConfirmText(action string) string {
+	return vm.emu.actions.GetConfirmText(vm, action)
+}
+
+func (vm *Vm) ExecuteAction(action string) bool {
+	return vm.emu.actions.Execute(vm.jtui, vm, action)
+}
+
+func (vm *Vm) GetHeaderTitles() []string {
+	return strHeaderTitles
+}
+
+func (vm *Vm) GetName() string {
+	return vm.Vname
+}
+
+func (vm *Vm) GetStatus() int {
+	return vm.Status
+}
+
+func (vm *Vm) GetAstart() int {
+	return vm.Astart
+}
+
+func (vm *Vm) IsRunning() bool {
+	return vm.Status == 1
+}
+
+func (vm *Vm) IsRunnable() bool {
+	return vm.Status == 0
+}
+
+func (vm *Vm) GetStatusString() string {
+	if vm.Status < 0 || vm.Status >= len(strStatus) {
+		return strconv.Itoa(vm.Status)
+	}
+	return strStatus[vm.Status]
+}
+
+func (vm *Vm) GetAutoStartString() string {
+	if vm.Astart < 0 || vm.Astart >= len(strAutoStart) {
+		return strconv.Itoa(vm.Astart)
+	}
+	return strAutoStart[vm.Astart]
+}
+
+func (vm *Vm) GetAllParams() []string {
+	return []string{vm.GetStatusString(), vm.GetAutoStartString(), vm.OsType, vm.Cpus, BytesToSize(vm.Ram)}
+}
+
+// BytesToSize shows the RAM size in bytes with the largest exact unit
+func BytesToSize(str string) string {
+	b, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || b <= 0 {
+		return str
+	}
+	units := []string{"", "k", "m", "g", "t"}
+	i := 0
+	for b%1024 == 0 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%d%s", b, units[i])
+}
+
+func (vm *Vm) GetCurrentStatus() int {
+	var stdout, stderr bytes.Buffer
+	retstatus := -1
+	command, args := host.GetCbsdCommand(vm.GetCliArgs(commandJailStatus, "invert=true")...)
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, "NOCOLOR=1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return retstatus
+	}
+	str_out := strings.TrimSuffix(stdout.String(), "\n")
+	if str_out != "" {
+		jid, err := strconv.Atoi(str_out)
+		if err != nil {
+			return retstatus
+		}
+		if jid > 0 {
+			retstatus = 1
+		} else if jid == 0 {
+			retstatus = 0
+		}
+	}
+	return retstatus
+}
+
+func (emu *Emulator) getListQuery() string {
+	return fmt.Sprintf("SELECT jails.jname,jails.status,jails.astart,IFNULL(%[1]s.vm_os_type,''),IFNULL(%[1]s.vm_cpus,''),IFNULL(%[1]s.vm_ram,'') FROM jails LEFT JOIN %[1]s ON jails.jname=%[1]s.jname WHERE jails.emulator='%[1]s'", emu.Name)
+}
+
+func (vm *Vm) scan(row interface{ Scan(dest ...any) error }) error {
+	err := row.Scan(&vm.Vname, &vm.Status, &vm.Astart, &vm.OsType, &vm.Cpus, &vm.Ram)
+	if err != nil {
+		return err
+	}
+	if (vm.Status == 0) || (vm.Status == 1) {
+		cur_status := vm.GetCurrentStatus()
+		if cur_status >= 0 {
+			vm.Status = cur_status
+		}
+	}
+	return nil
+}
+
+func GetVmsFromDb(emu *Emulator, dbname string) ([]*Vm, error) {
+	vms := make([]*Vm, 0)
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return vms, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(emu.getListQuery())
+	if err != nil {
+		return vms, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		vm := New(emu)
+		if err = vm.scan(rows); err != nil {
+			return vms, err
+		}
+		vms = append(vms, &vm)
+	}
+	return vms, nil
+}
+
+func (vm *Vm) UpdateVmFromDb(dbname string) (bool, error) {
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	row := db.QueryRow(vm.emu.getListQuery()+" AND jails.jname = ?", vm.Vname)
+	if err := vm.scan(row); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetVmFromDbFull reads all the columns of jails and of the emulator table to params
+func (vm *Vm) GetVmFromDbFull(dbname string) (bool, error) {
+	db, err := sql.Open("sqlite3", dbname)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM jails LEFT JOIN %[1]s ON jails.jname=%[1]s.jname WHERE jails.jname = ?", vm.emu.Name), vm.Vname)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	rawResult := make([][]byte, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range rawResult {
+		dest[i] = &rawResult[i]
+	}
+	result := false
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return false, err
+		}
+		for i, raw := range rawResult {
+			if raw != nil {
+				vm.params[cols[i]] = string(raw)
+				result = true
+			}
+		}
+	}
+	return result, nil
+}
+
+func (vm *Vm) GetVmViewString() string {
+	var strview string
+	_, _ = vm.GetVmFromDbFull(host.GetCbsdDbConnString(false))
+	strview += "Name: " + vm.Vname + "\n"
+	strview += "Status: " + vm.GetStatusString() + "\n"
+	strview += "Auto Start: " + vm.GetAutoStartString() + "\n"
+	strview += "OS type: " + vm.OsType + "\n"
+	strview += "CPUs: " + vm.Cpus + "\n"
+	strview += "RAM: " + BytesToSize(vm.Ram) + "\n\n"
+	keys := make([]string, 0, len(vm.params))
+	for key := range vm.params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		strview += key + ": " + vm.params[key] + "\n"
+	}
+	strview += "\n"
+	return strview
+}
+
+func (vm *Vm) View() {
+	viewspace := edit.New(edit.Options{ReadOnly: true})
+	outdlg := vm.jtui.CreateActionsLogDialog(viewspace, vm.jtui.Console.Height())
+	outdlg.Open(vm.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.7}, vm.jtui.App)
+	viewspace.SetText(vm.GetVmViewString()+vm.jtui.GetViewExtra(vm.Vname), vm.jtui.App)
+	vm.jtui.App.RedrawTerminal()
+}
+
+func (vm *Vm) GetStartCommand() string {
+	return strings.Join(vm.GetCliArgs(vm.emu.CommandStart, "inter=1"), " ")
+}
+
+func (vm *Vm) GetLoginArgs() []string {
+	return vm.GetCliArgs(vm.emu.CommandLogin)
+}
+
+func (vm *Vm) CreateScriptStartVm() (string, error) {
+	file, err := ioutil.TempFile("", "vm_start_")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	file.WriteString("#!" + host.SHELL_PROGRAM + "\n")
+	cmd := host.STDBUF_PROGRAM + " -o L "
+	if host.USE_DOAS {
+		cmd += host.DOAS_PROGRAM + " "
+	}
+	cmd += host.CBSD_PROGRAM + " " + vm.GetStartCommand() + " > " + host.LOGFILE_JSTART
+	if _, err = file.WriteString(cmd + "\n"); err != nil {
+		return file.Name(), err
+	}
+	return file.Name(), nil
+}
+
+func (vm *Vm) StartStop() {
+	if vm.IsRunning() {
+		vm.jtui.DetachConsole(vm.Vname)
+		command, args := host.GetCbsdCommand(vm.GetCliArgs(vm.emu.CommandStop, "inter=1")...)
+		vm.jtui.ExecActionCommand(vm.Vname, tui.RUN_STOP, "Stopping "+vm.emu.Title+"...\n", command, args)
+	} else if vm.IsRunnable() {
+		script, err := vm.CreateScriptStartVm()
+		if err != nil {
+			host.LogError("Cannot create "+vm.emu.CommandStart+" script", err)
+			if script != "" {
+				os.Remove(script)
+			}
+			return
+		}
+		defer os.Remove(script)
+		vm.jtui.ExecActionShellCommand(vm.Vname, tui.RUN_START, "Starting "+vm.emu.Title+"...\n", host.SHELL_PROGRAM, []string{script}, host.LOGFILE_JSTART)
+	}
+	_, _ = vm.UpdateVmFromDb(host.GetCbsdDbConnString(false))
+	vm.evtUpdated.Emit(vm.Vname)
+}
+
+// GetSnapshotCliArgs returns the cbsd arguments creating the snapshot
+func (vm *Vm) GetSnapshotCliArgs(snapname string) []string {
+	// cbsd jsnapshot mode=create snapname=gettimeofday jname=vm1
+	return vm.GetCliArgs(commandJailSnap, "mode=create", argSnapName+"="+snapname)
+}
+
+func (vm *Vm) Snapshot(snapname string) {
+	command, args := host.GetCbsdCommand(vm.GetSnapshotCliArgs(snapname)...)
+	vm.jtui.ExecActionCommand(vm.Vname, tui.ACTION_SNAPSHOT, "Creating "+vm.emu.Title+" snapshot...\n", command, args)
+}
+
+func (vm *Vm) OpenSnapshotDialog() {
+	var cbsdSnapshotDialog *dialog.Widget
+	cbsdSnapshotDialog = vm.jtui.MakeValidatedDialogForJail(
+		vm.Vname,
+		"Snapshot "+vm.emu.Title+" "+vm.Vname,
+		nil, nil, nil,
+		[]tui.StrField{{Caption: "Snapshot name: ", Default: "gettimeofday", Validate: host.ValidateSnapshotName}},
+		func(jname string, boolparams []bool, strparams []string) {
+			cbsdSnapshotDialog.Close(vm.jtui.App)
+			vm.Snapshot(strparams[0])
+		},
+	)
+	cbsdSnapshotDialog.Open(vm.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, vm.jtui.App)
+}
+
+func (vm *Vm) GetSnapshots() [][2]string {
+	retsnap := make([][2]string, 0)
+	var stdout, stderr bytes.Buffer
+	// cbsd jsnapshot jname=vm1 mode=list header=0 display=snapname,creation
+	command, args := host.GetCbsdCommand(vm.GetCliArgs(commandJailSnap, "mode=list", "header=0", "display=snapname,creation")...)
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, "NOCOLOR=1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		host.LogError("cmd.Run() failed", err)
+		return retsnap
+	}
+	for _, s := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(s)
+		if len(fields) < 2 {
+			continue
+		}
+		retsnap = append(retsnap, [2]string{fields[0], fields[1]})
+	}
+	return retsnap
+}
+
+func (vm *Vm) OpenSnapActionsDialog() {
+	var cbsdSnapActionsDialog *dialog.Widget
+	MakeWidgetChangedFunction := func(snapname string) func(jname string) {
+		return func(jname string) {
+			cbsdSnapActionsDialog.Close(vm.jtui.App)
+			vm.jtui.OpenConfirmDialog(vm.Vname, "Destroy snapshot "+snapname+"\nof "+vm.emu.Title+" "+vm.Vname,
+				"Really destroy snapshot "+snapname+"\nof "+vm.emu.Title+" "+vm.Vname+"??",
+				func() { vm.DestroySnapshot(snapname) })
+		}
+	}
+	var menulines []string
+	var cbfunc []func(jname string)
+	for _, s := range vm.GetSnapshots() {
+		menulines = append(menulines, s[0]+" ("+s[1]+")")
+		cbfunc = append(cbfunc, MakeWidgetChangedFunction(s[0]))
+	}
+	cbsdSnapActionsDialog = vm.jtui.MakeActionDialogForJail(vm.Vname, "Snapshots for "+vm.Vname, menulines, cbfunc)
+	cbsdSnapActionsDialog.Open(vm.jtui.ViewHolder, gowid.RenderWithRatio{R: 0.3}, vm.jtui.App)
+}
+
+func (vm *Vm) DestroySnapshot(snapname string) {
+	// cbsd jsnapshot mode=destroy jname=vm1 snapname=20220319193339
+	command, args := host.GetCbsdCommand(vm.GetCliArgs(commandJailSnap, "mode=destroy", argSnapName+"="+snapname)...)
+	if vm.jtui != nil {
+		vm.jtui.ExecActionCommand(vm.Vname, tui.RUN_DESTROY_SNAPSHOT, "Destroy "+vm.emu.Title+" snapshot...\n", command, args)
+	}
+}
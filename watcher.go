@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gcla/gowid"
+	"github.com/gcla/gowid/widgets/pile"
+	"github.com/gcla/gowid/widgets/styled"
+	"github.com/gcla/gowid/widgets/text"
+
+	"host"
+	"tui"
+)
+
+// State changes of a container in this time after a start/stop from the TUI are expected
+const WATCH_EXPECT_WINDOW time.Duration = 3 * time.Minute
+
+// Number of the notifications kept for the notifications dialog
+const NOTICES_MAX int = 50
+
+const (
+	STATE_RUNNING string = "running"
+	STATE_STOPPED string = "stopped"
+	STATE_REMOVED string = "removed"
+)
+
+// StateWatcher detects the containers stopped not from the TUI, shows a notification banner
+// and runs the hooks of the container
+type StateWatcher struct {
+	mu       sync.Mutex
+	expected map[string]time.Time // last start/stop from the TUI by name
+	notices  []host.StateEvent    // newest first, used in the UI goroutine only
+	banner   *text.Widget
+	layout   *pile.Widget
+	widgets  []gowid.IContainerWidget // widgets of the layout without the banner
+	shown    bool
+}
+
+func NewStateWatcher() *StateWatcher {
+	return &StateWatcher{
+		expected: make(map[string]time.Time),
+		notices:  make([]host.StateEvent, 0),
+		banner:   text.New(""),
+	}
+}
+
+func init() {
+	tui.AddActionObserver(func(name string, action string) {
+		if action == tui.ACTION_STARTSTOP || action == tui.ACTION_DESTROY {
+			stateWatcher.ExpectChange(name)
+		}
+	})
+}
+
+func GetWatchInterval() time.Duration {
+	if host.Cfg.WatchInterval <= 0 {
+		return time.Duration(host.WATCH_INTERVAL_DEFAULT) * time.Second
+	}
+	return time.Duration(host.Cfg.WatchInterval) * time.Second
+}
+
+// ExpectChange marks the state change of the container as initiated from the TUI
+func (sw *StateWatcher) ExpectChange(name string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.expected[name] = time.Now()
+}
+
+func (sw *StateWatcher) IsExpected(name string) bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	t, found := sw.expected[name]
+	return found && time.Since(t) < WATCH_EXPECT_WINDOW
+}
+
+// GetRunning returns the running containers of the snapshot
+func GetRunning(all map[string]Container) map[string]bool {
+	running := make(map[string]bool)
+	for name, c := range all {
+		if c.IsRunning() {
+			running[name] = true
+		}
+	}
+	return running
+}
+
+// Start watches the containers of all the types in the snapshots of the poller,
+// layout is the main pile, the banner is shown above its last widget
+func (sw *StateWatcher) Start(app *gowid.App, poller *ContainerPoller, layout *pile.Widget, widgets []gowid.IContainerWidget) {
+	sw.layout = layout
+	sw.widgets = widgets
+	hostname, _ := os.Hostname()
+	var prev map[string]bool
+	poller.Subscribe(GetWatchInterval(), func(all map[string]Container) {
+		running := GetRunning(all)
+		for name := range prev {
+			if running[name] || sw.IsExpected(name) {
+				continue
+			}
+			ev := host.StateEvent{Time: time.Now(), Host: hostname, Name: name, From: STATE_RUNNING, To: STATE_REMOVED}
+			if c, found := all[name]; found {
+				ev.Type, ev.To = c.GetType(), STATE_STOPPED
+			}
+			if hook, found := host.GetNotifyHook(name); found {
+				for _, err := range hook.Run(ev) {
+					host.LogError("Notification hook of "+name, err)
+				}
+			}
+			app.RunThenRenderEvent(gowid.RunFunction(func(app gowid.IApp) {
+				sw.AddNotice(ev, app)
+			}))
+		}
+		prev = running
+	})
+}
+
+func (sw *StateWatcher) AddNotice(ev host.StateEvent, app gowid.IApp) {
+	sw.notices = append([]host.StateEvent{ev}, sw.notices...)
+	if len(sw.notices) > NOTICES_MAX {
+		sw.notices = sw.notices[:NOTICES_MAX]
+	}
+	txt := "! " + ev.String() + " unexpectedly"
+	if len(sw.notices) > 1 {
+		txt += " (" + strconv.Itoa(len(sw.notices)-1) + " more)"
+	}
+	sw.banner.SetText(txt+", press '"+keyMap.GetKeyName(tui.ACTION_NOTICES)+"' to see and dismiss", app)
+	if !sw.shown {
+		banner := &gowid.ContainerWidget{IWidget: styled.New(sw.banner, gowid.MakePaletteRef("error")), D: gowid.RenderFlow{}}
+		widgets := make([]gowid.IWidget, 0, len(sw.widgets)+1)
+		for i, w := range sw.widgets {
+			if i == len(sw.widgets)-1 {
+				widgets = append(widgets, banner)
+			}
+			widgets = append(widgets, w)
+		}
+		sw.layout.SetSubWidgets(widgets, app)
+		sw.layout.SetFocus(app, len(widgets)-1)
+		sw.shown = true
+	}
+	if GetJailByName(ev.Name) != nil {
+		var jname string
+		if curjail := GetSelectedJail(); curjail != nil {
+			jname = curjail.GetName()
+		}
+		RefreshJailList()
+		SelectJail(jname)
+	}
+}
+
+// Dismiss hides the banner, the notifications are kept for the dialog
+func (sw *StateWatcher) Dismiss(app gowid.IApp) {
+	if !sw.shown {
+		return
+	}
+	widgets := make([]gowid.IWidget, len(sw.widgets))
+	for i, w := range sw.widgets {
+		widgets[i] = w
+	}
+	sw.layout.SetSubWidgets(widgets, app)
+	sw.layout.SetFocus(app, len(widgets)-1)
+	sw.shown = false
+}
+
+func OpenNoticesDialog() {
+	lines := make([]string, 0)
+	for _, ev := range stateWatcher.notices {
+		lines = append(lines, ev.String()+" unexpectedly")
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No containers stopped unexpectedly")
+	}
+	stateWatcher.Dismiss(app)
+	mainTui.OpenInfoDialog("Notifications", lines)
+}
@@ -0,0 +1,20 @@
+package xen
+
+import (
+	"tui"
+	"vm"
+)
+
+// Emulator of XEN VMs, xen table holds their settings
+var Emulator = &vm.Emulator{
+	Name:         "xen",
+	Title:        "XEN VM",
+	Action:       tui.ACTION_XEN,
+	CommandStart: "xstart",
+	CommandStop:  "xstop",
+	CommandLogin: "xlogin",
+}
+
+func init() {
+	vm.Register(Emulator)
+}